@@ -0,0 +1,312 @@
+package baseabci
+
+import (
+	"fmt"
+
+	"github.com/QOSGroup/qbase/account"
+	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/feegrant"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+//AnteDecorator AnteHandler处理链中的一环
+//decorator处理完成后调用next执行后续decorator, 返回非OK的result时终止后续处理
+type AnteDecorator interface {
+	AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (newCtx ctx.Context, result types.Result)
+}
+
+//ChainAnteDecorators 将decorators按顺序组装为AnteHandler
+func ChainAnteDecorators(chain ...AnteDecorator) AnteHandler {
+	if len(chain) == 0 {
+		return func(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (ctx.Context, types.Result) {
+			return ctx, types.Result{}
+		}
+	}
+
+	next := ChainAnteDecorators(chain[1:]...)
+	return func(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (ctx.Context, types.Result) {
+		return chain[0].AnteHandle(ctx, tx, txStdFromChainID, skipSigVerify, next)
+	}
+}
+
+//DefaultAnteDecorators 默认的decorators, 依次为:
+//1. 校验txStd基础信息 2. 校验最低手续费 3. 校验gas payer授权 4. gasPreHandler 5. 校验签名账户nonce 6. 校验签名 7. 增加签名账户nonce
+//gasPreHandler在签名校验及增加nonce之前执行, payer无法支付gas时不消耗签名账户nonce
+//需在SetFeeCheckHandler, SetMaxFeeHandler, SetGasPreHandler之后调用
+func (app *BaseApp) DefaultAnteDecorators() []AnteDecorator {
+	return []AnteDecorator{
+		NewValidateBasicDecorator(),
+		NewMinimumFeesDecorator(app.feeCheckHandler),
+		NewGasPayerDecorator(app.maxFeeHandler),
+		NewGasPreDecorator(app.gasPreHandler),
+		NewNonceCheckDecorator(),
+		NewSigVerifyDecoratorWithCache(app.sigCache),
+		NewIncrementNonceDecorator(),
+	}
+}
+
+//-------------------------------------------------------------------
+
+//ValidateBasicDecorator 校验txStd基础信息
+type ValidateBasicDecorator struct{}
+
+func NewValidateBasicDecorator() ValidateBasicDecorator {
+	return ValidateBasicDecorator{}
+}
+
+func (vbd ValidateBasicDecorator) AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	if err := tx.ValidateBasicData(ctx, true, ctx.ChainID()); err != nil {
+		return ctx, err.Result()
+	}
+
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
+
+//MinimumFeesDecorator checkTx阶段执行feeCheckHandler, 校验tx手续费是否满足节点配置的最低手续费
+//未配置minimum_fees时不校验. deliverTx及模拟执行时不校验
+type MinimumFeesDecorator struct {
+	handler FeeCheckHandler
+}
+
+func NewMinimumFeesDecorator(handler FeeCheckHandler) MinimumFeesDecorator {
+	return MinimumFeesDecorator{handler: handler}
+}
+
+func (mfd MinimumFeesDecorator) AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	if mfd.handler != nil && ctx.IsCheckTx() {
+		minimumFees := ctx.MinimumFees()
+		if !isZeroFees(minimumFees) {
+			if err := mfd.handler(ctx, tx, minimumFees); err != nil {
+				return ctx, err.Result()
+			}
+		}
+	}
+
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
+
+func isZeroFees(fees []types.Coin) bool {
+	for _, fee := range fees {
+		if fee != nil && !fee.GetAmount().IsNil() && !fee.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+//NonceCheckDecorator 校验account address,nonce是否与签名中一致, 并设置account pubkey
+//签名账户保存在ctx的ContextKeySigners中,供后续decorator使用
+//未注册accountProto时, 不做校验. 用户可以在itx.validate()中自定义签名校验逻辑
+type NonceCheckDecorator struct{}
+
+func NewNonceCheckDecorator() NonceCheckDecorator {
+	return NonceCheckDecorator{}
+}
+
+func (ncd NonceCheckDecorator) AnteHandle(cctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	accountMapper := GetAccountMapper(cctx)
+	if accountMapper == nil {
+		cctx.Logger().Info("accountMapper not setup....")
+		return next(cctx, tx, txStdFromChainID, skipSigVerify)
+	}
+
+	signers := tx.GetSigners()
+	signatures := tx.Signature
+
+	if !skipSigVerify && len(signatures) != len(signers) {
+		return cctx, types.ErrUnauthorized(fmt.Sprintf("signatures and signers not match. signatures count: %d , non-dup signers count: %d ", len(signatures), len(signers))).Result()
+	}
+
+	//签名者为空则不校验签名
+	if len(signers) == 0 {
+		//TODO: !!!!Dangerous if signers is empty
+		return next(cctx, tx, txStdFromChainID, skipSigVerify)
+	}
+
+	signerAccount := make([]account.Account, len(signers))
+	for i, addr := range signers {
+		acc := accountMapper.GetAccount(addr)
+		if acc == nil {
+			acc = accountMapper.NewAccountWithAddress(addr)
+		}
+		signerAccount[i] = acc
+
+		//跳过签名校验时,仅在签名中包含公钥时设置账户公钥
+		if skipSigVerify {
+			if acc.GetPublicKey() == nil && i < len(signatures) && signatures[i].Pubkey != nil {
+				acc.SetPublicKey(signatures[i].Pubkey)
+			}
+			continue
+		}
+
+		signature := signatures[i]
+		if signature.Pubkey != nil {
+			pubkeyAddress := types.AccAddress(signature.Pubkey.Address().Bytes())
+			if !acc.GetAddress().Equals(pubkeyAddress) {
+				return cctx, types.ErrInternal(fmt.Sprintf("invalid address. expect: %s, got: %s", acc.GetAddress(), pubkeyAddress)).Result()
+			}
+		}
+
+		//issue-68 https://github.com/QOSGroup/qbase/issues/68
+		if signature.Nonce != acc.GetNonce()+1 {
+			return cctx, types.ErrInternal(fmt.Sprintf("invalid nonce. expect: %d, got: %d", acc.GetNonce()+1, signature.Nonce)).Result()
+		}
+
+		if acc.GetPublicKey() == nil {
+			if signature.Pubkey == nil {
+				return cctx, types.ErrInternal("txstd's pubkey is nil in signature").Result()
+			}
+			acc.SetPublicKey(signature.Pubkey)
+		}
+	}
+
+	return next(cctx.WithValue(ctx.ContextKeySigners, signerAccount), tx, txStdFromChainID, skipSigVerify)
+}
+
+//SigVerifyDecorator 根据账户nonce及txStd源chainID校验签名. 需在NonceCheckDecorator之后执行
+//多签账户(multisig.PubKeyMultisigThreshold)的签名为amino编码的multisig.Multisignature, 需满足门限数量的成员签名
+//设置签名校验缓存时, checkTx校验通过的tx写入缓存, deliverTx命中缓存时不再重复校验
+type SigVerifyDecorator struct {
+	cache *SigVerifyCache
+}
+
+func NewSigVerifyDecorator() SigVerifyDecorator {
+	return SigVerifyDecorator{}
+}
+
+func NewSigVerifyDecoratorWithCache(cache *SigVerifyCache) SigVerifyDecorator {
+	return SigVerifyDecorator{cache: cache}
+}
+
+func (svd SigVerifyDecorator) AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	if skipSigVerify {
+		return next(ctx, tx, txStdFromChainID, skipSigVerify)
+	}
+
+	signerAccount := GetSignerAccounts(ctx)
+	if len(signerAccount) == 0 {
+		return next(ctx, tx, txStdFromChainID, skipSigVerify)
+	}
+
+	//模拟执行不修改签名校验缓存
+	var txHash []byte
+	simulate := isSimulate(ctx)
+	if svd.cache != nil && len(ctx.TxBytes()) != 0 {
+		txHash = tmhash.Sum(ctx.TxBytes())
+		if svd.cache.Has(txHash) {
+			//tx仅deliver一次, 命中后删除
+			if !ctx.IsCheckTx() && !simulate {
+				svd.cache.Remove(txHash)
+			}
+			return next(ctx, tx, txStdFromChainID, skipSigVerify)
+		}
+	}
+
+	for i, acc := range signerAccount {
+		signBytes := tx.BuildSignatureBytes(acc.GetNonce()+1, txStdFromChainID)
+		if err := verifySignature(acc.GetPublicKey(), signBytes, tx.Signature[i].Signature); err != nil {
+			return ctx, err.Result()
+		}
+	}
+
+	if txHash != nil && ctx.IsCheckTx() {
+		svd.cache.Add(txHash)
+	}
+
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
+
+//verifySignature 校验签名, 多签公钥的签名由PubKeyMultisigThreshold.VerifyBytes校验
+func verifySignature(pubkey crypto.PubKey, signBytes, sig []byte) types.Error {
+	if !pubkey.VerifyBytes(signBytes, sig) {
+		return types.ErrInternal("txstd's signature verification failed")
+	}
+	return nil
+}
+
+//IncrementNonceDecorator 增加签名账户nonce并保存账户. 需在NonceCheckDecorator之后执行
+type IncrementNonceDecorator struct{}
+
+func NewIncrementNonceDecorator() IncrementNonceDecorator {
+	return IncrementNonceDecorator{}
+}
+
+func (ind IncrementNonceDecorator) AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	signerAccount := GetSignerAccounts(ctx)
+	if len(signerAccount) != 0 {
+		accountMapper := GetAccountMapper(ctx)
+		for _, acc := range signerAccount {
+			acc.SetNonce(acc.GetNonce() + 1)
+			accountMapper.SetAccount(acc)
+		}
+	}
+
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
+
+//GasPayerDecorator 校验gas payer同意支付gas费: gas payer为空或为tx签名者, 或已通过feegrant授权给tx签名者且额度足够支付MaxFeeHandler返回的gas费
+//使用授权额度时, 代付的签名者通过feegrant.WithFeeGrantee保存在ctx中, gasPreHandler, gasHandler需调用feegrant.UseGrantedFees扣除额度
+//TxQcp中的TxStd不校验, 其gas费由TxQcp处理
+type GasPayerDecorator struct {
+	handler MaxFeeHandler
+}
+
+func NewGasPayerDecorator(handler MaxFeeHandler) GasPayerDecorator {
+	return GasPayerDecorator{handler: handler}
+}
+
+func (gpd GasPayerDecorator) AnteHandle(cctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	payer := tx.ITxs[0].GetGasPayer()
+	if len(payer) == 0 || txStdFromChainID != "" {
+		return next(cctx, tx, txStdFromChainID, skipSigVerify)
+	}
+
+	signers := tx.GetSigners()
+	for _, signer := range signers {
+		if signer.Equals(payer) {
+			return next(cctx, tx, txStdFromChainID, skipSigVerify)
+		}
+	}
+
+	feeGrantMapper := GetFeeGrantMapper(cctx)
+	if feeGrantMapper == nil {
+		return cctx, types.ErrUnauthorized(fmt.Sprintf("gas payer %s is not a signer", payer)).Result()
+	}
+
+	//额度需足够支付tx最多消耗的gas费, 未设置MaxFeeHandler时max fee未知
+	var maxFee types.BaseCoins
+	if gpd.handler != nil {
+		if maxFee = gpd.handler(cctx, tx); maxFee == nil {
+			maxFee = types.BaseCoins{}
+		}
+	}
+
+	grantee, err := feeGrantMapper.FindGrantee(cctx, payer, signers, maxFee)
+	if err != nil {
+		return cctx, types.ErrUnauthorized(err.Error()).Result()
+	}
+
+	return next(feegrant.WithFeeGrantee(cctx, grantee), tx, txStdFromChainID, skipSigVerify)
+}
+
+//GasPreDecorator deliverTx阶段对非跨链的TxStd执行gasPreHandler. checkTx阶段及TxQcp中的TxStd不执行, TxQcp在deliverTxQcp中执行gasPreHandler
+type GasPreDecorator struct {
+	handler GasPreHandler
+}
+
+func NewGasPreDecorator(handler GasPreHandler) GasPreDecorator {
+	return GasPreDecorator{handler: handler}
+}
+
+func (gpd GasPreDecorator) AnteHandle(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (ctx.Context, types.Result) {
+	if gpd.handler != nil && !ctx.IsCheckTx() && txStdFromChainID == "" {
+		if err := gpd.handler(ctx, tx.ITxs[0].GetGasPayer()); err != nil {
+			return ctx, err.Result()
+		}
+	}
+
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
@@ -8,7 +8,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/QOSGroup/qbase/account"
 	"github.com/QOSGroup/qbase/consensus"
 	"github.com/QOSGroup/qbase/mapper"
 	"github.com/QOSGroup/qbase/qcp"
@@ -16,6 +15,7 @@ import (
 
 	ctx "github.com/QOSGroup/qbase/context"
 	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/store/snapshots"
 	"github.com/QOSGroup/qbase/txs"
 	"github.com/QOSGroup/qbase/types"
 	"github.com/QOSGroup/qbase/validator"
@@ -40,12 +40,32 @@ type BaseApp struct {
 	cms    store.CommitMultiStore // Main (uncached) state
 
 	// may be nil
-	initChainer  InitChainHandler  // initialize state with validators and state blob
-	beginBlocker BeginBlockHandler // logic to run before any txs
-	endBlocker   EndBlockHandler   // logic to run after all txs, and to determine valset changes
+	initChainer    InitChainHandler   // initialize state with validators and state blob
+	exportAppState ExportAppStateFunc // export state blob for a new genesis
+	beginBlocker   BeginBlockHandler  // logic to run before any txs
+	endBlocker     EndBlockHandler    // logic to run after all txs, and to determine valset changes
 
-	gasPreHandler GasPreHandler // gas fee pre handler
-	gasHandler    GasHandler    // gas fee handler
+	anteHandler     AnteHandler     // ante handler for txStd, run before ITx.Exec
+	feeCheckHandler FeeCheckHandler // check tx fee against minimumFees in checkTx
+	maxFeeHandler   MaxFeeHandler   // max gas fee of tx, checked against fee allowances
+	gasPreHandler   GasPreHandler   // gas fee pre handler
+	gasHandler      GasHandler      // gas fee handler
+
+	minimumFees []types.Coin // minimum fees for checkTx, set by app.toml: minimum_fees
+
+	maxMemoLength int // max length of TxStd memo, 0 for ctx.DefaultMaxMemoLength
+
+	sigCache *SigVerifyCache // verified txs in checkTx or PreVerifyTxs, skip signature verification in deliverTx. nil if disabled
+
+	snapshotManager    *snapshots.Manager // state sync snapshots, nil if disabled
+	snapshotInterval   int64              // create snapshot every snapshotInterval blocks
+	snapshotKeepRecent int                // number of recent snapshots to keep, 0 keeps all
+
+	qcpOutTxMigrationHeight int64 // migrate legacy qcp out tx keys at this height, see: qcp.MigrateOutTxKeys
+
+	haltHeight      int64                     // halt the node after committing this height, 0 disables halting
+	storeUpgrades   *store.StoreUpgrades      // store changes applied when loading a version, may be nil
+	upgradeHandlers map[string]UpgradeHandler // upgrade handlers by plan name, see: upgrade.UpgradeMapper
 
 	//--------------------
 	// Volatile
@@ -62,6 +82,8 @@ type BaseApp struct {
 
 	//注册自定义查询处理
 	customQueryHandler CustomQueryHandler
+	//按mapper名称注册的查询处理
+	queryRouter map[string]QueryHandler
 	//注册的mapper
 	registerMappers map[string]mapper.IMapper
 
@@ -88,6 +110,9 @@ func NewBaseApp(name string, cfg *cfg.Config, logger log.Logger, db dbm.DB, regi
 		cms:             store.NewCommitMultiStore(db),
 		cdc:             cdc,
 		registerMappers: make(map[string]mapper.IMapper),
+		queryRouter:     make(map[string]QueryHandler),
+		upgradeHandlers: make(map[string]UpgradeHandler),
+		sigCache:        NewSigVerifyCache(DefaultSigVerifyCacheSize),
 	}
 
 	for _, option := range options {
@@ -97,6 +122,7 @@ func NewBaseApp(name string, cfg *cfg.Config, logger log.Logger, db dbm.DB, regi
 	app.registerQcpMapper()
 	app.RegisterMapper(consensus.NewConsensusMapper(cdc))
 	app.RegisterMapper(validator.NewValidatorMapper())
+	app.registerQueryHandlers()
 	return app
 }
 
@@ -127,18 +153,18 @@ func (app *BaseApp) mountStore(key store.StoreKey, typ store.StoreType) {
 	app.cms.MountStoreWithDB(key, typ, nil)
 }
 
-// load latest application version
+// load latest application version, applying the store upgrades set by SetStoreUpgrades
 func (app *BaseApp) LoadLatestVersion() error {
-	err := app.cms.LoadLatestVersion()
+	err := app.cms.LoadLatestVersionAndUpgrade(app.storeUpgrades)
 	if err != nil {
 		return err
 	}
 	return app.initFromStore()
 }
 
-// load application version
+// load application version, applying the store upgrades set by SetStoreUpgrades
 func (app *BaseApp) LoadVersion(version int64) error {
-	err := app.cms.LoadVersion(version)
+	err := app.cms.LoadVersionAndUpgrade(version, app.storeUpgrades)
 	if err != nil {
 		return err
 	}
@@ -157,7 +183,16 @@ func (app *BaseApp) LastBlockHeight() int64 {
 
 // initializes the remaining logic from app.cms
 func (app *BaseApp) initFromStore() error {
+	if app.anteHandler == nil {
+		app.anteHandler = ChainAnteDecorators(app.DefaultAnteDecorators()...)
+	}
 	app.setCheckState(abci.Header{})
+
+	//存在旧版本的qcp tx/out数据时, 必须设置尚未到达的迁移高度
+	if qcp.HasLegacyOutTxKeys(GetQcpMapper(app.checkState.ctx)) && app.qcpOutTxMigrationHeight <= app.LastBlockHeight() {
+		return fmt.Errorf("legacy qcp out txs exist, set SetQcpOutTxKeyMigrationHeight to a height above %d", app.LastBlockHeight())
+	}
+
 	app.Seal()
 	return nil
 }
@@ -185,6 +220,15 @@ func (app *BaseApp) setCheckState(header abci.Header) {
 		ms:  ms,
 		ctx: ctx.NewContext(ms, header, true, app.Logger, app.registerMappers),
 	}
+
+	//最低手续费仅在checkTx阶段生效
+	if len(app.minimumFees) != 0 {
+		app.checkState.ctx = app.checkState.ctx.WithMinimumFees(app.minimumFees)
+	}
+
+	if app.maxMemoLength > 0 {
+		app.checkState.ctx = app.checkState.ctx.WithMaxMemoLength(app.maxMemoLength)
+	}
 }
 
 func (app *BaseApp) setDeliverState(header abci.Header) {
@@ -196,6 +240,10 @@ func (app *BaseApp) setDeliverState(header abci.Header) {
 
 	//注入txQcpResultHandler
 	app.deliverState.ctx = app.deliverState.ctx.WithTxQcpResultHandler(app.txQcpResultHandler)
+
+	if app.maxMemoLength > 0 {
+		app.deliverState.ctx = app.deliverState.ctx.WithMaxMemoLength(app.maxMemoLength)
+	}
 }
 
 //______________________________________________________________________________
@@ -247,12 +295,37 @@ func initQCP(ctx ctx.Context, cdc *go_amino.Codec, appState []byte) {
 	if err != nil {
 		panic(err)
 	}
+	if len(gs.QCPAdmin) > 0 {
+		GetQcpMapper(ctx).SetAdmin(gs.QCPAdmin)
+	}
 	if len(gs.QCPs) > 0 {
 		qcpMapper := GetQcpMapper(ctx)
 		for _, qcp := range gs.QCPs {
-			qcpMapper.SetChainInTrustPubKey(qcp.ChainId, qcp.PubKey)
+			if qcp.PubKey != nil {
+				qcpMapper.SetChainInTrustPubKey(qcp.ChainId, qcp.PubKey)
+			}
+			if len(qcp.Validators) > 0 {
+				qcpMapper.SetChainInValidators(qcp.ChainId, qcp.ValidatorSet())
+			}
+			if qcp.InSequence > 0 {
+				qcpMapper.SetMaxChainInSequence(qcp.ChainId, qcp.InSequence)
+			}
+			if qcp.OutSequence > 0 {
+				qcpMapper.SetMaxChainOutSequence(qcp.ChainId, qcp.OutSequence)
+			}
 		}
 	}
+
+	//导出状态时保存的未完成跨链输出交易
+	var outGenesis struct {
+		QcpOut []*qcp.OutChainState `json:"qcp_out"`
+	}
+	if err := cdc.UnmarshalJSON(appState, &outGenesis); err != nil {
+		panic(err)
+	}
+	for _, state := range outGenesis.QcpOut {
+		qcp.ImportOutChainState(GetQcpMapper(ctx), state)
+	}
 }
 
 func splitPath(requestPath string) (path []string) {
@@ -295,6 +368,12 @@ func handleQueryApp(app *BaseApp, path []string, req abci.RequestQuery) (res abc
 				Codespace: string(types.CodespaceRoot),
 				Value:     []byte(version.GetVersion()),
 			}
+		case "simulate":
+			//path: /app/simulate/skipsig 时不校验签名
+			skipSigVerify := len(path) >= 3 && path[2] == SimulateSkipSigVerify
+			result = app.Simulate(req.Data, skipSigVerify)
+		case "qcp":
+			return handleQueryQcp(app, path[2:])
 		default:
 			result = types.ErrUnknownRequest(fmt.Sprintf("Unknown query: %s", path)).Result()
 		}
@@ -310,28 +389,70 @@ func handleQueryApp(app *BaseApp, path []string, req abci.RequestQuery) (res abc
 	return types.ErrUnknownRequest(msg).QueryResult()
 }
 
-func handlerCustomQuery(app *BaseApp, path []string, req abci.RequestQuery) (res abci.ResponseQuery) {
+//handleQueryQcp qcp查询, 基于最新高度
+//path: /app/qcp/out-txs/{chainId}/{fromSeq}/{limit}: 按序号返回输出到chainId的qcp tx
+func handleQueryQcp(app *BaseApp, path []string) (res abci.ResponseQuery) {
+	if len(path) != 4 || path[0] != "out-txs" {
+		return types.ErrUnknownRequest(fmt.Sprintf("Unknown qcp query: %s", path)).QueryResult()
+	}
 
-	if app.customQueryHandler == nil {
-		return types.ErrUnknownRequest("CustomQueryHandler not register").QueryResult()
+	fromSeq, err := strconv.ParseInt(path[2], 10, 64)
+	if err != nil {
+		return types.ErrUnknownRequest(fmt.Sprintf("invalid fromSeq: %s", path[2])).QueryResult()
+	}
+	limit, err := strconv.Atoi(path[3])
+	if err != nil {
+		return types.ErrUnknownRequest(fmt.Sprintf("invalid limit: %s", path[3])).QueryResult()
 	}
 
 	ctx := ctx.NewContext(app.cms.CacheMultiStore(), app.checkState.ctx.BlockHeader(), true, app.Logger, app.registerMappers)
-	bz, err := app.customQueryHandler(ctx, path[1:], req)
+	txQcps := GetQcpMapper(ctx).IterateChainOutTxs(path[1], fromSeq, limit)
+
+	return abci.ResponseQuery{
+		Code:   uint32(types.CodeOK),
+		Value:  app.cdc.MustMarshalBinaryBare(txQcps),
+		Height: app.LastBlockHeight(),
+	}
+}
+
+//handlerCustomQuery 自定义查询: path: /custom/{name}/...
+//优先由name注册的QueryHandler处理, 未注册或内置handler不支持该路由时交由CustomQueryHandler处理
+func handlerCustomQuery(app *BaseApp, path []string, req abci.RequestQuery) (res abci.ResponseQuery) {
+	if len(path) < 2 || path[1] == "" {
+		return types.ErrUnknownRequest("no custom query route provided").QueryResult()
+	}
+
+	handler, route := app.queryRouter[path[1]], path[2:]
+	if handler == nil {
+		if app.customQueryHandler == nil {
+			return types.ErrUnknownRequest(fmt.Sprintf("unknown query route: %s", path[1])).QueryResult()
+		}
+		handler, route = QueryHandler(app.customQueryHandler), path[1:]
+	}
+
+	ctx, err := app.newQueryContext(req.Height)
+	if err != nil {
+		return err.QueryResult()
+	}
 
+	bz, err := handler(ctx, route, req)
+	if _, ok := err.(unknownRouteError); ok && app.customQueryHandler != nil {
+		bz, err = app.customQueryHandler(ctx, path[1:], req)
+	}
 	if err != nil {
 		return abci.ResponseQuery{
 			Code:      uint32(err.Code()),
 			Codespace: string(err.Codespace()),
 			Log:       err.ABCILog(),
+			Height:    ctx.BlockHeight(),
 		}
 	}
 
 	return abci.ResponseQuery{
-		Code:  uint32(types.CodeOK),
-		Value: bz,
+		Code:   uint32(types.CodeOK),
+		Value:  bz,
+		Height: ctx.BlockHeight(),
 	}
-
 }
 
 func handleQueryStore(app *BaseApp, path []string, req abci.RequestQuery) (res abci.ResponseQuery) {
@@ -367,9 +488,24 @@ func (app *BaseApp) BeginBlock(req abci.RequestBeginBlock) (res abci.ResponseBeg
 	//重置block tx index
 	app.deliverState.ctx = app.deliverState.ctx.ResetBlockTxIndex()
 
+	//根据共识参数Block.MaxGas设置区块gas上限
+	app.deliverState.ctx = app.deliverState.ctx.WithBlockGasMeter(newBlockGasMeter(app.deliverState.ctx))
+
+	//在指定高度迁移旧版本的qcp tx/out数据
+	if app.qcpOutTxMigrationHeight > 0 && req.Header.Height == app.qcpOutTxMigrationHeight {
+		migrated := qcp.MigrateOutTxKeys(GetQcpMapper(app.deliverState.ctx))
+		app.Logger.Info("migrated qcp out txs", "height", req.Header.Height, "count", migrated)
+	}
+
+	app.deliverState.ctx = app.deliverState.ctx.WithEventManager(types.NewEventManager())
+
+	//执行到达高度的升级计划
+	app.applyUpgrade(app.deliverState.ctx)
+
 	if app.beginBlocker != nil {
 		res = app.beginBlocker(app.deliverState.ctx, req)
 	}
+	res.Events = append(res.Events, app.deliverState.ctx.EventManager().ABCIEvents()...)
 
 	valMapper := validator.GetValidatorMapper(app.deliverState.ctx)
 	valMapper.SetLastBlockProposer(types.ConsAddress(req.Header.GetProposerAddress()))
@@ -412,19 +548,20 @@ func (app *BaseApp) CheckTx(req abci.RequestCheckTx) (res abci.ResponseCheckTx)
 	}()
 
 	// 初始化context相关数据
-	ctx := app.checkState.ctx.WithTxBytes(req.Tx)
+	ctx := app.checkState.ctx.WithTxBytes(req.Tx).WithEventManager(types.NewEventManager())
 
 	switch implTx := tx.(type) {
 	case *txs.TxStd:
 		ctx = setGasMeter(ctx, implTx)
-		result, _ = app.checkTxStd(ctx, implTx, "")
+		result, _ = app.checkTxStd(ctx, implTx, "", false)
 	case *txs.TxQcp:
 		ctx = setGasMeter(ctx, implTx.TxStd)
-		result = app.checkTxQcp(ctx, implTx)
+		result = app.checkTxQcp(ctx, implTx, false)
 	default:
 		result = types.ErrInternal("not support itx type").Result()
 	}
 
+	result.Events = ctx.EventManager().Events().AppendEvents(result.Events)
 	return toResponseCheckTx(result)
 }
 
@@ -440,7 +577,8 @@ func toResponseCheckTx(result types.Result) abci.ResponseCheckTx {
 }
 
 //checkTxStd: checkTx阶段对TxStd进行校验
-func (app *BaseApp) checkTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string) (result types.Result, newctx ctx.Context) {
+//skipSigVerify: 是否跳过签名校验,仅在模拟执行时使用
+func (app *BaseApp) checkTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (result types.Result, newctx ctx.Context) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -458,119 +596,13 @@ func (app *BaseApp) checkTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID
 		result.GasWanted = uint64(tx.MaxGas.Int64())
 	}()
 
-	//1. 校验txStd基础信息
-	err := tx.ValidateBasicData(ctx, true, ctx.ChainID())
-	if err != nil {
-		result = err.Result()
-		return
-	}
-
-	//2. 校验签名
-	newctx, res := app.validateTxStdUserSignatureAndNonce(ctx, tx, txStdFromChainID)
-	if !res.IsOK() {
-		result = res
-		return
-	}
-
-	return
-}
-
-//校验txstd用户签名，签名通过后，增加用户none
-func (app *BaseApp) validateTxStdUserSignatureAndNonce(cctx ctx.Context, tx *txs.TxStd, qcpFromChainID string) (newctx ctx.Context, result types.Result) {
-	defer func() {
-		if newctx.IsZero() {
-			newctx = cctx
-		}
-	}()
-
-	//未注册accountProto时， 不做签名校验
-	//用户可以在itx.validate()中自定义签名校验逻辑
-	accounMapper := GetAccountMapper(cctx)
-
-	if accounMapper == nil {
-		app.Logger.Info("accountMapper not setup....")
-		return
-	}
-
-	//签名者为空则不校验签名
-	signers := tx.GetSigners()
-	signatures := tx.Signature
-
-	if len(signatures) != len(signers) {
-		result = types.ErrUnauthorized(fmt.Sprintf("signatures and signers not match. signatures count: %d , non-dup signers count: %d ", len(signatures), len(signers))).Result()
-		return
-	}
-
-	if len(signers) == 0 {
-		//TODO: !!!!Dangerous if signers is empty
-		// if qcpFromChainID == tx.ChainID {
-		// 	result = types.ErrUnauthorized("no signers in TxStd's ITx").Result()
-		// }
-		return
-	}
-
-	signerAccount := make([]account.Account, len(signers))
-	for i, addr := range signers {
-		acc := accounMapper.GetAccount(addr)
-		if acc == nil {
-			acc = accounMapper.NewAccountWithAddress(addr)
-		}
-		signerAccount[i] = acc
-	}
-
-	//校验account address,nonce是否与签名中一致. 并设置account pubkey
-	for i := 0; i < len(signatures); i++ {
-		acc := signerAccount[i]
-		signature := signatures[i]
-
-		if signature.Pubkey != nil {
-			pubkeyAddress := types.AccAddress(signature.Pubkey.Address().Bytes())
-			if !acc.GetAddress().Equals(pubkeyAddress) {
-				result = types.ErrInternal(fmt.Sprintf("invalid address. expect: %s, got: %s", acc.GetAddress(), pubkeyAddress)).Result()
-				return
-			}
-		}
-
-		//issue-68 https://github.com/QOSGroup/qbase/issues/68
-		if signature.Nonce != acc.GetNonce()+1 {
-			result = types.ErrInternal(fmt.Sprintf("invalid nonce. expect: %d, got: %d", acc.GetNonce()+1, signature.Nonce)).Result()
-			return
-		}
-
-		if acc.GetPublicKey() == nil {
-			if signature.Pubkey == nil {
-				result = types.ErrInternal("txstd's pubkey is nil in signature").Result()
-				return
-			}
-			acc.SetPublicKey(signature.Pubkey)
-		}
-	}
-
-	//校验签名并增加账户nonce
-	for i := 0; i < len(signatures); i++ {
-		acc := signerAccount[i]
-		signature := signatures[i]
-		pubkey := acc.GetPublicKey()
-		//1. 根据账户nonce及txStd源chainID生成signData
-		signBytes := tx.BuildSignatureBytes(acc.GetNonce()+1, qcpFromChainID)
-		if !pubkey.VerifyBytes(signBytes, signature.Signature) {
-			result = types.ErrInternal("txstd's signature verification failed").Result()
-			return
-		}
-
-		//acccount nonce increment
-		acc.SetNonce(acc.GetNonce() + 1)
-		accounMapper.SetAccount(acc)
-
-		signerAccount[i] = acc
-	}
-
-	newctx = cctx.WithValue(ctx.ContextKeySigners, signerAccount)
+	//执行AnteHandler: 基础校验、签名校验等
+	newctx, result = app.anteHandler(ctx, tx, txStdFromChainID, skipSigVerify)
 	return
 }
 
 //checkTxQcp: checkTx阶段对TxQcp进行校验
-func (app *BaseApp) checkTxQcp(ctx ctx.Context, tx *txs.TxQcp) (result types.Result) {
+func (app *BaseApp) checkTxQcp(ctx ctx.Context, tx *txs.TxQcp, skipSigVerify bool) (result types.Result) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -601,16 +633,29 @@ func (app *BaseApp) checkTxQcp(ctx ctx.Context, tx *txs.TxQcp) (result types.Res
 		return
 	}
 
-	//3. 校验TxQcp签名
-	res := app.validateTxQcpSignature(ctx, tx)
-	if !res.IsOK() {
-		result = res
-		return
+	//3. 校验TxQcp签名. 来源链设置了可信验证人集合时, 使用轻客户端模式校验TxQcp存在性证明
+	if !skipSigVerify {
+		var res types.Result
+		if GetQcpMapper(ctx).GetChainInValidators(tx.From) != nil {
+			res = app.validateTxQcpProof(ctx, tx)
+		} else {
+			res = app.validateTxQcpSignature(ctx, tx)
+		}
+		if !res.IsOK() {
+			result = res
+			return
+		}
 	}
 
 	//4. 更新qcp in sequence
 	GetQcpMapper(ctx).SetMaxChainInSequence(tx.From, maxInSequence+1)
 
+	//deliverTx时校验是否超时. 超时的tx仍占用sequence, 超时结果由deliverTxQcp返回来源链
+	//checkTx时不拒绝超时的tx, 否则其后续sequence的tx将无法执行
+	if !ctx.IsCheckTx() && tx.IsTimeout(ctx.BlockHeight(), ctx.BlockHeader().Time) {
+		return types.ErrQcpTimeout(fmt.Sprintf("txqcp timeout. height: %d, time: %d", tx.TimeoutHeight, tx.TimeoutTime)).Result()
+	}
+
 	return
 }
 
@@ -641,6 +686,14 @@ func (app *BaseApp) validateTxQcpSignature(ctx ctx.Context, qcpTx *txs.TxQcp) (r
 	return
 }
 
+//轻客户端模式下校验QcpTx存在性证明, 不校验中继签名
+func (app *BaseApp) validateTxQcpProof(ctx ctx.Context, qcpTx *txs.TxQcp) (result types.Result) {
+	if err := GetQcpMapper(ctx).VerifyTxQcpProof(qcpTx); err != nil {
+		return types.ErrUnauthorized(fmt.Sprintf("txqcp's proof verification failed: %v", err)).Result()
+	}
+	return
+}
+
 // Implements ABCI
 func (app *BaseApp) DeliverTx(req abci.RequestDeliverTx) (res abci.ResponseDeliverTx) {
 
@@ -656,8 +709,20 @@ func (app *BaseApp) DeliverTx(req abci.RequestDeliverTx) (res abci.ResponseDeliv
 		return toResponseDeliverTx(result)
 	}
 
+	//区块gas已耗尽时不再执行tx
+	blockGasMeter := app.deliverState.ctx.BlockGasMeter()
+	if blockGasMeter.IsOutOfGas() {
+		result = types.ErrOutOfGas("block gas limit exceeded").Result()
+		return toResponseDeliverTx(result)
+	}
+
+	//tx消耗的gas计入区块gas
+	defer func() {
+		consumeBlockGas(blockGasMeter, uint64(res.GasUsed))
+	}()
+
 	//初始化context相关数据
-	ctx := app.deliverState.ctx.WithTxBytes(req.Tx).WithVoteInfos(app.voteInfos)
+	ctx := app.deliverState.ctx.WithTxBytes(req.Tx).WithVoteInfos(app.voteInfos).WithEventManager(types.NewEventManager())
 
 	switch implTx := tx.(type) {
 	case *txs.TxStd:
@@ -668,6 +733,9 @@ func (app *BaseApp) DeliverTx(req abci.RequestDeliverTx) (res abci.ResponseDeliv
 		result = types.ErrInternal("not support itx type").Result()
 	}
 
+	//合并tx执行过程中通过EventManager产生的事件
+	result.Events = ctx.EventManager().Events().AppendEvents(result.Events)
+
 	// Tell the blockchain engine (i.e. Tendermint).
 	return toResponseDeliverTx(result)
 }
@@ -684,6 +752,32 @@ func toResponseDeliverTx(result types.Result) abci.ResponseDeliverTx {
 	}
 }
 
+//newBlockGasMeter 共识参数Block.MaxGas大于0时限制区块gas, 否则不限制
+func newBlockGasMeter(ctx ctx.Context) types.GasMeter {
+	consParams := GetConsParams(ctx)
+	if consParams != nil && consParams.Block != nil && consParams.Block.MaxGas > 0 {
+		return types.NewGasMeter(uint64(consParams.Block.MaxGas))
+	}
+	return types.NewInfiniteGasMeter()
+}
+
+//exceedBlockGasLimit 判断区块gas是否不足以支付gasUsed
+func exceedBlockGasLimit(blockGasMeter types.GasMeter, gasUsed uint64) bool {
+	limit := blockGasMeter.Limit()
+	if limit == 0 {
+		return false
+	}
+	return limit-blockGasMeter.GasConsumedToLimit() < gasUsed
+}
+
+//consumeBlockGas 扣除区块gas, 最多扣除至区块gas上限
+func consumeBlockGas(blockGasMeter types.GasMeter, gasUsed uint64) {
+	if exceedBlockGasLimit(blockGasMeter, gasUsed) {
+		gasUsed = blockGasMeter.Limit() - blockGasMeter.GasConsumedToLimit()
+	}
+	blockGasMeter.ConsumeGas(gasUsed, "block gas meter")
+}
+
 func setGasMeter(ctx ctx.Context, tx *txs.TxStd) ctx.Context {
 	var gm types.GasMeter
 	if ctx.BlockHeight() == 0 {
@@ -722,13 +816,7 @@ func (app *BaseApp) deliverTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainI
 	}()
 
 	ctx = setGasMeter(ctx, tx)
-	if nil != app.gasPreHandler {
-		err := app.gasPreHandler(ctx, tx.ITxs[0].GetGasPayer())
-		if err != nil {
-			return err.Result()
-		}
-	}
-	result, newctx := app.runTxStd(ctx, tx, txStdFromChainID)
+	result, newctx := app.runTxStd(ctx, tx, txStdFromChainID, false)
 
 	if !newctx.IsZero() {
 		ctx = newctx
@@ -737,9 +825,9 @@ func (app *BaseApp) deliverTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainI
 	return
 }
 
-func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string) (result types.Result, newctx ctx.Context) {
+func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (result types.Result, newctx ctx.Context) {
 
-	result, newctx = app.checkTxStd(ctx, tx, txStdFromChainID)
+	result, newctx = app.checkTxStd(ctx, tx, txStdFromChainID, skipSigVerify)
 	if !result.IsOK() {
 		return
 	}
@@ -749,7 +837,7 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 	}
 
 	//3. 执行exec
-	msCache := getState(app, false).CacheMultiStore()
+	msCache := ctx.MultiStore().CacheMultiStore()
 	if msCache.TracingEnabled() {
 		msCache = msCache.SetTracingContext(store.TraceContext(
 			map[string]interface{}{"txHash": cmn.HexBytes(tmhash.Sum(ctx.TxBytes())).String()},
@@ -758,15 +846,21 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 
 	runCtx := ctx.WithMultiStore(msCache)
 
-	for _, itx := range tx.ITxs {
-		singleResult, crossTxQcp := itx.Exec(runCtx)
+	//按ITx记录执行日志及事件, 保存在result.Log中
+	var logs types.ABCIMessageLogs
+
+	for i, itx := range tx.ITxs {
+		//每个ITx使用独立的EventManager, 其事件与singleResult.Events合并
+		itxCtx := runCtx.WithEventManager(types.NewEventManager())
+		singleResult, crossTxQcp := itx.Exec(itxCtx)
+		events := itxCtx.EventManager().Events().AppendEvents(singleResult.Events)
+
 		result.Code = singleResult.Code
 		if !singleResult.IsOK() {
+			logs = append(logs, types.NewABCIMessageLog(uint16(i), false, singleResult.Log, events))
 			break
 		}
 
-		result.Events = result.Events.AppendEvents(singleResult.Events)
-
 		//4. 根据crossTxQcp结果判断是否保存跨链结果
 		// crossTxQcp 不为空时，需要将跨链结果保存
 		if crossTxQcp != nil && app.txQcpSigner == nil {
@@ -775,7 +869,7 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 
 		if crossTxQcp != nil && crossTxQcp.TxStd != nil {
 			txQcp := saveCrossChainResult(runCtx, crossTxQcp, false, app.txQcpSigner)
-			result.Events = result.Events.AppendEvents(types.Events{
+			events = events.AppendEvents(types.Events{
 				types.NewEvent(
 					types.EventTypeMessage,
 					types.NewAttribute(types.AttributeKeyModule, qcp.EventModule),
@@ -786,7 +880,11 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 				),
 			})
 		}
+
+		result.Events = result.Events.AppendEvents(events)
+		logs = append(logs, types.NewABCIMessageLog(uint16(i), true, singleResult.Log, events))
 	}
+	result.Log = logs.String()
 
 	if app.gasHandler != nil {
 		// 第一个Tx的签名者支付gas费
@@ -797,6 +895,18 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 		result.GasUsed = gasUsed
 	}
 
+	//deliverTx: 区块gas不足时tx执行失败, 不保存执行结果
+	if result.IsOK() && !ctx.IsCheckTx() {
+		gasUsed := result.GasUsed
+		if gasUsed == 0 {
+			gasUsed = ctx.GasMeter().GasConsumed()
+		}
+		if exceedBlockGasLimit(ctx.BlockGasMeter(), gasUsed) {
+			result = types.ErrOutOfGas("block gas limit exceeded").Result()
+			result.GasUsed = gasUsed
+		}
+	}
+
 	if result.IsOK() {
 		msCache.Write()
 	}
@@ -804,6 +914,25 @@ func (app *BaseApp) runTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID st
 	return
 }
 
+//isTimeoutResult 返回的执行结果对应的tx是否已通过超时证明处理
+func isTimeoutResult(ctx ctx.Context, tx *txs.TxQcp) bool {
+	for _, itx := range tx.TxStd.ITxs {
+		if qcpResult, ok := itx.(*txs.QcpTxResult); ok && GetQcpMapper(ctx).IsChainOutTxTimeout(tx.From, qcpResult.QcpOriginalSequence) {
+			return true
+		}
+	}
+	return false
+}
+
+//ackTxQcpResult 确认执行结果对应的输出tx
+func ackTxQcpResult(ctx ctx.Context, tx *txs.TxQcp) {
+	for _, itx := range tx.TxStd.ITxs {
+		if qcpResult, ok := itx.(*txs.QcpTxResult); ok {
+			GetQcpMapper(ctx).AckChainOutTx(tx.From, qcpResult.QcpOriginalSequence)
+		}
+	}
+}
+
 func saveCrossChainResult(ctx ctx.Context, crossTxQcp *txs.TxQcp, isResult bool, txQcpSigner crypto.PrivKey) *txs.TxQcp {
 
 	qcpMapper := GetQcpMapper(ctx)
@@ -816,6 +945,9 @@ func saveCrossChainResult(ctx ctx.Context, crossTxQcp *txs.TxQcp, isResult bool,
 		TxIndex:     ctx.BlockTxIndex(),
 		IsResult:    isResult,
 		Extends:     crossTxQcp.Extends,
+
+		TimeoutHeight: crossTxQcp.TimeoutHeight,
+		TimeoutTime:   crossTxQcp.TimeoutTime,
 	}
 
 	return qcpMapper.SignAndSaveTxQcp(txQcp, txQcpSigner)
@@ -882,13 +1014,23 @@ func (app *BaseApp) deliverTxQcp(ctx ctx.Context, tx *txs.TxQcp) (result types.R
 		}
 	}
 
-	result = app.checkTxQcp(ctx, tx)
+	result = app.checkTxQcp(ctx, tx, false)
 	if !result.IsOK() {
 		return result
 	}
 
+	if tx.IsResult {
+		//来源链已通过超时证明处理的tx, 忽略目标链返回的执行结果
+		timeout := isTimeoutResult(ctx, tx)
+		//收到执行结果后删除对应的输出tx, 不计算GAS
+		ackTxQcpResult(ctx.WithGasMeter(types.NewInfiniteGasMeter()), tx)
+		if timeout {
+			return
+		}
+	}
+
 	//5. 执行内部txStd
-	result, newctx := app.runTxStd(ctx, tx.TxStd, tx.From)
+	result, newctx := app.runTxStd(ctx, tx.TxStd, tx.From, false)
 
 	if !newctx.IsZero() {
 		ctx = newctx
@@ -912,9 +1054,11 @@ func (app *BaseApp) EndBlock(req abci.RequestEndBlock) (res abci.ResponseEndBloc
 		app.deliverState.ms = app.deliverState.ms.SetTracingContext(nil).(store.CacheMultiStore)
 	}
 
+	app.deliverState.ctx = app.deliverState.ctx.WithEventManager(types.NewEventManager())
 	if app.endBlocker != nil {
 		res = app.endBlocker(app.deliverState.ctx, req)
 	}
+	res.Events = append(res.Events, app.deliverState.ctx.EventManager().ABCIEvents()...)
 
 	valMapper := validator.GetValidatorMapper(app.deliverState.ctx)
 	if b := valMapper.IsEnableValidatorUpdated(); b {
@@ -944,6 +1088,16 @@ func (app *BaseApp) Commit() (res abci.ResponseCommit) {
 	// Empty the Deliver state
 	app.deliverState = nil
 
+	//按间隔创建快照. 同步执行, 避免导出过程中下一次Commit裁剪该版本
+	if app.snapshotManager != nil && app.snapshotInterval > 0 && commitID.Version%app.snapshotInterval == 0 {
+		app.snapshot(commitID.Version)
+	}
+
+	//到达停止高度时停止节点
+	if app.haltHeight > 0 && header.Height >= app.haltHeight {
+		app.halt()
+	}
+
 	return abci.ResponseCommit{
 		Data: commitID.Hash,
 	}
@@ -1,14 +1,18 @@
 package baseabci
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/QOSGroup/qbase/account"
 	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/feegrant"
+	"github.com/QOSGroup/qbase/qcp"
 	"github.com/QOSGroup/qbase/store"
 	"github.com/QOSGroup/qbase/txs"
 	"github.com/QOSGroup/qbase/types"
@@ -18,6 +22,7 @@ import (
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/multisig"
 	"github.com/tendermint/tendermint/libs/log"
 	dbm "github.com/tendermint/tm-db"
 )
@@ -29,9 +34,12 @@ func defaultLogger() log.Logger {
 }
 
 func mockApp() *BaseApp {
+	return mockAppWithDB(dbm.NewMemDB())
+}
+
+func mockAppWithDB(db dbm.DB) *BaseApp {
 
 	logger := defaultLogger()
-	db := dbm.NewMemDB()
 
 	app := NewBaseApp("test", nil, logger, db, func(cdc *go_amino.Codec) {
 		cdc.RegisterConcrete(&transferTx{}, "baseapp/test/transferTx", nil)
@@ -341,7 +349,7 @@ func TestTxQcp(t *testing.T) {
 	// -------------------------------------
 
 	for i := int64(1); i <= seq; i++ {
-		key := []byte(fmt.Sprintf("tx/out/%s/%d", cid, i))
+		key := qcp.BuildOutSequenceTxKey(cid, i)
 		query := abci.RequestQuery{
 			Path:   "/store/qcp/key",
 			Data:   key,
@@ -369,6 +377,125 @@ func TestTxQcp(t *testing.T) {
 
 }
 
+func TestTxQcpTimeout(t *testing.T) {
+
+	app := mockApp()
+
+	var handled []int64
+	app.RegisterTxQcpResultHandler(func(ctx context.Context, txQcpResult interface{}) {
+		qcpResult, _ := txQcpResult.(*txs.QcpTxResult)
+		handled = append(handled, qcpResult.QcpOriginalSequence)
+	})
+
+	signer := ed25519.GenPrivKey()
+	app.RegisterTxQcpSigner(signer)
+
+	app.LoadLatestVersion()
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	newCtx := app.NewContext(true, abci.Header{})
+	accMapper := GetAccountMapper(newCtx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+
+	signTxQcp := func(txQcp *txs.TxQcp) []byte {
+		signature, _ := txQcp.SignTx(signer)
+		txQcp.Sig.Pubkey = signer.PubKey()
+		txQcp.Sig.Signature = signature
+		return app.GetCdc().MustMarshalBinaryBare(txQcp)
+	}
+
+	//seq 1在高度2超时, seq 2不超时. 超时的tx不执行, 账户nonce不变
+	var txQcpBytes [][]byte
+	for i := int64(1); i <= 2; i++ {
+		acc := accMapper.GetAccount(pidAccount1.GetAddress())
+		acc.SetNonce(1)
+		txQcp := txs.NewTxQCP(createTransformTxWithNoQcpTx(acc, pidAccount2, 1000), cid, cid, i, 1, 0, false, "")
+		if i == 1 {
+			txQcp.TimeoutHeight = 2
+		}
+		txQcpBytes = append(txQcpBytes, signTxQcp(txQcp))
+	}
+
+	//checkTx不拒绝超时的tx
+	for _, txQcpByte := range txQcpBytes {
+		res := app.CheckTx(abci.RequestCheckTx{Tx: txQcpByte})
+		require.Equal(t, int64(0), int64(res.Code))
+	}
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	res := app.DeliverTx(abci.RequestDeliverTx{Tx: txQcpBytes[0]})
+	require.Equal(t, uint32(types.CodeQcpTimeout), res.Code)
+	res = app.DeliverTx(abci.RequestDeliverTx{Tx: txQcpBytes[1]})
+	require.Equal(t, uint32(types.CodeOK), res.Code)
+
+	qcpMapper := GetQcpMapper(app.deliverState.ctx)
+	require.Equal(t, int64(2), qcpMapper.GetMaxChainInSequence(cid))
+	qcpResult := qcpMapper.GetChainOutTxs(cid, 1).TxStd.ITxs[0].(*txs.QcpTxResult)
+	require.Equal(t, types.CodeQcpTimeout, qcpResult.Result.Code)
+
+	//已通过超时证明处理的tx, 忽略返回的执行结果
+	qcpMapper.SetChainOutTxTimeout(cid, 5)
+	for i, originalSeq := range []int64{5, 6} {
+		stdTx := txs.NewTxStd(txs.NewQcpTxResult(types.Result{}, originalSeq, "", ""), cid, types.NewInt(10000))
+		res = app.DeliverTx(abci.RequestDeliverTx{Tx: signTxQcp(txs.NewTxQCP(stdTx, cid, cid, int64(i)+3, 1, 0, true, ""))})
+		require.Equal(t, uint32(types.CodeOK), res.Code)
+	}
+	require.Equal(t, []int64{6}, handled)
+
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+}
+
+func TestTxQcpGasPayer(t *testing.T) {
+
+	app := mockApp()
+
+	signer := ed25519.GenPrivKey()
+	app.RegisterTxQcpSigner(signer)
+
+	app.LoadLatestVersion()
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+	pidAccount3 := getAccount(accMapper, int64(3))
+
+	//TxQcp中TxStd的gas payer非签名者且未注册feegrant mapper时不拒绝
+	tx := &transferTx{
+		FromUsers: []types.AccAddress{pidAccount1.GetAddress()},
+		ToUsers:   []types.AccAddress{pidAccount2.GetAddress()},
+		Amount:    1000,
+		GasPayer:  pidAccount3.GetAddress(),
+	}
+	stdTx := txs.NewTxStd(tx, cid, types.NewInt(50000))
+	sig, err := stdTx.SignTx(pidAccount1.PrivKey, 1, cid, cid)
+	require.Nil(t, err)
+	stdTx.Signature = []txs.Signature{{Pubkey: pidAccount1.PrivKey.PubKey(), Signature: sig, Nonce: 1}}
+
+	txQcp := txs.NewTxQCP(stdTx, cid, cid, 1, 1, 0, false, "")
+	signature, _ := txQcp.SignTx(signer)
+	txQcp.Sig.Pubkey = signer.PubKey()
+	txQcp.Sig.Signature = signature
+	txQcpBytes := app.GetCdc().MustMarshalBinaryBare(txQcp)
+
+	res := app.CheckTx(abci.RequestCheckTx{Tx: txQcpBytes})
+	require.Equal(t, uint32(types.CodeOK), res.Code, res.Log)
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	deliverRes := app.DeliverTx(abci.RequestDeliverTx{Tx: txQcpBytes})
+	require.Equal(t, uint32(types.CodeOK), deliverRes.Code, deliverRes.Log)
+
+	//TxStd执行结果作为QcpTxResult返回来源链
+	qcpResult := GetQcpMapper(app.deliverState.ctx).GetChainOutTxs(cid, 1).TxStd.ITxs[0].(*txs.QcpTxResult)
+	require.Equal(t, types.CodeOK, qcpResult.Result.Code, qcpResult.Result.Log)
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+}
+
 func TestCrossStdTx(t *testing.T) {
 
 	app := mockApp()
@@ -444,7 +571,7 @@ func TestCrossStdTx(t *testing.T) {
 	require.Equal(t, int64(5), seq)
 
 	for i := int64(1); i <= seq; i++ {
-		k2 := []byte(fmt.Sprintf("tx/out/%s/%d", cid, i))
+		k2 := qcp.BuildOutSequenceTxKey(cid, i)
 		queryHeight2 := abci.RequestQuery{
 			Path:   "/store/qcp/key",
 			Data:   k2,
@@ -463,6 +590,13 @@ func TestCrossStdTx(t *testing.T) {
 		require.Equal(t, i-1, outQcpTx.TxIndex)
 	}
 
+	res = app.Query(abci.RequestQuery{Path: fmt.Sprintf("/app/qcp/out-txs/%s/2/3", cid)})
+	require.Equal(t, uint32(0), res.Code)
+	var outQcpTxs []*txs.TxQcp
+	require.Nil(t, app.GetCdc().UnmarshalBinaryBare(res.GetValue(), &outQcpTxs))
+	require.Len(t, outQcpTxs, 3)
+	require.Equal(t, int64(2), outQcpTxs[0].Sequence)
+	require.Equal(t, int64(4), outQcpTxs[2].Sequence)
 }
 
 func TestStdTx(t *testing.T) {
@@ -513,6 +647,16 @@ func TestStdTx(t *testing.T) {
 			require.Equal(t, uint32(0), uint32(res.Code))
 		}
 
+		//result.Log记录每个ITx的执行结果
+		logs, err := types.ParseABCILogs(res.Log)
+		require.Nil(t, err)
+		require.Len(t, logs, 1)
+		require.Equal(t, uint16(0), logs[0].MsgIndex)
+		require.Equal(t, i < 5, logs[0].Success)
+		if i >= 5 {
+			require.Contains(t, logs[0].Log, "not much money")
+		}
+
 	}
 
 	app.EndBlock(abci.RequestEndBlock{})
@@ -542,6 +686,577 @@ func TestStdTx(t *testing.T) {
 
 }
 
+func TestEventManager(t *testing.T) {
+
+	app := mockApp()
+	app.SetBeginBlocker(func(ctx context.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+		ctx.EventManager().EmitEvent(types.NewEvent("begin"))
+		return abci.ResponseBeginBlock{}
+	})
+	app.SetEndBlocker(func(ctx context.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
+		ctx.EventManager().EmitEvent(types.NewEvent("end"))
+		return abci.ResponseEndBlock{}
+	})
+	app.LoadLatestVersion()
+
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+
+	acc := accMapper.GetAccount(pidAccount1.GetAddress())
+	acc.SetNonce(1)
+	stdTxBz, _ := app.GetCdc().MarshalBinaryBare(createTransformTxWithNoQcpTx(acc, pidAccount2, 1000))
+
+	beginRes := app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	require.Len(t, beginRes.Events, 1)
+	require.Equal(t, "begin", beginRes.Events[0].Type)
+
+	//ITx通过EventManager产生的事件
+	res := app.DeliverTx(abci.RequestDeliverTx{Tx: stdTxBz})
+	require.Equal(t, uint32(0), res.Code)
+	require.Len(t, res.Events, 1)
+	require.Equal(t, "transfer", res.Events[0].Type)
+	require.Equal(t, "1000", string(res.Events[0].Attributes[0].Value))
+
+	logs, err := types.ParseABCILogs(res.Log)
+	require.Nil(t, err)
+	require.Equal(t, "transfer", logs[0].Events[0].Type)
+
+	endRes := app.EndBlock(abci.RequestEndBlock{})
+	require.Len(t, endRes.Events, 1)
+	require.Equal(t, "end", endRes.Events[0].Type)
+	app.Commit()
+}
+
+func TestMultisigAccount(t *testing.T) {
+
+	app := mockApp()
+
+	//2-of-3多签账户
+	privKeys := []crypto.PrivKey{ed25519.GenPrivKey(), ed25519.GenPrivKey(), ed25519.GenPrivKey()}
+	pubKeys := []crypto.PubKey{privKeys[0].PubKey(), privKeys[1].PubKey(), privKeys[2].PubKey()}
+	multiPub := multisig.NewPubKeyMultisigThreshold(2, pubKeys)
+	multiAddr := types.AccAddress(multiPub.Address())
+
+	initChainer := app.initChainer
+	app.SetInitChainer(func(ctx context.Context, req abci.RequestInitChain) abci.ResponseInitChain {
+		res := initChainer(ctx, req)
+		acc := GetAccountMapper(ctx).NewAccountWithAddress(multiAddr).(*testAccount)
+		acc.Money = 5500
+		GetAccountMapper(ctx).SetAccount(acc)
+		return res
+	})
+	app.LoadLatestVersion()
+
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount2 := getAccount(accMapper, int64(2))
+
+	stdTx := txs.NewTxStd(&transferTx{
+		FromUsers: []types.AccAddress{multiAddr},
+		ToUsers:   []types.AccAddress{pidAccount2.GetAddress()},
+		Amount:    1000,
+	}, cid, types.NewInt(50000))
+	signBytes := stdTx.BuildSignatureBytes(1, "")
+
+	buildTx := func(signers ...int) []byte {
+		multiSig := multisig.NewMultisig(3)
+		for _, i := range signers {
+			sig, err := privKeys[i].Sign(signBytes)
+			require.Nil(t, err)
+			require.Nil(t, multiSig.AddSignatureFromPubKey(sig, privKeys[i].PubKey(), pubKeys))
+		}
+		stdTx.Signature = []txs.Signature{{
+			Pubkey:    multiPub,
+			Signature: app.GetCdc().MustMarshalBinaryBare(multiSig),
+			Nonce:     1,
+		}}
+		return app.GetCdc().MustMarshalBinaryBare(stdTx)
+	}
+
+	//未达到门限
+	res := app.CheckTx(abci.RequestCheckTx{Tx: buildTx(1)})
+	require.NotEqual(t, uint32(0), res.Code)
+	require.Contains(t, res.Log, "signature verification failed")
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	dres := app.DeliverTx(abci.RequestDeliverTx{Tx: buildTx(0, 2)})
+	require.Equal(t, uint32(0), dres.Code, dres.Log)
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+
+	acc := GetAccountMapper(app.checkState.ctx).GetAccount(multiAddr).(*testAccount)
+	require.Equal(t, int64(1), acc.GetNonce())
+	require.Equal(t, int64(4500), acc.Money)
+	require.True(t, multiPub.Equals(acc.GetPublicKey()))
+}
+
+func TestFeeGrant(t *testing.T) {
+
+	app := mockApp()
+	app.RegisterMapper(feegrant.NewFeeGrantMapper(app.GetCdc()))
+
+	//测试: 每个tx由gas payer支付10, 使用授权额度时先扣除额度
+	fee := types.BaseCoins{types.NewInt64BaseCoin("qstar", 10)}
+	app.SetGasHandler(func(ctx context.Context, payer types.AccAddress) (uint64, types.Error) {
+		if err := feegrant.UseGrantedFees(ctx, payer, fee); err != nil {
+			return 0, err
+		}
+		acc := GetAccountMapper(ctx).GetAccount(payer).(*testAccount)
+		acc.Money -= fee.AmountOf("qstar").Int64()
+		GetAccountMapper(ctx).SetAccount(acc)
+		return 0, nil
+	})
+	app.SetMaxFeeHandler(func(ctx context.Context, tx *txs.TxStd) types.BaseCoins {
+		return fee
+	})
+	app.LoadLatestVersion()
+
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	granter := getAccount(accMapper, int64(1))
+	grantee := getAccount(accMapper, int64(2))
+	receiver := getAccount(accMapper, int64(3))
+
+	signTx := func(itx txs.ITx, signer *testAccount) []byte {
+		acc := GetAccountMapper(app.checkState.ctx).GetAccount(signer.GetAddress())
+		stdTx := txs.NewTxStd(itx, cid, types.NewInt(50000))
+		sig, err := stdTx.SignTx(signer.PrivKey, acc.GetNonce()+1, "", cid)
+		require.Nil(t, err)
+		stdTx.Signature = []txs.Signature{{Pubkey: signer.PrivKey.PubKey(), Signature: sig, Nonce: acc.GetNonce() + 1}}
+		return app.GetCdc().MustMarshalBinaryBare(stdTx)
+	}
+	sponsoredTx := &transferTx{
+		FromUsers: []types.AccAddress{grantee.GetAddress()},
+		ToUsers:   []types.AccAddress{receiver.GetAddress()},
+		Amount:    100,
+		GasPayer:  granter.GetAddress(),
+	}
+
+	//未授权时gas payer不能为非签名者
+	res := app.CheckTx(abci.RequestCheckTx{Tx: signTx(sponsoredTx, grantee)})
+	require.Equal(t, uint32(types.CodeUnauthorized), res.Code, res.Log)
+
+	deliver := func(tx []byte) abci.ResponseDeliverTx {
+		app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: app.LastBlockHeight() + 1, ChainID: cid}})
+		res := app.DeliverTx(abci.RequestDeliverTx{Tx: tx})
+		app.EndBlock(abci.RequestEndBlock{})
+		app.Commit()
+		return res
+	}
+
+	grantTx := &feegrant.TxGrantAllowance{
+		Granter:    granter.GetAddress(),
+		Grantee:    grantee.GetAddress(),
+		SpendLimit: types.BaseCoins{types.NewInt64BaseCoin("qstar", 15)},
+	}
+	dres := deliver(signTx(grantTx, granter))
+	require.Equal(t, uint32(0), dres.Code, dres.Log)
+
+	//使用额度, granter支付gas费
+	dres = deliver(signTx(sponsoredTx, grantee))
+	require.Equal(t, uint32(0), dres.Code, dres.Log)
+
+	accMapper = GetAccountMapper(app.checkState.ctx)
+	require.Equal(t, int64(5500-20), accMapper.GetAccount(granter.GetAddress()).(*testAccount).Money)
+	require.Equal(t, int64(5500-100), accMapper.GetAccount(grantee.GetAddress()).(*testAccount).Money)
+	allowance, exists := GetFeeGrantMapper(app.checkState.ctx).GetAllowance(granter.GetAddress(), grantee.GetAddress())
+	require.True(t, exists)
+	require.Equal(t, int64(5), allowance.SpendLimit.AmountOf("qstar").Int64())
+
+	//额度不足以支付max fee, tx被拒绝
+	dres = deliver(signTx(sponsoredTx, grantee))
+	require.Equal(t, uint32(types.CodeUnauthorized), dres.Code, dres.Log)
+	require.Contains(t, dres.Log, "covering max fee")
+	require.Equal(t, int64(5500-100), GetAccountMapper(app.checkState.ctx).GetAccount(grantee.GetAddress()).(*testAccount).Money)
+
+	//撤销授权
+	dres = deliver(signTx(&feegrant.TxRevokeAllowance{Granter: granter.GetAddress(), Grantee: grantee.GetAddress()}, granter))
+	require.Equal(t, uint32(0), dres.Code, dres.Log)
+	_, exists = GetFeeGrantMapper(app.checkState.ctx).GetAllowance(granter.GetAddress(), grantee.GetAddress())
+	require.False(t, exists)
+}
+
+func TestSimulate(t *testing.T) {
+
+	app := mockApp()
+	app.LoadLatestVersion()
+
+	//init chain
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	checkContext := app.checkState.ctx
+	accMapper := GetAccountMapper(checkContext)
+
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+
+	acc := accMapper.GetAccount(pidAccount1.GetAddress())
+	acc.SetNonce(1)
+	stdTx := createTransformTxWithNoQcpTx(acc, pidAccount2, 1000)
+	stdTxBz, _ := app.GetCdc().MarshalBinaryBare(stdTx)
+
+	res := app.Query(abci.RequestQuery{Path: "/app/simulate", Data: stdTxBz})
+	require.Equal(t, uint32(0), res.Code)
+
+	var result types.Result
+	app.GetCdc().MustUnmarshalBinaryBare(res.Value, &result)
+	require.True(t, result.IsOK())
+	require.True(t, result.GasUsed > 0)
+
+	//模拟执行不修改checkState
+	acc = accMapper.GetAccount(pidAccount1.GetAddress())
+	require.Equal(t, int64(0), acc.GetNonce())
+	require.Equal(t, int64(5500), acc.(*testAccount).Money)
+
+	//未签名交易需跳过签名校验
+	unsignedTx := txs.NewTxStd(stdTx.ITxs[0], cid, types.NewInt(50000))
+	unsignedTxBz, _ := app.GetCdc().MarshalBinaryBare(unsignedTx)
+
+	res = app.Query(abci.RequestQuery{Path: "/app/simulate", Data: unsignedTxBz})
+	app.GetCdc().MustUnmarshalBinaryBare(res.Value, &result)
+	require.False(t, result.IsOK())
+
+	res = app.Query(abci.RequestQuery{Path: "/app/simulate/" + SimulateSkipSigVerify, Data: unsignedTxBz})
+	app.GetCdc().MustUnmarshalBinaryBare(res.Value, &result)
+	require.True(t, result.IsOK())
+	require.True(t, result.GasUsed > 0)
+
+	checkRes := app.CheckTx(abci.RequestCheckTx{Tx: stdTxBz})
+	require.Equal(t, uint32(0), checkRes.Code)
+}
+
+func TestMinimumFees(t *testing.T) {
+
+	app := mockApp()
+	SetMinimumFees(types.BaseCoins{types.NewInt64BaseCoin("qstar", 100000)})(app)
+
+	//测试: 以MaxGas作为tx支付的手续费
+	app.SetFeeCheckHandler(func(ctx context.Context, tx *txs.TxStd, minimumFees []types.Coin) types.Error {
+		for _, fee := range minimumFees {
+			if tx.MaxGas.LT(fee.GetAmount()) {
+				return types.ErrInsufficientFee(fmt.Sprintf("insufficient fee. expect: %s, got: %s", fee.GetAmount(), tx.MaxGas))
+			}
+		}
+		return nil
+	})
+	app.LoadLatestVersion()
+
+	//init chain
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+
+	acc := accMapper.GetAccount(pidAccount1.GetAddress())
+	acc.SetNonce(1)
+	stdTx := createTransformTxWithNoQcpTx(acc, pidAccount2, 1000)
+	stdTxBz, _ := app.GetCdc().MarshalBinaryBare(stdTx)
+
+	res := app.CheckTx(abci.RequestCheckTx{Tx: stdTxBz})
+	require.Equal(t, uint32(types.CodeInsufficientFee), res.Code)
+
+	//模拟执行不校验最低手续费
+	queryRes := app.Query(abci.RequestQuery{Path: "/app/simulate", Data: stdTxBz})
+	var result types.Result
+	app.GetCdc().MustUnmarshalBinaryBare(queryRes.Value, &result)
+	require.True(t, result.IsOK())
+
+	//deliverTx不校验最低手续费
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	deliverRes := app.DeliverTx(abci.RequestDeliverTx{Tx: stdTxBz})
+	require.Equal(t, uint32(0), deliverRes.Code)
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+}
+
+func TestBlockGasLimit(t *testing.T) {
+
+	buildTxs := func(app *BaseApp) [][]byte {
+		accMapper := GetAccountMapper(app.checkState.ctx)
+		pidAccount1 := getAccount(accMapper, int64(1))
+		pidAccount2 := getAccount(accMapper, int64(2))
+
+		var txs [][]byte
+		for i := int64(1); i <= 3; i++ {
+			acc := accMapper.GetAccount(pidAccount1.GetAddress())
+			acc.SetNonce(i)
+			stdTx := createTransformTxWithNoQcpTx(acc, pidAccount2, 1000)
+			stdTxBz, _ := app.GetCdc().MarshalBinaryBare(stdTx)
+			txs = append(txs, stdTxBz)
+		}
+		return txs
+	}
+
+	//不限制区块gas时,统计单笔tx消耗的gas
+	app := mockApp()
+	app.LoadLatestVersion()
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	var txGas int64
+	for _, stdTxBz := range buildTxs(app) {
+		res := app.DeliverTx(abci.RequestDeliverTx{Tx: stdTxBz})
+		require.Equal(t, uint32(0), res.Code)
+		txGas = res.GasUsed
+	}
+	require.True(t, txGas > 0)
+
+	//区块gas上限可容纳2笔tx
+	maxGas := txGas*2 + txGas/2
+	app = mockApp()
+	app.LoadLatestVersion()
+	app.InitChain(abci.RequestInitChain{ChainId: cid, ConsensusParams: &abci.ConsensusParams{Block: &abci.BlockParams{MaxGas: maxGas}}})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	for i, stdTxBz := range buildTxs(app) {
+		res := app.DeliverTx(abci.RequestDeliverTx{Tx: stdTxBz})
+		if i < 2 {
+			require.Equal(t, uint32(0), res.Code)
+		} else {
+			require.Equal(t, uint32(types.CodeOutOfGas), res.Code)
+		}
+	}
+	require.True(t, app.deliverState.ctx.BlockGasMeter().IsOutOfGas())
+
+	//区块gas耗尽后tx不再执行
+	res := app.DeliverTx(abci.RequestDeliverTx{Tx: buildTxs(app)[0]})
+	require.Equal(t, uint32(types.CodeOutOfGas), res.Code)
+	require.Equal(t, int64(0), res.GasUsed)
+
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+
+	//超出区块gas上限的tx执行结果未保存
+	acc := getAccount(GetAccountMapper(app.checkState.ctx), int64(1))
+	require.Equal(t, int64(3500), acc.Money)
+
+	//新区块重置区块gas
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 3, ChainID: cid}})
+	require.Equal(t, uint64(0), app.deliverState.ctx.BlockGasMeter().GasConsumed())
+	require.Equal(t, uint64(maxGas), app.deliverState.ctx.BlockGasMeter().Limit())
+}
+
+type blacklistDecorator struct {
+	blacklist *types.AccAddress
+}
+
+func (bd blacklistDecorator) AnteHandle(ctx context.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool, next AnteHandler) (context.Context, types.Result) {
+	for _, signer := range tx.GetSigners() {
+		if signer.Equals(*bd.blacklist) {
+			return ctx, types.ErrUnauthorized("signer in blacklist").Result()
+		}
+	}
+	return next(ctx, tx, txStdFromChainID, skipSigVerify)
+}
+
+func TestAnteHandler(t *testing.T) {
+
+	app := mockApp()
+
+	var blacklist types.AccAddress
+	app.SetAnteDecorators(blacklistDecorator{blacklist: &blacklist}, NewValidateBasicDecorator(), NewNonceCheckDecorator(), NewSigVerifyDecorator(), NewIncrementNonceDecorator())
+	app.LoadLatestVersion()
+	require.Panics(t, func() { app.SetAnteHandler(nil) })
+
+	//init chain
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+	blacklist = pidAccount1.GetAddress()
+
+	acc := accMapper.GetAccount(pidAccount1.GetAddress())
+	acc.SetNonce(1)
+	stdTx := createTransformTxWithNoQcpTx(acc, pidAccount2, 1000)
+	stdTxBz, _ := app.GetCdc().MarshalBinaryBare(stdTx)
+
+	res := app.CheckTx(abci.RequestCheckTx{Tx: stdTxBz})
+	require.Equal(t, uint32(types.CodeUnauthorized), res.Code)
+
+	//被拦截的交易不增加nonce
+	acc = accMapper.GetAccount(pidAccount1.GetAddress())
+	require.Equal(t, int64(0), acc.GetNonce())
+
+	blacklist = pidAccount2.GetAddress()
+	res = app.CheckTx(abci.RequestCheckTx{Tx: stdTxBz})
+	require.Equal(t, uint32(0), res.Code)
+
+	acc = accMapper.GetAccount(pidAccount1.GetAddress())
+	require.Equal(t, int64(1), acc.GetNonce())
+}
+
+func TestGasPreHandler(t *testing.T) {
+
+	app := mockApp()
+
+	var payers []types.AccAddress
+	var insufficient types.AccAddress
+	app.SetGasPreHandler(func(ctx context.Context, payer types.AccAddress) types.Error {
+		payers = append(payers, payer)
+		if payer.Equals(insufficient) {
+			return types.ErrInsufficientCoins("insufficient gas")
+		}
+		return nil
+	})
+	app.LoadLatestVersion()
+
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	accMapper := GetAccountMapper(app.checkState.ctx)
+	pidAccount1 := getAccount(accMapper, int64(1))
+	pidAccount2 := getAccount(accMapper, int64(2))
+	insufficient = pidAccount1.GetAddress()
+
+	acc := accMapper.GetAccount(pidAccount1.GetAddress())
+	acc.SetNonce(1)
+	stdTxBz, _ := app.GetCdc().MarshalBinaryBare(createTransformTxWithNoQcpTx(acc, pidAccount2, 1000))
+
+	//checkTx阶段不执行gasPreHandler
+	res := app.CheckTx(abci.RequestCheckTx{Tx: stdTxBz})
+	require.Equal(t, uint32(0), res.Code)
+	require.Len(t, payers, 0)
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	dres := app.DeliverTx(abci.RequestDeliverTx{Tx: stdTxBz})
+	require.Equal(t, uint32(types.CodeInsufficientCoins), dres.Code, dres.Log)
+	require.Equal(t, []types.AccAddress{pidAccount1.GetAddress()}, payers)
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+
+	//payer无法支付gas时不增加签名账户nonce
+	acc = GetAccountMapper(app.checkState.ctx).GetAccount(pidAccount1.GetAddress())
+	require.Equal(t, int64(0), acc.GetNonce())
+}
+
+func TestQcpOutTxKeyMigration(t *testing.T) {
+	db := dbm.NewMemDB()
+	app := mockAppWithDB(db)
+	require.Nil(t, app.LoadLatestVersion())
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+
+	//旧版本key保存的qcp tx
+	qcpMapper := GetQcpMapper(app.deliverState.ctx)
+	qcpMapper.Set([]byte("outSequenceTxPrefixKeyqsc/1"), txs.TxQcp{From: cid, To: "qsc", Sequence: 1})
+	qcpMapper.SetMaxChainOutSequence("qsc", 1)
+	app.Commit()
+
+	//未设置迁移高度或迁移高度已过时不能加载
+	require.NotNil(t, mockAppWithDB(db).LoadLatestVersion())
+	app = mockAppWithDB(db)
+	app.qcpOutTxMigrationHeight = 1
+	require.NotNil(t, app.LoadLatestVersion())
+
+	app = mockAppWithDB(db)
+	app.qcpOutTxMigrationHeight = 2
+	require.Nil(t, app.LoadLatestVersion())
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: cid}})
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+
+	txQcps := GetQcpMapper(app.checkState.ctx).IterateChainOutTxs("qsc", 1, 10)
+	require.Len(t, txQcps, 1)
+	require.Equal(t, int64(1), txQcps[0].Sequence)
+
+	//迁移后不再需要设置迁移高度
+	require.Nil(t, mockAppWithDB(db).LoadLatestVersion())
+}
+
+func TestExportAppState(t *testing.T) {
+	capKey := types.NewKVStoreKey("main")
+	key := []byte("hello")
+
+	type appGenesis struct {
+		Hello string             `json:"hello"`
+		QCPs  []*types.QCPConfig `json:"qcps,omitempty"`
+	}
+
+	newApp := func() *BaseApp {
+		app := NewBaseApp(t.Name(), nil, defaultLogger(), dbm.NewMemDB(), nil)
+		app.mountStoresIAVL(capKey)
+		app.SetInitChainer(func(ctx context.Context, req abci.RequestInitChain) abci.ResponseInitChain {
+			var gs appGenesis
+			require.Nil(t, app.GetCdc().UnmarshalJSON(req.AppStateBytes, &gs))
+			ctx.KVStore(capKey).Set(key, []byte(gs.Hello))
+			return abci.ResponseInitChain{}
+		})
+		app.SetExportAppStateFunc(func(ctx context.Context) (json.RawMessage, error) {
+			return app.GetCdc().MarshalJSON(appGenesis{Hello: string(ctx.KVStore(capKey).Get(key))})
+		})
+		require.Nil(t, app.LoadLatestVersion())
+		return app
+	}
+
+	pubkey := ed25519.GenPrivKey().PubKey()
+	appStateBytes, err := MakeQBaseCodec().MarshalJSON(appGenesis{
+		Hello: "world",
+		QCPs:  []*types.QCPConfig{{Name: "qsc", ChainId: "qsc", PubKey: pubkey}},
+	})
+	require.Nil(t, err)
+
+	app := newApp()
+	app.InitChain(abci.RequestInitChain{ChainId: cid, AppStateBytes: appStateBytes, ConsensusParams: &abci.ConsensusParams{Block: &abci.BlockParams{MaxBytes: 1024, MaxGas: 1000}}})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2}})
+	qcpMapper := GetQcpMapper(app.deliverState.ctx)
+	qcpMapper.SetMaxChainInSequence("qsc", 3)
+	qcpMapper.SetMaxChainOutSequence("qsc", 5)
+	//未完成的跨链输出交易: 4已确认, 5超时处理中
+	for seq := int64(4); seq <= 5; seq++ {
+		qcpMapper.SetChainOutTxs("qsc", seq, &txs.TxQcp{From: cid, To: "qsc", Sequence: seq, TimeoutHeight: 10})
+	}
+	qcpMapper.SetChainOutAckSequence("qsc", 3)
+	qcpMapper.AckChainOutTx("qsc", 4)
+	qcpMapper.SetChainOutTxTimeout("qsc", 5)
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+	app.Commit()
+
+	appState, consParams, err := app.ExportAppState()
+	require.Nil(t, err)
+	require.Equal(t, int64(1000), consParams.Block.MaxGas)
+
+	var exported types.GenesisState
+	require.Nil(t, app.GetCdc().UnmarshalJSON(appState, &exported))
+	require.Len(t, exported.QCPs, 1)
+	require.Equal(t, pubkey, exported.QCPs[0].PubKey)
+	require.Equal(t, int64(3), exported.QCPs[0].InSequence)
+	require.Equal(t, int64(5), exported.QCPs[0].OutSequence)
+
+	//exported state can be fed back through InitChain
+	imported := newApp()
+	imported.InitChain(abci.RequestInitChain{ChainId: cid, AppStateBytes: appState})
+	imported.Commit()
+
+	ctx := imported.checkState.ctx
+	require.Equal(t, []byte("world"), ctx.KVStore(capKey).Get(key))
+	qcpMapper = GetQcpMapper(ctx)
+	require.Equal(t, pubkey, qcpMapper.GetChainInTrustPubKey("qsc"))
+	require.Equal(t, int64(3), qcpMapper.GetMaxChainInSequence("qsc"))
+	require.Equal(t, int64(5), qcpMapper.GetMaxChainOutSequence("qsc"))
+	require.Equal(t, int64(4), qcpMapper.GetChainOutAckSequence("qsc"))
+	require.True(t, qcpMapper.IsChainOutTxTimeout("qsc", 5))
+	txQcps := qcpMapper.IterateChainOutTxs("qsc", 1, 0)
+	require.Len(t, txQcps, 1)
+	require.Equal(t, int64(5), txQcps[0].Sequence)
+	require.Equal(t, int64(10), txQcps[0].TimeoutHeight)
+}
+
 func createTransformTxWithNoQcpTx(from, to account.Account, amount int64) *txs.TxStd {
 	tx := &transferTx{
 		FromUsers: []types.AccAddress{from.GetAddress()},
@@ -647,6 +1362,7 @@ type transferTx struct {
 	Amount    int64
 	FromUsers []types.AccAddress
 	ToUsers   []types.AccAddress
+	GasPayer  types.AccAddress //为空时由FromUsers[0]支付gas费
 }
 
 var _ txs.ITx = (*transferTx)(nil)
@@ -677,6 +1393,8 @@ func (t *transferTx) Exec(ctx context.Context) (result types.Result, crossTxQcps
 	accMapper.SetAccount(from)
 	accMapper.SetAccount(to)
 
+	ctx.EventManager().EmitEvent(types.NewEvent("transfer", types.NewAttribute("amount", strconv.FormatInt(t.Amount, 10))))
+
 	//from.id == 3  && to.id == 4时，生成跨链结果
 	if from.Id == int64(3) && to.Id == int64(4) {
 
@@ -716,6 +1434,9 @@ func (t *transferTx) CalcGas() types.BigInt {
 }
 
 func (t *transferTx) GetGasPayer() types.AccAddress {
+	if len(t.GasPayer) != 0 {
+		return t.GasPayer
+	}
 	return t.FromUsers[0]
 }
 
@@ -731,6 +1452,8 @@ func (t *transferTx) GetSignData() []byte {
 		signData = append(signData, addr.Bytes()...)
 	}
 
+	signData = append(signData, t.GasPayer.Bytes()...)
+
 	return signData
 }
 
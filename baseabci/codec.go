@@ -3,9 +3,12 @@ package baseabci
 import (
 	"github.com/QOSGroup/qbase/account"
 	"github.com/QOSGroup/qbase/consensus"
+	"github.com/QOSGroup/qbase/feegrant"
 	"github.com/QOSGroup/qbase/keys"
+	"github.com/QOSGroup/qbase/qcp"
 	"github.com/QOSGroup/qbase/txs"
 	"github.com/QOSGroup/qbase/types"
+	"github.com/QOSGroup/qbase/upgrade"
 	go_amino "github.com/tendermint/go-amino"
 	cryptoAmino "github.com/tendermint/tendermint/crypto/encoding/amino"
 )
@@ -21,6 +24,9 @@ func MakeQBaseCodec() *go_amino.Codec {
 
 func RegisterCodec(cdc *go_amino.Codec) {
 	txs.RegisterCodec(cdc)
+	qcp.RegisterCodec(cdc)
+	feegrant.RegisterCodec(cdc)
+	upgrade.RegisterCodec(cdc)
 	account.RegisterCodec(cdc)
 	keys.RegisterCodec(cdc)
 	consensus.RegisterCodec(cdc)
@@ -0,0 +1,112 @@
+package baseabci
+
+import (
+	"encoding/json"
+	"sort"
+
+	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/qcp"
+	"github.com/QOSGroup/qbase/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+//ExportAppState 导出当前加载版本的应用状态, 作为新genesis.json的app_state及consensus_params
+//导出指定高度的状态时, 需先调用LoadVersion(height)
+//app_state中qcps, qcp_admin, qcp_out(未完成的跨链输出交易)由qbase导出, 其余字段由app注册的ExportAppStateFunc导出
+func (app *BaseApp) ExportAppState() (appState json.RawMessage, consParams *abci.ConsensusParams, err error) {
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+
+	appState = json.RawMessage("{}")
+	if app.exportAppState != nil {
+		appState, err = app.exportAppState(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var jsonMap map[string]json.RawMessage
+	if err = json.Unmarshal(appState, &jsonMap); err != nil {
+		return nil, nil, err
+	}
+	if jsonMap == nil {
+		jsonMap = make(map[string]json.RawMessage)
+	}
+
+	if qcps := exportQCP(ctx); len(qcps) > 0 {
+		bz, err := app.cdc.MarshalJSON(qcps)
+		if err != nil {
+			return nil, nil, err
+		}
+		jsonMap["qcps"] = bz
+	}
+
+	if qcpMapper := GetQcpMapper(ctx); qcpMapper != nil {
+		if admin := qcpMapper.GetAdmin(); len(admin) > 0 {
+			bz, err := app.cdc.MarshalJSON(admin)
+			if err != nil {
+				return nil, nil, err
+			}
+			jsonMap["qcp_admin"] = bz
+		}
+
+		if outStates := qcp.ExportOutChainStates(qcpMapper); len(outStates) > 0 {
+			bz, err := app.cdc.MarshalJSON(outStates)
+			if err != nil {
+				return nil, nil, err
+			}
+			jsonMap["qcp_out"] = bz
+		}
+	}
+
+	appState, err = json.MarshalIndent(jsonMap, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return appState, GetConsParams(ctx), nil
+}
+
+//exportQCP 导出信任公钥, 可信验证人集合及跨链序号, 按chainId排序
+func exportQCP(ctx ctx.Context) []*types.QCPConfig {
+	qcpMapper := GetQcpMapper(ctx)
+	if qcpMapper == nil {
+		return nil
+	}
+
+	configs := make(map[string]*types.QCPConfig)
+	get := func(chainId string) *types.QCPConfig {
+		if _, ok := configs[chainId]; !ok {
+			configs[chainId] = &types.QCPConfig{Name: chainId, ChainId: chainId}
+		}
+		return configs[chainId]
+	}
+
+	qcpMapper.IterateChainInTrustPubKeys(func(inChain string, pubkey crypto.PubKey) bool {
+		get(inChain).PubKey = pubkey
+		return false
+	})
+	qcpMapper.IterateChainInValidators(func(inChain string, valSet *tmtypes.ValidatorSet) bool {
+		get(inChain).Validators = types.NewGenesisValidators(valSet)
+		return false
+	})
+	qcpMapper.IterateMaxChainInSequences(func(inChain string, sequence int64) bool {
+		get(inChain).InSequence = sequence
+		return false
+	})
+	qcpMapper.IterateMaxChainOutSequences(func(outChain string, sequence int64) bool {
+		get(outChain).OutSequence = sequence
+		return false
+	})
+
+	qcps := make([]*types.QCPConfig, 0, len(configs))
+	for _, config := range configs {
+		qcps = append(qcps, config)
+	}
+	sort.Slice(qcps, func(i, j int) bool {
+		return qcps[i].ChainId < qcps[j].ChainId
+	})
+
+	return qcps
+}
@@ -1,7 +1,10 @@
 package baseabci
 
 import (
+	"encoding/json"
+
 	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
 	"github.com/QOSGroup/qbase/types"
 	abci "github.com/tendermint/tendermint/abci/types"
 )
@@ -9,6 +12,9 @@ import (
 // initialize application state at genesis
 type InitChainHandler func(ctx ctx.Context, req abci.RequestInitChain) abci.ResponseInitChain
 
+// export application state as app_state of genesis.json, which can be fed back through InitChainHandler
+type ExportAppStateFunc func(ctx ctx.Context) (appState json.RawMessage, err error)
+
 // run code before the transactions in a block
 type BeginBlockHandler func(ctx ctx.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock
 
@@ -20,13 +26,38 @@ type EndBlockHandler func(ctx ctx.Context, req abci.RequestEndBlock) abci.Respon
 //ex: path: "/custom/qcp/a/b/c":
 //调用app.RegisterCustomQueryHandler(handler)
 //handler中route为切片:[qcp,a,b,c]
+//已通过RegisterQueryHandler注册的路由优先, 未注册的路由交由CustomQueryHandler处理
 type CustomQueryHandler func(ctx ctx.Context, route []string, req abci.RequestQuery) (res []byte, err types.Error)
 
+//QueryHandler mapper(或模块)查询处理
+//ex: path: "/custom/acc/account/{address}":
+//调用app.RegisterQueryHandler("acc", handler)
+//handler中route为去掉mapper名称后的切片:[account,{address}]
+//ctx基于req.Height高度的状态, req.Height为0时基于最新高度
+type QueryHandler func(ctx ctx.Context, route []string, req abci.RequestQuery) (res []byte, err types.Error)
+
+//UpgradeHandler 升级计划高度的BeginBlock中执行, 用于迁移mapper数据. 每个计划只执行一次
+//Important!: 该方法panic时节点停止出块
+type UpgradeHandler func(ctx ctx.Context)
+
 //TxQcpResultHandler qcpTx result 回调函数，在TxQcpResult.Exec中调用
 //Important!: txQcpResult 类型为 *txs.QcpTxResult
 //Important!: 该方法panic时,在其中保存的数据将会被丢弃
 type TxQcpResultHandler func(ctx ctx.Context, txQcpResult interface{})
 
+//AnteHandler ITx.Exec执行前的处理逻辑, 如基础校验、nonce校验、签名校验、gas预处理等
+//返回非OK的result时tx不再执行. 可通过ChainAnteDecorators组装
+//skipSigVerify为true时不校验签名, 仅在模拟执行时使用
+type AnteHandler func(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (newCtx ctx.Context, result types.Result)
+
+//FeeCheckHandler checkTx阶段校验tx手续费是否满足节点配置的最低手续费(app.toml: minimum_fees)
+//返回error时tx不会进入mempool. deliverTx及模拟执行时不会调用
+type FeeCheckHandler func(ctx ctx.Context, tx *txs.TxStd, minimumFees []types.Coin) types.Error
+
+//MaxFeeHandler 返回tx最多消耗的gas费, 即MaxGas对应的gas费.
+//gas payer非签名者时, 用于校验payer授予签名者的额度是否足够支付gas费
+type MaxFeeHandler func(ctx ctx.Context, tx *txs.TxStd) types.BaseCoins
+
 // gas-fee 处理
 type GasPreHandler func(ctx ctx.Context, payer types.AccAddress) types.Error
 type GasHandler func(ctx ctx.Context, payer types.AccAddress) (gasUsed uint64, err types.Error)
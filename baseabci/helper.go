@@ -4,8 +4,10 @@ import (
 	"github.com/QOSGroup/qbase/account"
 	"github.com/QOSGroup/qbase/consensus"
 	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/feegrant"
 	"github.com/QOSGroup/qbase/qcp"
 	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/upgrade"
 	abci "github.com/tendermint/tendermint/abci/types"
 )
 
@@ -25,6 +27,20 @@ func GetQcpMapper(ctx context.Context) *qcp.QcpMapper {
 	return mapper.(*qcp.QcpMapper)
 }
 
+//GetFeeGrantMapper 未注册feegrant.FeeGrantMapper时返回nil
+func GetFeeGrantMapper(ctx context.Context) *feegrant.FeeGrantMapper {
+	mapper := ctx.Mapper(feegrant.MapperName)
+	if mapper == nil {
+		return nil
+	}
+	return mapper.(*feegrant.FeeGrantMapper)
+}
+
+//GetUpgradeMapper 未注册upgrade.UpgradeMapper时返回nil
+func GetUpgradeMapper(ctx context.Context) *upgrade.UpgradeMapper {
+	return upgrade.GetUpgradeMapper(ctx)
+}
+
 func GetConsMapper(ctx context.Context) *consensus.ConsensusMapper {
 	mapper := ctx.Mapper(consensus.ConsensusMapperName)
 	if mapper == nil {
@@ -33,6 +49,14 @@ func GetConsMapper(ctx context.Context) *consensus.ConsensusMapper {
 	return mapper.(*consensus.ConsensusMapper)
 }
 
+//GetSignerAccounts 获取AnteHandler中保存在ctx的tx签名账户
+func GetSignerAccounts(ctx context.Context) []account.Account {
+	if signers, ok := ctx.Value(context.ContextKeySigners).([]account.Account); ok {
+		return signers
+	}
+	return nil
+}
+
 //see: handler.go: TxQcpResultHandler
 func ConvertTxQcpResult(txQcpResult interface{}) (*txs.QcpTxResult, bool) {
 	qcpResult, ok := txQcpResult.(*txs.QcpTxResult)
@@ -1,10 +1,14 @@
 package baseabci
 
 import (
+	"fmt"
+
 	"github.com/QOSGroup/qbase/account"
 	"github.com/QOSGroup/qbase/mapper"
 	"github.com/QOSGroup/qbase/qcp"
 	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/store/snapshots"
+	"github.com/QOSGroup/qbase/types"
 	"github.com/tendermint/tendermint/crypto"
 )
 
@@ -22,6 +26,13 @@ func (app *BaseApp) SetInitChainer(initChainer InitChainHandler) {
 	app.initChainer = initChainer
 }
 
+func (app *BaseApp) SetExportAppStateFunc(exportAppState ExportAppStateFunc) {
+	if app.sealed {
+		panic("SetExportAppStateFunc() on sealed BaseApp")
+	}
+	app.exportAppState = exportAppState
+}
+
 func (app *BaseApp) SetBeginBlocker(beginBlocker BeginBlockHandler) {
 	if app.sealed {
 		panic("SetBeginBlocker() on sealed BaseApp")
@@ -52,6 +63,7 @@ func (app *BaseApp) RegisterAccountProto(proto func() account.Account) {
 	}
 	mapper := account.NewAccountMapper(app.GetCdc(), proto)
 	app.RegisterMapper(mapper)
+	app.RegisterQueryHandler(account.AccountMapperName, app.accountQueryHandler)
 }
 
 func (app *BaseApp) RegisterMapper(mapper mapper.IMapper) {
@@ -78,6 +90,30 @@ func (app *BaseApp) RegisterCustomQueryHandler(handler CustomQueryHandler) {
 	app.customQueryHandler = handler
 }
 
+//RegisterQueryHandler 注册名称为name的查询路由, 处理"/custom/{name}/..."路径的查询
+//name一般为mapper名称, 重复注册时panic
+func (app *BaseApp) RegisterQueryHandler(name string, handler QueryHandler) {
+	if app.sealed {
+		panic("RegisterQueryHandler() on sealed BaseApp")
+	}
+	if _, ok := app.queryRouter[name]; ok {
+		panic(fmt.Sprintf("Register dup query handler: %s", name))
+	}
+	app.queryRouter[name] = handler
+}
+
+//RegisterUpgradeHandler 注册名称为name的升级计划的处理逻辑, 计划高度的BeginBlock中执行
+//upgrade.UpgradeMapper中存在升级计划但未注册对应handler时, 计划高度的BeginBlock中panic
+func (app *BaseApp) RegisterUpgradeHandler(name string, handler UpgradeHandler) {
+	if app.sealed {
+		panic("RegisterUpgradeHandler() on sealed BaseApp")
+	}
+	if _, ok := app.upgradeHandlers[name]; ok {
+		panic(fmt.Sprintf("Register dup upgrade handler: %s", name))
+	}
+	app.upgradeHandlers[name] = handler
+}
+
 func (app *BaseApp) Seal()          { app.sealed = true }
 func (app *BaseApp) IsSealed() bool { return app.sealed }
 func (app *BaseApp) enforceSeal() {
@@ -116,7 +152,100 @@ func (app *BaseApp) SetGasPreHandler(handler GasPreHandler) {
 	app.gasPreHandler = handler
 }
 
+func (app *BaseApp) SetFeeCheckHandler(handler FeeCheckHandler) {
+	if app.sealed {
+		panic("SetFeeCheckHandler() on sealed BaseApp")
+	}
+	app.feeCheckHandler = handler
+}
+
+//SetMaxFeeHandler 设置tx最多消耗的gas费计算方法. 未设置时gas payer仅能通过不限额度的授权为签名者支付gas费
+func (app *BaseApp) SetMaxFeeHandler(handler MaxFeeHandler) {
+	if app.sealed {
+		panic("SetMaxFeeHandler() on sealed BaseApp")
+	}
+	app.maxFeeHandler = handler
+}
+
+//SetAnteHandler 设置ITx.Exec执行前的处理逻辑. 未设置时使用DefaultAnteDecorators
+func (app *BaseApp) SetAnteHandler(handler AnteHandler) {
+	if app.sealed {
+		panic("SetAnteHandler() on sealed BaseApp")
+	}
+	app.anteHandler = handler
+}
+
+//SetAnteDecorators 按顺序组装decorators并设置为AnteHandler
+//ex: app.SetAnteDecorators(append(app.DefaultAnteDecorators(), myDecorator)...)
+func (app *BaseApp) SetAnteDecorators(decorators ...AnteDecorator) {
+	if app.sealed {
+		panic("SetAnteDecorators() on sealed BaseApp")
+	}
+	app.anteHandler = ChainAnteDecorators(decorators...)
+}
+
+// SetMinimumFees sets the minimum fees that checkTx requires, see: FeeCheckHandler
+func SetMinimumFees(fees types.BaseCoins) func(*BaseApp) {
+	return func(bap *BaseApp) {
+		minimumFees := make([]types.Coin, 0, len(fees))
+		for _, fee := range fees {
+			minimumFees = append(minimumFees, fee)
+		}
+		bap.minimumFees = minimumFees
+	}
+}
+
+// SetMaxMemoLength sets the max length of TxStd memo, default is ctx.DefaultMaxMemoLength.
+// NOTE: all nodes must use the same value, as the memo length is checked in deliverTx
+func SetMaxMemoLength(maxMemoLength int) func(*BaseApp) {
+	return func(bap *BaseApp) { bap.maxMemoLength = maxMemoLength }
+}
+
+// SetSigVerifyCacheSize sets the size of signature verification cache, default is DefaultSigVerifyCacheSize.
+// size <= 0 disables the cache
+func SetSigVerifyCacheSize(size int) func(*BaseApp) {
+	return func(bap *BaseApp) {
+		if size <= 0 {
+			bap.sigCache = nil
+			return
+		}
+		bap.sigCache = NewSigVerifyCache(size)
+	}
+}
+
 // SetPruning sets a pruning option on the multistore associated with the app
 func SetPruning(opts store.PruningOptions) func(*BaseApp) {
 	return func(bap *BaseApp) { bap.cms.SetPruning(opts) }
 }
+
+// SetSnapshot enables state sync snapshots stored under dir, a snapshot is created every interval blocks
+// and only the latest keepRecent snapshots are kept. keepRecent 0 keeps all snapshots.
+// Snapshots are created synchronously in Commit, so the exported version cannot be pruned
+// meanwhile, at the cost of blocking Commit while the snapshot is written.
+func SetSnapshot(dir string, interval int64, keepRecent int) func(*BaseApp) {
+	return func(bap *BaseApp) {
+		bap.snapshotManager = snapshots.NewManager(dir, bap.cms)
+		bap.snapshotInterval = interval
+		bap.snapshotKeepRecent = keepRecent
+	}
+}
+
+// SetHaltHeight halts the node after committing the block at height, 0 disables halting.
+// It is used to stop all nodes at the same height for a coordinated upgrade.
+func SetHaltHeight(height int64) func(*BaseApp) {
+	return func(bap *BaseApp) { bap.haltHeight = height }
+}
+
+// SetStoreUpgrades sets the changes of mapper stores applied when loading a version, see: store.StoreUpgrades.
+// Once set, mappers added, renamed or deleted since the last committed version must be listed,
+// otherwise LoadLatestVersion returns an error. Without it, added mappers are mounted empty.
+func SetStoreUpgrades(upgrades *store.StoreUpgrades) func(*BaseApp) {
+	return func(bap *BaseApp) { bap.storeUpgrades = upgrades }
+}
+
+// SetQcpOutTxKeyMigrationHeight migrates qcp out txs saved under the legacy key prefix to "tx/out/" at height.
+// All nodes upgrading from a release with the legacy keys must use the same height.
+// Loading a version with legacy keys fails unless height is above the loaded version.
+func SetQcpOutTxKeyMigrationHeight(height int64) func(*BaseApp) {
+	return func(bap *BaseApp) { bap.qcpOutTxMigrationHeight = height }
+}
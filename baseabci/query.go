@@ -0,0 +1,198 @@
+package baseabci
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/consensus"
+	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/qcp"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/QOSGroup/qbase/validator"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+//内置查询路由:
+//acc:       /custom/acc/account/{address}                     账户, 调用RegisterAccountProto后可用
+//qcp:       /custom/qcp/in-sequence/{chainId}                 已接收chainId的最大qcp序号
+//           /custom/qcp/in-pubkey/{chainId}                   chainId的qcp信任公钥
+//           /custom/qcp/out-sequence/{chainId}                输出到chainId的最大qcp序号
+//           /custom/qcp/out-ack-sequence/{chainId}            chainId已确认的最大qcp序号
+//           /custom/qcp/out-tx/{chainId}/{seq}                输出到chainId的qcp tx
+//           /custom/qcp/out-txs/{chainId}/{fromSeq}/{limit}   按序号返回输出到chainId的qcp tx, 最多qcp.MaxOutTxsLimit个
+//consensus: /custom/consensus/params                          共识参数
+//validator: /custom/validator/last-proposer                   上一区块proposer
+//           /custom/validator/updates                         validator更新集合
+//查询结果使用amino编码. 内置handler不支持的路由交由CustomQueryHandler处理, 与内置路由同名的自定义查询不会被屏蔽
+func (app *BaseApp) registerQueryHandlers() {
+	app.RegisterQueryHandler(qcp.MapperName, app.qcpQueryHandler)
+	app.RegisterQueryHandler(consensus.ConsensusMapperName, app.consensusQueryHandler)
+	app.RegisterQueryHandler(validator.ValidatorMapperName, app.validatorQueryHandler)
+}
+
+//newQueryContext 返回height高度状态的只读ctx, height为0时为最新高度
+func (app *BaseApp) newQueryContext(height int64) (ctx.Context, types.Error) {
+	latest := app.LastBlockHeight()
+	if height < 0 {
+		return ctx.Context{}, types.ErrUnknownRequest(fmt.Sprintf("invalid query height: %d", height))
+	}
+	if height == 0 {
+		height = latest
+	}
+	if height > latest {
+		return ctx.Context{}, types.ErrUnknownRequest(fmt.Sprintf("query height %d is higher than latest height %d", height, latest))
+	}
+
+	var ms store.CacheMultiStore
+	if height == latest {
+		ms = app.cms.CacheMultiStore()
+	} else {
+		var err error
+		if ms, err = app.cms.CacheMultiStoreWithVersion(height); err != nil {
+			return ctx.Context{}, types.ErrUnknownRequest(err.Error())
+		}
+	}
+
+	header := app.checkState.ctx.BlockHeader()
+	if header.Height != height {
+		header = abci.Header{ChainID: header.ChainID, Height: height}
+	}
+	return ctx.NewContext(ms, header, true, app.Logger, app.registerMappers), nil
+}
+
+func (app *BaseApp) accountQueryHandler(ctx ctx.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+	if len(route) != 2 || route[0] != "account" {
+		return nil, unknownQueryRoute(account.AccountMapperName, route)
+	}
+
+	addr, err := types.AccAddressFromBech32(route[1])
+	if err != nil {
+		return nil, types.ErrInvalidAddress(err.Error())
+	}
+
+	acc := GetAccountMapper(ctx).GetAccount(addr)
+	if acc == nil {
+		return nil, types.ErrUnknownAddress(fmt.Sprintf("account %s does not exist", addr))
+	}
+	return app.marshalQueryResult(acc)
+}
+
+func (app *BaseApp) qcpQueryHandler(ctx ctx.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+	if len(route) < 2 {
+		return nil, unknownQueryRoute(qcp.MapperName, route)
+	}
+
+	qcpMapper, chainID := GetQcpMapper(ctx), route[1]
+	switch {
+	case route[0] == "in-sequence" && len(route) == 2:
+		return app.marshalQueryResult(qcpMapper.GetMaxChainInSequence(chainID))
+	case route[0] == "in-pubkey" && len(route) == 2:
+		pubkey := qcpMapper.GetChainInTrustPubKey(chainID)
+		if pubkey == nil {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("qcp pubkey of %s does not exist", chainID))
+		}
+		return app.marshalQueryResult(pubkey)
+	case route[0] == "out-sequence" && len(route) == 2:
+		return app.marshalQueryResult(qcpMapper.GetMaxChainOutSequence(chainID))
+	case route[0] == "out-ack-sequence" && len(route) == 2:
+		return app.marshalQueryResult(qcpMapper.GetChainOutAckSequence(chainID))
+	case route[0] == "out-tx" && len(route) == 3:
+		seq, err := strconv.ParseInt(route[2], 10, 64)
+		if err != nil {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("invalid seq: %s", route[2]))
+		}
+		txQcp := qcpMapper.GetChainOutTxs(chainID, seq)
+		if txQcp == nil {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("qcp tx %d to %s does not exist", seq, chainID))
+		}
+		return app.marshalQueryResult(txQcp)
+	case route[0] == "out-txs" && len(route) == 4:
+		fromSeq, err := strconv.ParseInt(route[2], 10, 64)
+		if err != nil {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("invalid fromSeq: %s", route[2]))
+		}
+		limit, err := strconv.Atoi(route[3])
+		if err != nil {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("invalid limit: %s", route[3]))
+		}
+		return app.marshalQueryResult(qcpMapper.IterateChainOutTxs(chainID, fromSeq, limit))
+	}
+
+	return nil, unknownQueryRoute(qcp.MapperName, route)
+}
+
+func (app *BaseApp) consensusQueryHandler(ctx ctx.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+	if len(route) != 1 || route[0] != "params" {
+		return nil, unknownQueryRoute(consensus.ConsensusMapperName, route)
+	}
+
+	params := GetConsParams(ctx)
+	if params == nil {
+		return nil, types.ErrUnknownRequest("consensus params does not exist")
+	}
+	return app.marshalQueryResult(params)
+}
+
+func (app *BaseApp) validatorQueryHandler(ctx ctx.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+	if len(route) != 1 {
+		return nil, unknownQueryRoute(validator.ValidatorMapperName, route)
+	}
+
+	validatorMapper := validator.GetValidatorMapper(ctx)
+	switch route[0] {
+	case "last-proposer":
+		proposer, exists := validatorMapper.GetLastBlockProposer()
+		if !exists {
+			return nil, types.ErrUnknownRequest("last block proposer does not exist")
+		}
+		return app.marshalQueryResult(proposer)
+	case "updates":
+		return app.marshalQueryResult(validatorMapper.GetValidatorUpdateSet())
+	}
+
+	return nil, unknownQueryRoute(validator.ValidatorMapperName, route)
+}
+
+func (app *BaseApp) marshalQueryResult(o interface{}) ([]byte, types.Error) {
+	bz, err := app.cdc.MarshalBinaryBare(o)
+	if err != nil {
+		return nil, types.ErrInternal(err.Error())
+	}
+	return bz, nil
+}
+
+//unknownRouteError 内置查询handler不支持的路由, 注册了CustomQueryHandler时交由其处理
+type unknownRouteError struct {
+	err types.Error
+}
+
+var _ types.Error = unknownRouteError{}
+
+func unknownQueryRoute(name string, route []string) types.Error {
+	return unknownRouteError{err: types.ErrUnknownRequest(fmt.Sprintf("unknown %s query route: %v", name, route))}
+}
+
+func (e unknownRouteError) Error() string                   { return e.err.Error() }
+func (e unknownRouteError) Stacktrace() cmn.Error           { return e.err.Stacktrace() }
+func (e unknownRouteError) Data() interface{}               { return e.err.Data() }
+func (e unknownRouteError) Code() types.CodeType            { return e.err.Code() }
+func (e unknownRouteError) Codespace() types.CodespaceType  { return e.err.Codespace() }
+func (e unknownRouteError) ABCILog() string                 { return e.err.ABCILog() }
+func (e unknownRouteError) Result() types.Result            { return e.err.Result() }
+func (e unknownRouteError) QueryResult() abci.ResponseQuery { return e.err.QueryResult() }
+
+func (e unknownRouteError) Trace(offset int, format string, args ...interface{}) cmn.Error {
+	return e.err.Trace(offset+1, format, args...)
+}
+
+func (e unknownRouteError) TraceSDK(format string, args ...interface{}) types.Error {
+	e.err.Trace(1, format, args...)
+	return e
+}
+
+func (e unknownRouteError) WithDefaultCodespace(cs types.CodespaceType) types.Error {
+	return unknownRouteError{err: e.err.WithDefaultCodespace(cs)}
+}
@@ -0,0 +1,102 @@
+package baseabci
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+func TestQueryRouter(t *testing.T) {
+	app, txBytes := newSigBenchApp(t, 2,
+		func(app *BaseApp) {
+			app.RegisterQueryHandler("height", func(ctx context.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+				return []byte(fmt.Sprintf("%d:%s", ctx.BlockHeight(), strings.Join(route, "/"))), nil
+			})
+		},
+		func(app *BaseApp) {
+			app.RegisterCustomQueryHandler(func(ctx context.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+				return []byte(strings.Join(route, "/")), nil
+			})
+		})
+	require.Panics(t, func() { app.RegisterQueryHandler("height", nil) })
+
+	var stdTx txs.TxStd
+	app.GetCdc().MustUnmarshalBinaryBare(txBytes[0], &stdTx)
+	addr := stdTx.ITxs[0].(*transferTx).FromUsers[0]
+	deliverBlock(t, app, txBytes[:1])
+
+	queryAccount := func(height int64) (abci.ResponseQuery, *testAccount) {
+		res := app.Query(abci.RequestQuery{Path: "/custom/acc/account/" + addr.String(), Height: height})
+		if res.Code != uint32(types.CodeOK) {
+			return res, nil
+		}
+		var acc account.Account
+		app.GetCdc().MustUnmarshalBinaryBare(res.Value, &acc)
+		return res, acc.(*testAccount)
+	}
+
+	//默认基于最新高度
+	res, acc := queryAccount(0)
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, int64(2), res.Height)
+	require.Equal(t, int64(5499), acc.Money)
+
+	//基于历史高度
+	res, acc = queryAccount(1)
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, int64(1), res.Height)
+	require.Equal(t, int64(5500), acc.Money)
+
+	res, _ = queryAccount(3)
+	require.Equal(t, uint32(types.CodeUnknownRequest), res.Code)
+	res, _ = queryAccount(-1)
+	require.Equal(t, uint32(types.CodeUnknownRequest), res.Code)
+
+	res = app.Query(abci.RequestQuery{Path: "/custom/height/a/b", Height: 1})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, "1:a/b", string(res.Value))
+
+	//未注册的路由交由CustomQueryHandler处理
+	res = app.Query(abci.RequestQuery{Path: "/custom/legacy/a"})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, "legacy/a", string(res.Value))
+
+	//内置handler不支持的路由交由CustomQueryHandler处理
+	res = app.Query(abci.RequestQuery{Path: "/custom/acc/unknown"})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, "acc/unknown", string(res.Value))
+	res = app.Query(abci.RequestQuery{Path: "/custom/qcp/pending/qstar"})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, "qcp/pending/qstar", string(res.Value))
+
+	res = app.Query(abci.RequestQuery{Path: "/custom/qcp/out-sequence/qstar"})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	var seq int64
+	app.GetCdc().MustUnmarshalBinaryBare(res.Value, &seq)
+	require.Equal(t, int64(0), seq)
+
+	res = app.Query(abci.RequestQuery{Path: "/custom/validator/updates"})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+}
+
+func TestQueryRouterUnknownRoute(t *testing.T) {
+	app, _ := newSigBenchApp(t, 1)
+
+	res := app.Query(abci.RequestQuery{Path: "/custom/unknown/a"})
+	require.Equal(t, uint32(types.CodeUnknownRequest), res.Code)
+	require.Contains(t, res.Log, "unknown query route: unknown")
+
+	res = app.Query(abci.RequestQuery{Path: "/custom/acc/unknown"})
+	require.Equal(t, uint32(types.CodeUnknownRequest), res.Code)
+	require.Contains(t, res.Log, "unknown acc query route")
+
+	res = app.Query(abci.RequestQuery{Path: "/custom"})
+	require.Equal(t, uint32(types.CodeUnknownRequest), res.Code)
+}
@@ -0,0 +1,185 @@
+package baseabci
+
+import (
+	"runtime"
+	"sync"
+
+	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+//DefaultSigVerifyCacheSize 签名校验缓存默认容量
+const DefaultSigVerifyCacheSize = 10000
+
+//SigVerifyCache 已通过签名校验的TxStd缓存, key为tx hash
+//checkTx及PreVerifyTxs校验通过后写入, deliverTx命中时跳过签名校验并删除. 超出容量时按写入顺序淘汰
+//签名数据由tx字节、签名nonce及签名者公钥确定, NonceCheckDecorator保证deliverTx时二者与缓存时一致, 命中缓存不影响执行结果
+type SigVerifyCache struct {
+	mtx   sync.Mutex
+	size  int
+	txs   map[string]struct{}
+	queue []string
+}
+
+func NewSigVerifyCache(size int) *SigVerifyCache {
+	return &SigVerifyCache{
+		size: size,
+		txs:  make(map[string]struct{}, size),
+	}
+}
+
+func (cache *SigVerifyCache) Has(txHash []byte) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	_, ok := cache.txs[string(txHash)]
+	return ok
+}
+
+func (cache *SigVerifyCache) Add(txHash []byte) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	key := string(txHash)
+	if _, ok := cache.txs[key]; ok {
+		return
+	}
+	cache.txs[key] = struct{}{}
+	cache.queue = append(cache.queue, key)
+
+	for len(cache.queue) > cache.size {
+		delete(cache.txs, cache.queue[0])
+		cache.queue = cache.queue[1:]
+	}
+}
+
+func (cache *SigVerifyCache) Remove(txHash []byte) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	delete(cache.txs, string(txHash))
+}
+
+func (cache *SigVerifyCache) Len() int {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	return len(cache.txs)
+}
+
+//sigVerifyJob 一个tx中全部签名的校验数据
+type sigVerifyJob struct {
+	txHash    []byte
+	tx        *txs.TxStd
+	fromChain string
+	pubkeys   []crypto.PubKey
+}
+
+func (job sigVerifyJob) verify() (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	for i, pubkey := range job.pubkeys {
+		sig := job.tx.Signature[i]
+		if verifySignature(pubkey, job.tx.BuildSignatureBytes(sig.Nonce, job.fromChain), sig.Signature) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//PreVerifyTxs 并行校验txs中TxStd的签名, 校验通过的tx写入签名校验缓存, deliverTx时不再重复校验
+//该方法为可选接口, 本仓库中没有调用方: ABCI在DeliverTx前不提供区块内的tx, BaseApp不会调用该方法,
+//默认流程下DeliverTx仅使用CheckTx阶段写入的缓存. 调用方约定:
+//1. 已知区块内tx时(如自定义的区块执行流程), 在BeginBlock之后、该区块首个DeliverTx之前传入区块全部tx
+//2. 需与ABCI方法在同一goroutine中调用, 不能与Commit等方法并发
+//未调用时DeliverTx按原流程校验签名, 不影响执行结果. 未启用签名校验缓存时不做处理
+func (app *BaseApp) PreVerifyTxs(txBytes [][]byte) {
+	if app.sigCache == nil {
+		return
+	}
+
+	//账户公钥在当前goroutine中读取, 仅签名校验并行执行
+	checkCtx := app.checkState.ctx.WithMultiStore(app.checkState.CacheMultiStore()).WithGasMeter(types.NewInfiniteGasMeter())
+	jobs := make([]sigVerifyJob, 0, len(txBytes))
+	for _, bz := range txBytes {
+		if job, ok := app.newSigVerifyJob(checkCtx, bz); ok {
+			jobs = append(jobs, job)
+		}
+	}
+
+	jobCh := make(chan sigVerifyJob, len(jobs))
+	for _, job := range jobs {
+		jobCh <- job
+	}
+	close(jobCh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				if job.verify() {
+					app.sigCache.Add(job.txHash)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+//newSigVerifyJob 按SigVerifyDecorator的校验方式生成签名校验数据. 签名者公钥优先使用账户公钥
+func (app *BaseApp) newSigVerifyJob(checkCtx ctx.Context, bz []byte) (job sigVerifyJob, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	tx, err := types.DecoderTx(app.cdc, bz)
+	if err != nil {
+		return
+	}
+
+	job.txHash = tmhash.Sum(bz)
+	switch implTx := tx.(type) {
+	case *txs.TxStd:
+		job.tx = implTx
+	case *txs.TxQcp:
+		job.tx, job.fromChain = implTx.TxStd, implTx.From
+	default:
+		return
+	}
+	if job.tx == nil || len(job.tx.ITxs) == 0 || app.sigCache.Has(job.txHash) {
+		return
+	}
+
+	signers := job.tx.GetSigners()
+	if len(signers) == 0 || len(signers) != len(job.tx.Signature) {
+		return
+	}
+
+	accountMapper := GetAccountMapper(checkCtx)
+	if accountMapper == nil {
+		return
+	}
+
+	job.pubkeys = make([]crypto.PubKey, len(signers))
+	for i, addr := range signers {
+		if acc := accountMapper.GetAccount(addr); acc != nil && acc.GetPublicKey() != nil {
+			job.pubkeys[i] = acc.GetPublicKey()
+			continue
+		}
+		pubkey := job.tx.Signature[i].Pubkey
+		if pubkey == nil || !addr.Equals(types.AccAddress(pubkey.Address())) {
+			return
+		}
+		job.pubkeys[i] = pubkey
+	}
+
+	return job, true
+}
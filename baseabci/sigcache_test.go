@@ -0,0 +1,163 @@
+package baseabci
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	go_amino "github.com/tendermint/go-amino"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+//newSigBenchApp 创建使用secp256k1账户的app, 返回每个账户签名的一个转账tx
+func newSigBenchApp(tb testing.TB, accounts int, options ...func(*BaseApp)) (*BaseApp, [][]byte) {
+	app := NewBaseApp("sigbench", nil, log.NewNopLogger(), dbm.NewMemDB(), func(cdc *go_amino.Codec) {
+		cdc.RegisterConcrete(&transferTx{}, "baseapp/test/transferTx", nil)
+		cdc.RegisterConcrete(&testAccount{}, "baseapp/test/testAccount", nil)
+	}, append([]func(*BaseApp){SetPruning(store.PruneSyncable)}, options...)...)
+	app.RegisterAccountProto(func() account.Account {
+		return &testAccount{BaseAccount: &account.BaseAccount{}}
+	})
+
+	privKeys := make([]crypto.PrivKey, accounts)
+	for i := range privKeys {
+		privKeys[i] = secp256k1.GenPrivKey()
+	}
+	app.SetInitChainer(func(ctx context.Context, req abci.RequestInitChain) abci.ResponseInitChain {
+		accMapper := GetAccountMapper(ctx)
+		for _, privKey := range privKeys {
+			acc := accMapper.NewAccountWithAddress(types.AccAddress(privKey.PubKey().Address())).(*testAccount)
+			acc.Money = 5500
+			accMapper.SetAccount(acc)
+		}
+		return abci.ResponseInitChain{}
+	})
+	require.Nil(tb, app.LoadLatestVersion())
+	app.InitChain(abci.RequestInitChain{ChainId: cid})
+	app.Commit()
+
+	txBytes := make([][]byte, accounts)
+	for i, privKey := range privKeys {
+		stdTx := txs.NewTxStd(&transferTx{
+			FromUsers: []types.AccAddress{types.AccAddress(privKey.PubKey().Address())},
+			ToUsers:   []types.AccAddress{types.AccAddress(privKeys[(i+1)%accounts].PubKey().Address())},
+			Amount:    1,
+		}, cid, types.NewInt(50000))
+		sig, err := stdTx.SignTx(privKey, 1, "", cid)
+		require.Nil(tb, err)
+		stdTx.Signature = []txs.Signature{{Pubkey: privKey.PubKey(), Signature: sig, Nonce: 1}}
+		txBytes[i] = app.GetCdc().MustMarshalBinaryBare(stdTx)
+	}
+
+	return app, txBytes
+}
+
+func deliverBlock(tb testing.TB, app *BaseApp, txBytes [][]byte) {
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: app.LastBlockHeight() + 1, ChainID: cid}})
+	for _, bz := range txBytes {
+		res := app.DeliverTx(abci.RequestDeliverTx{Tx: bz})
+		require.Equal(tb, uint32(0), res.Code, res.Log)
+	}
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+}
+
+func TestSigVerifyCache(t *testing.T) {
+	app, txBytes := newSigBenchApp(t, 4)
+
+	//checkTx校验通过后写入缓存
+	res := app.CheckTx(abci.RequestCheckTx{Tx: txBytes[0]})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	require.Equal(t, 1, app.sigCache.Len())
+
+	//签名错误的tx不写入缓存
+	var stdTx txs.TxStd
+	app.GetCdc().MustUnmarshalBinaryBare(txBytes[3], &stdTx)
+	stdTx.Signature[0].Signature[0]++
+	forged := app.GetCdc().MustMarshalBinaryBare(&stdTx)
+
+	app.PreVerifyTxs([][]byte{txBytes[1], txBytes[2], forged})
+	require.Equal(t, 3, app.sigCache.Len())
+
+	//模拟执行不删除缓存
+	sres := app.Simulate(txBytes[1], false)
+	require.Equal(t, uint32(0), uint32(sres.Code), sres.Log)
+	require.Equal(t, 3, app.sigCache.Len())
+
+	//deliverTx命中后删除
+	deliverBlock(t, app, txBytes[:3])
+	require.Equal(t, 0, app.sigCache.Len())
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: app.LastBlockHeight() + 1, ChainID: cid}})
+	dres := app.DeliverTx(abci.RequestDeliverTx{Tx: forged})
+	require.NotEqual(t, uint32(0), dres.Code)
+	require.Contains(t, dres.Log, "signature verification failed")
+
+	//已缓存的tx重复执行时nonce校验失败
+	app.PreVerifyTxs([][]byte{txBytes[3]})
+	dres = app.DeliverTx(abci.RequestDeliverTx{Tx: txBytes[3]})
+	require.Equal(t, uint32(0), dres.Code, dres.Log)
+	dres = app.DeliverTx(abci.RequestDeliverTx{Tx: txBytes[3]})
+	require.NotEqual(t, uint32(0), dres.Code)
+	require.Contains(t, dres.Log, "invalid nonce")
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+
+	//超出容量时淘汰最早写入的tx
+	cache := NewSigVerifyCache(2)
+	cache.Add([]byte("a"))
+	cache.Add([]byte("b"))
+	cache.Add([]byte("c"))
+	require.False(t, cache.Has([]byte("a")))
+	require.True(t, cache.Has([]byte("c")))
+	require.Equal(t, 2, cache.Len())
+}
+
+//BenchmarkSigVerifyCache 不同签名校验缓存来源下DeliverTx的耗时: 无缓存, checkTx缓存, 调用方显式调用PreVerifyTxs
+//BaseApp自身不调用PreVerifyTxs, preverify仅反映调用方接入后的效果
+func BenchmarkSigVerifyCache(b *testing.B) {
+	const blockSize = 200
+
+	cases := []struct {
+		name  string
+		setup func(b *testing.B, app *BaseApp, txBytes [][]byte)
+	}{
+		{"serial", func(b *testing.B, app *BaseApp, txBytes [][]byte) {}},
+		{"checktx-cache", func(b *testing.B, app *BaseApp, txBytes [][]byte) {
+			b.StopTimer()
+			for _, bz := range txBytes {
+				app.CheckTx(abci.RequestCheckTx{Tx: bz})
+			}
+			b.StartTimer()
+		}},
+		{"preverify", func(b *testing.B, app *BaseApp, txBytes [][]byte) {
+			app.PreVerifyTxs(txBytes)
+		}},
+	}
+
+	for _, c := range cases {
+		b.Run(fmt.Sprintf("%s-%d", c.name, blockSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				var options []func(*BaseApp)
+				if c.name == "serial" {
+					options = append(options, SetSigVerifyCacheSize(0))
+				}
+				app, txBytes := newSigBenchApp(b, blockSize, options...)
+				b.StartTimer()
+
+				c.setup(b, app, txBytes)
+				deliverBlock(b, app, txBytes)
+			}
+		})
+	}
+}
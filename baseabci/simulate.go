@@ -0,0 +1,114 @@
+package baseabci
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	ctx "github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+)
+
+const (
+	//SimulateSkipSigVerify 模拟执行时跳过签名校验. query path: /app/simulate/skipsig
+	SimulateSkipSigVerify = "skipsig"
+)
+
+//Simulate 基于checkState的缓存模拟执行交易,返回执行结果及gas消耗. 模拟执行的数据不会被保存
+//skipSigVerify为true时不校验签名, 可用于估算未签名交易的gas消耗
+func (app *BaseApp) Simulate(txBytes []byte, skipSigVerify bool) (result types.Result) {
+	tx, err := types.DecoderTx(app.cdc, txBytes)
+	if err != nil {
+		return err.Result()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case types.ErrorOutOfGas:
+				log := "simulate out of gas"
+				result = types.ErrOutOfGas(log).Result()
+			default:
+				log := fmt.Sprintf("simulate recovered: %v\nstack:\n%v", r, string(debug.Stack()))
+				result = types.ErrInternal(log).Result()
+			}
+		}
+	}()
+
+	//模拟执行在checkState的缓存上进行,执行结果将被丢弃
+	//按deliverTx流程执行,以统计gasPreHandler等处理消耗的gas
+	simulateCtx := withSimulate(app.checkState.ctx).
+		WithIsCheckTx(false).
+		WithMultiStore(app.checkState.CacheMultiStore()).
+		WithTxBytes(txBytes).
+		WithTxQcpResultHandler(app.txQcpResultHandler).
+		WithEventManager(types.NewEventManager())
+
+	switch implTx := tx.(type) {
+	case *txs.TxStd:
+		result = app.simulateTxStd(simulateCtx, implTx, "", skipSigVerify)
+	case *txs.TxQcp:
+		result = app.simulateTxQcp(simulateCtx, implTx, skipSigVerify)
+	default:
+		result = types.ErrInternal("not support itx type").Result()
+	}
+
+	result.Events = simulateCtx.EventManager().Events().AppendEvents(result.Events)
+
+	return
+}
+
+func (app *BaseApp) simulateTxStd(ctx ctx.Context, tx *txs.TxStd, txStdFromChainID string, skipSigVerify bool) (result types.Result) {
+	ctx = setSimulateGasMeter(ctx, tx)
+
+	defer func() {
+		if result.GasUsed == 0 {
+			result.GasUsed = ctx.GasMeter().GasConsumed()
+		}
+		result.GasWanted = uint64(tx.MaxGas.Int64())
+	}()
+
+	result, _ = app.runTxStd(ctx, tx, txStdFromChainID, skipSigVerify)
+	return
+}
+
+func (app *BaseApp) simulateTxQcp(ctx ctx.Context, tx *txs.TxQcp, skipSigVerify bool) (result types.Result) {
+	checkCtx := setSimulateGasMeter(ctx, tx.TxStd)
+	if nil != app.gasPreHandler {
+		if err := app.gasPreHandler(checkCtx, tx.TxStd.ITxs[0].GetGasPayer()); err != nil {
+			return err.Result()
+		}
+	}
+	result = app.checkTxQcp(checkCtx, tx, skipSigVerify)
+	if !result.IsOK() {
+		return
+	}
+
+	return app.simulateTxStd(ctx, tx.TxStd, tx.From, skipSigVerify)
+}
+
+//simulateKey 模拟执行的ctx中保存的标记
+type simulateKey struct{}
+
+func withSimulate(ctx ctx.Context) ctx.Context {
+	return ctx.WithValue(simulateKey{}, true)
+}
+
+//isSimulate 是否为模拟执行, 模拟执行时不修改签名校验缓存等节点本地状态
+func isSimulate(ctx ctx.Context) bool {
+	simulate, _ := ctx.Value(simulateKey{}).(bool)
+	return simulate
+}
+
+//setSimulateGasMeter 模拟执行时不限制gas,用于统计交易实际消耗的gas
+func setSimulateGasMeter(ctx ctx.Context, tx *txs.TxStd) ctx.Context {
+	gm := types.NewInfiniteGasMeter()
+
+	txsGas := types.ZeroInt()
+	for _, itx := range tx.ITxs {
+		txsGas = txsGas.Add(itx.CalcGas())
+	}
+	gm.ConsumeGas(uint64(txsGas.Int64()), "exceeded limit gas or overflow")
+
+	return ctx.WithGasMeter(gm)
+}
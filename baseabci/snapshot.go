@@ -0,0 +1,74 @@
+package baseabci
+
+import (
+	"errors"
+
+	"github.com/QOSGroup/qbase/store/snapshots"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+//以下方法对应tendermint state sync的ABCI方法: ListSnapshots/LoadSnapshotChunk/OfferSnapshot/ApplySnapshotChunk
+//当前依赖的tendermint版本(v0.32)尚未提供state sync, 升级到v0.34+后由ABCI接口直接调用
+
+var errSnapshotDisabled = errors.New("snapshot is not enabled")
+
+//ListSnapshots 返回本地保存的快照, 高度由高到低
+func (app *BaseApp) ListSnapshots() ([]*snapshots.Snapshot, error) {
+	if app.snapshotManager == nil {
+		return nil, errSnapshotDisabled
+	}
+	return app.snapshotManager.List()
+}
+
+//LoadSnapshotChunk 返回快照的第chunk个分块
+func (app *BaseApp) LoadSnapshotChunk(height int64, format uint32, chunk uint32) ([]byte, error) {
+	if app.snapshotManager == nil {
+		return nil, errSnapshotDisabled
+	}
+	return app.snapshotManager.LoadChunk(height, format, chunk)
+}
+
+//OfferSnapshot 开始从快照恢复状态, appHash为快照高度下可信的app hash
+func (app *BaseApp) OfferSnapshot(snapshot snapshots.Snapshot, appHash []byte) error {
+	if app.snapshotManager == nil {
+		return errSnapshotDisabled
+	}
+	if app.LastBlockHeight() != 0 {
+		return errors.New("cannot restore snapshot into non-empty app")
+	}
+	return app.snapshotManager.Restore(snapshot, appHash)
+}
+
+//ApplySnapshotChunk 按顺序应用快照分块, 全部分块应用并校验通过后返回true
+func (app *BaseApp) ApplySnapshotChunk(chunk []byte) (bool, error) {
+	if app.snapshotManager == nil {
+		return false, errSnapshotDisabled
+	}
+
+	done, err := app.snapshotManager.RestoreChunk(chunk)
+	if err != nil || !done {
+		return done, err
+	}
+
+	//状态恢复后重置checkState
+	app.setCheckState(abci.Header{})
+	app.Logger.Info("snapshot restored", "height", app.LastBlockHeight())
+	return true, nil
+}
+
+//snapshot 在commit后按snapshotInterval创建快照, 并删除多余的旧快照
+func (app *BaseApp) snapshot(height int64) {
+	app.Logger.Info("creating snapshot", "height", height)
+	snapshot, err := app.snapshotManager.Create(height)
+	if err != nil {
+		app.Logger.Error("failed to create snapshot", "height", height, "err", err)
+		return
+	}
+	app.Logger.Info("completed snapshot", "height", height, "chunks", snapshot.Chunks)
+
+	if app.snapshotKeepRecent > 0 {
+		if err := app.snapshotManager.Prune(app.snapshotKeepRecent); err != nil {
+			app.Logger.Error("failed to prune snapshots", "err", err)
+		}
+	}
+}
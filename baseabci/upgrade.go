@@ -0,0 +1,57 @@
+package baseabci
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	ctx "github.com/QOSGroup/qbase/context"
+)
+
+//applyUpgrade BeginBlock中执行到达高度的升级计划:
+//已注册计划handler时执行handler并记录计划已执行; 未注册时panic, 旧版本程序在计划高度停止出块
+//计划高度前已注册计划handler时panic, 避免提前替换程序导致状态不一致
+func (app *BaseApp) applyUpgrade(ctx ctx.Context) {
+	upgradeMapper := GetUpgradeMapper(ctx)
+	if upgradeMapper == nil {
+		return
+	}
+
+	plan, exists := upgradeMapper.GetPlan()
+	if !exists {
+		return
+	}
+
+	handler, ok := app.upgradeHandlers[plan.Name]
+	if !plan.ShouldExecute(ctx.BlockHeight()) {
+		if ok {
+			panic(fmt.Sprintf("BINARY UPDATED BEFORE TRIGGER! UPGRADE \"%s\" at height %d, current height %d", plan.Name, plan.Height, ctx.BlockHeight()))
+		}
+		return
+	}
+
+	if !ok {
+		msg := fmt.Sprintf("UPGRADE \"%s\" NEEDED at height %d: %s", plan.Name, plan.Height, plan.Info)
+		app.Logger.Error(msg)
+		panic(msg)
+	}
+
+	app.Logger.Info("applying upgrade", "name", plan.Name, "height", ctx.BlockHeight())
+	handler(ctx)
+	upgradeMapper.ApplyUpgrade(ctx, plan)
+}
+
+//halt 停止节点. 优先发送SIGINT/SIGTERM信号正常退出, 失败时直接退出
+func (app *BaseApp) halt() {
+	app.Logger.Info("halting node per configuration", "height", app.haltHeight)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		if p.Signal(syscall.SIGINT) == nil || p.Signal(syscall.SIGTERM) == nil {
+			return
+		}
+	}
+
+	app.Logger.Info("failed to send SIGINT/SIGTERM; exiting...")
+	os.Exit(0)
+}
@@ -0,0 +1,88 @@
+package baseabci
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/QOSGroup/qbase/upgrade"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestUpgrade(t *testing.T) {
+	db := dbm.NewMemDB()
+	newApp := func(handlers map[string]UpgradeHandler) *BaseApp {
+		app := mockAppWithDB(db)
+		app.RegisterMapper(upgrade.NewUpgradeMapper(app.GetCdc()))
+		for name, handler := range handlers {
+			app.RegisterUpgradeHandler(name, handler)
+		}
+		require.Nil(t, app.LoadLatestVersion())
+		return app
+	}
+	beginBlock := func(app *BaseApp) {
+		app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: app.LastBlockHeight() + 1, ChainID: cid}})
+	}
+	endBlock := func(app *BaseApp) {
+		app.EndBlock(abci.RequestEndBlock{})
+		app.Commit()
+	}
+
+	//旧版本程序
+	oldApp := newApp(nil)
+	oldApp.InitChain(abci.RequestInitChain{ChainId: cid})
+	authority := getAccount(GetAccountMapper(oldApp.deliverState.ctx), 1)
+	GetUpgradeMapper(oldApp.deliverState.ctx).SetAuthority(authority.GetAddress())
+	oldApp.Commit()
+
+	stdTx := txs.NewTxStd(&upgrade.TxScheduleUpgrade{
+		Authority: authority.GetAddress(),
+		Plan:      upgrade.Plan{Name: "v2", Height: 4, Info: "binary: v2"},
+	}, cid, types.NewInt(50000))
+	sig, err := stdTx.SignTx(authority.PrivKey, 1, "", cid)
+	require.Nil(t, err)
+	stdTx.Signature = []txs.Signature{{Pubkey: authority.PrivKey.PubKey(), Signature: sig, Nonce: 1}}
+
+	beginBlock(oldApp)
+	res := oldApp.DeliverTx(abci.RequestDeliverTx{Tx: oldApp.GetCdc().MustMarshalBinaryBare(stdTx)})
+	require.Equal(t, uint32(0), res.Code, res.Log)
+	endBlock(oldApp)
+
+	applied := 0
+	handlers := map[string]UpgradeHandler{
+		"v2": func(ctx context.Context) {
+			applied++
+			acc := getAccount(GetAccountMapper(ctx), 2)
+			acc.Money = 6000
+			GetAccountMapper(ctx).SetAccount(acc)
+		},
+	}
+
+	//计划高度前替换程序
+	require.Panics(t, func() { beginBlock(newApp(handlers)) })
+
+	beginBlock(oldApp)
+	endBlock(oldApp)
+
+	//旧版本程序在计划高度停止出块
+	require.Panics(t, func() { beginBlock(oldApp) })
+
+	//新版本程序在计划高度执行升级
+	app := newApp(handlers)
+	require.Equal(t, int64(3), app.LastBlockHeight())
+	beginBlock(app)
+	endBlock(app)
+	beginBlock(app)
+	endBlock(app)
+	require.Equal(t, 1, applied)
+
+	require.Equal(t, int64(6000), getAccount(GetAccountMapper(app.checkState.ctx), 2).Money)
+	_, exists := GetUpgradeMapper(app.checkState.ctx).GetPlan()
+	require.False(t, exists)
+	height, done := GetUpgradeMapper(app.checkState.ctx).GetDoneHeight("v2")
+	require.True(t, done)
+	require.Equal(t, int64(4), height)
+}
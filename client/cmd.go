@@ -5,6 +5,8 @@ import (
 	"github.com/QOSGroup/qbase/client/block"
 	"github.com/QOSGroup/qbase/client/keys"
 	"github.com/QOSGroup/qbase/client/qcp"
+	"github.com/QOSGroup/qbase/client/sign"
+	"github.com/QOSGroup/qbase/client/tx"
 	"github.com/QOSGroup/qbase/client/types"
 	"github.com/spf13/cobra"
 
@@ -64,6 +66,19 @@ func TxCommand() *cobra.Command {
 	return txCommand
 }
 
+//TxCommands 离线签名, 模拟执行及广播交易等通用的tx子命令
+//ex: client.TxCommand().AddCommand(client.TxCommands(cdc)...)
+func TxCommands(cdc *go_amino.Codec) []*cobra.Command {
+	return []*cobra.Command{
+		sign.SignCommand(cdc),
+		sign.MultiSignCommand(cdc),
+		sign.MergeSignaturesCommand(cdc),
+		sign.ValidateSignaturesCommand(cdc),
+		tx.SimulateCmd(cdc),
+		tx.BroadcastCmd(cdc),
+	}
+}
+
 //KeysCommand add keys subcommand
 func KeysCommand(cdc *go_amino.Codec) *cobra.Command {
 	return keys.KeysCommand(cdc)
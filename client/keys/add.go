@@ -1,7 +1,9 @@
 package keys
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 
 	"github.com/QOSGroup/qbase/client/context"
 	"github.com/QOSGroup/qbase/client/utils"
@@ -10,11 +12,16 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	go_amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/multisig"
 	"github.com/tendermint/tendermint/libs/cli"
 )
 
 const (
-	flagRecover = "recover"
+	flagRecover           = "recover"
+	flagMultisig          = "multisig"
+	flagMultisigThreshold = "multisig-threshold"
+	flagNoSort            = "nosort"
 )
 
 func addKeyCommand(cdc *go_amino.Codec) *cobra.Command {
@@ -24,13 +31,20 @@ func addKeyCommand(cdc *go_amino.Codec) *cobra.Command {
 		Short: "Create a new key, or import from seed",
 		Long: `Add a public/private key pair to the key store.
 If you select --recover you can recover a key from the seed
-phrase, otherwise, a new key will be generated.`,
+phrase, otherwise, a new key will be generated.
+
+Use --multisig to store a multisig public key made of existing keys,
+e.g. a 2-of-3 treasury key:
+$ basecli keys add treasury --multisig=alice,bob,carol --multisig-threshold=2`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliCtx := context.NewCLIContext().WithCodec(cdc)
 			return runAddCmd(cliCtx, cmd, args)
 		},
 	}
 	cmd.Flags().Bool(flagRecover, false, "Provide seed phrase to recover existing key instead of creating")
+	cmd.Flags().StringSlice(flagMultisig, nil, "Construct and store a multisig public key from the given comma separated key names")
+	cmd.Flags().Int(flagMultisigThreshold, 1, "K out of N required signatures. For use in conjunction with --multisig")
+	cmd.Flags().Bool(flagNoSort, false, "Keys passed to --multisig are taken in the order they're supplied")
 	return cmd
 }
 
@@ -54,6 +68,11 @@ func runAddCmd(ctx context.CLIContext, cmd *cobra.Command, args []string) error
 		}
 	}
 
+	//多签公钥无私钥, 无需设置密码
+	if multisigKeys := viper.GetStringSlice(flagMultisig); len(multisigKeys) != 0 {
+		return addMultisigKey(ctx, kb, name, multisigKeys, viper.GetInt(flagMultisigThreshold), !viper.GetBool(flagNoSort))
+	}
+
 	pass, err = utils.GetCheckPassword(
 		"Enter a passphrase for your key:",
 		"Repeat the passphrase:", buf)
@@ -82,6 +101,37 @@ func runAddCmd(ctx context.CLIContext, cmd *cobra.Command, args []string) error
 	return nil
 }
 
+//addMultisigKey 由keyNames对应的公钥构造threshold-of-N多签公钥并保存
+//sortKeys为true时按地址排序成员公钥, 使不同成员创建的多签公钥一致
+func addMultisigKey(ctx context.CLIContext, kb keys.Keybase, name string, keyNames []string, threshold int, sortKeys bool) error {
+	if threshold <= 0 || threshold > len(keyNames) {
+		return fmt.Errorf("threshold must be between 1 and %d, got %d", len(keyNames), threshold)
+	}
+
+	pks := make([]crypto.PubKey, len(keyNames))
+	for i, keyName := range keyNames {
+		info, err := kb.Get(keyName)
+		if err != nil {
+			return err
+		}
+		pks[i] = info.GetPubKey()
+	}
+
+	if sortKeys {
+		sort.Slice(pks, func(i, j int) bool {
+			return bytes.Compare(pks[i].Address(), pks[j].Address()) < 0
+		})
+	}
+
+	info, err := kb.CreateMulti(name, multisig.NewPubKeyMultisigThreshold(threshold, pks))
+	if err != nil {
+		return err
+	}
+
+	printKeyInfo(ctx, info, Bech32KeyOutput)
+	return nil
+}
+
 func printCreate(ctx context.CLIContext, info keys.Info, seed string) {
 	output := viper.Get(cli.OutputFlag)
 	switch output {
@@ -3,26 +3,49 @@ package qcp
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/QOSGroup/qbase/client/context"
+	"github.com/QOSGroup/qbase/client/keys"
 	go_amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/libs/cli"
+	"github.com/tendermint/tendermint/libs/log"
 )
 
 const (
 	flagOutSeq = "seq"
+	flagFrom   = "from"
+	flagLimit  = "limit"
+
+	flagChainA       = "chain-a"
+	flagNodeA        = "node-a"
+	flagChainB       = "chain-b"
+	flagNodeB        = "node-b"
+	flagRelayKey     = "relay-key"
+	flagInterval     = "interval"
+	flagBatch        = "batch"
+	flagProgressFile = "progress-file"
+	flagProve        = "prove"
 )
 
 func QcpCommands(cdc *go_amino.Codec) []*cobra.Command {
 	return []*cobra.Command{
 		listCommand(cdc),
 		outSeqCmd(cdc),
+		outAckCmd(cdc),
 		inSeqCmd(cdc),
 		outTxCmd(cdc),
+		outTxsCmd(cdc),
+		relayCmd(cdc),
 	}
 }
 
@@ -85,6 +108,36 @@ $ basecli qcp out [outChainID]
 	return cmd
 }
 
+func outAckCmd(cdc *go_amino.Codec) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "ack [chainID]",
+		Args:  cobra.ExactArgs(1),
+		Short: "Get acknowledged sequence to outChain",
+		Long: strings.TrimSpace(`
+Get max contiguous sequence to outChain whose result has been received.
+Out txs up to this sequence have been pruned, except result txs
+
+example:
+$ basecli qcp ack [outChainID]
+`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outChainID := args[0]
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			seq, err := GetOutChainAckSequence(cliCtx, outChainID)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(seq)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func outTxCmd(cdc *go_amino.Codec) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tx [chainID]",
@@ -117,6 +170,124 @@ $ basecli qcp tx [chainID] --seq [Seq]
 	return cmd
 }
 
+func outTxsCmd(cdc *go_amino.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "out-txs [chainID]",
+		Args:  cobra.ExactArgs(1),
+		Short: "List qcp out txs to chainID",
+		Long: strings.TrimSpace(`
+list qcp out txs to chainID in sequence order, starting from sequence --from
+
+example:
+$ basecli qcp out-txs [chainID] --from [Seq] --limit [Limit]
+`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			outChainID := args[0]
+			fromSeq := viper.GetInt64(flagFrom)
+			limit := viper.GetInt(flagLimit)
+
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			result, err := GetOutChainTxs(cliCtx, outChainID, fromSeq, limit)
+
+			if err != nil {
+				return err
+			}
+
+			return cliCtx.PrintResult(result)
+		},
+	}
+
+	cmd.Flags().Int64(flagFrom, 1, "start sequence")
+	cmd.Flags().Int(flagLimit, 100, "max number of txs to return")
+	return cmd
+}
+
+func relayCmd(cdc *go_amino.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "relay",
+		Short: "Relay qcp txs between two chains",
+		Long: strings.TrimSpace(`
+Relay qcp txs between chain-a and chain-b in both directions.
+Out txs are re-signed with relay-key, so each chain must trust the relay pubkey for the other chain.
+With --prove, merkle proofs of out txs are attached instead, for chains which verify qcp txs with light client.
+Relay progress is saved to progress-file.
+
+example:
+$ basecli qcp relay --chain-a [chainA] --node-a tcp://localhost:26657 --chain-b [chainB] --node-b tcp://localhost:36657 --relay-key [keyName]
+$ basecli qcp relay --chain-a [chainA] --node-a tcp://localhost:26657 --chain-b [chainB] --node-b tcp://localhost:36657 --prove
+`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+
+			//轻客户端验证模式下中继无需签名
+			prove := viper.GetBool(flagProve)
+			var signer RelaySigner
+			if keyName := viper.GetString(flagRelayKey); keyName != "" {
+				info, err := keys.GetKeyInfo(cliCtx, keyName)
+				if err != nil {
+					return err
+				}
+				pass, err := keys.GetPassphrase(cliCtx, keyName)
+				if err != nil {
+					return err
+				}
+				keybase, err := keys.GetKeyBase(cliCtx)
+				if err != nil {
+					return err
+				}
+				signer = func(data []byte) ([]byte, crypto.PubKey, error) {
+					return keybase.Sign(info.GetName(), pass, data)
+				}
+			} else if !prove {
+				return fmt.Errorf("--%s is required unless --%s is set", flagRelayKey, flagProve)
+			}
+
+			chainA := NewNodeRelayChain(cdc, viper.GetString(flagChainA), viper.GetString(flagNodeA))
+			chainB := NewNodeRelayChain(cdc, viper.GetString(flagChainB), viper.GetString(flagNodeB))
+
+			progressFile := viper.GetString(flagProgressFile)
+			if progressFile == "" {
+				progressFile = filepath.Join(viper.GetString(cli.HomeFlag), "relay", fmt.Sprintf("%s-%s.json", chainA.ChainID(), chainB.ChainID()))
+			}
+
+			logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout)).With("module", "qcp-relay")
+			relayer, err := NewRelayer(chainA, chainB, signer, progressFile, logger)
+			if err != nil {
+				return err
+			}
+			relayer.SetBatchSize(viper.GetInt(flagBatch))
+			relayer.SetProve(prove)
+
+			stop := make(chan struct{})
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				<-sigs
+				close(stop)
+			}()
+
+			logger.Info("starting qcp relay", "chain-a", chainA.ChainID(), "chain-b", chainB.ChainID(), "progress", progressFile)
+			relayer.Start(time.Duration(viper.GetInt64(flagInterval))*time.Second, stop)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagChainA, "", "chain id of chain a")
+	cmd.Flags().String(flagNodeA, "tcp://localhost:26657", "<host>:<port> to tendermint rpc interface of chain a")
+	cmd.Flags().String(flagChainB, "", "chain id of chain b")
+	cmd.Flags().String(flagNodeB, "", "<host>:<port> to tendermint rpc interface of chain b")
+	cmd.Flags().String(flagRelayKey, "", "name of the key signing relayed txs")
+	cmd.Flags().Int64(flagInterval, 5, "polling interval in seconds")
+	cmd.Flags().Int(flagBatch, 100, "max number of txs relayed per chain each polling")
+	cmd.Flags().Bool(flagProve, false, "attach merkle proofs to relayed txs for chains verifying qcp txs with light client")
+	cmd.Flags().String(flagProgressFile, "", "relay progress file, default: $home/relay/[chainA]-[chainB].json")
+	cmd.MarkFlagRequired(flagChainA)
+	cmd.MarkFlagRequired(flagChainB)
+	cmd.MarkFlagRequired(flagNodeB)
+	return cmd
+}
+
 func inSeqCmd(cdc *go_amino.Codec) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "in [chainID]",
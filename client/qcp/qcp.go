@@ -37,6 +37,28 @@ func GetOutChainSequence(ctx context.CLIContext, outChainID string) (int64, erro
 	return seq, nil
 }
 
+//GetOutChainAckSequence 返回输出到outChainID的qcp tx已确认的最大连续序号, 未确认时返回0
+func GetOutChainAckSequence(ctx context.CLIContext, outChainID string) (int64, error) {
+	key := qcp.BuildOutAckKey(outChainID)
+	bz, err := query(ctx, key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	if len(bz) == 0 {
+		return seq, nil
+	}
+
+	err = ctx.Codec.UnmarshalBinaryBare(bz, &seq)
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
 func GetGetOutChainTx(ctx context.CLIContext, outChainID string, seq int64) (*txs.TxQcp, error) {
 	key := qcp.BuildOutSequenceTxKey(outChainID, seq)
 	bz, err := query(ctx, key)
@@ -58,6 +80,23 @@ func GetGetOutChainTx(ctx context.CLIContext, outChainID string, seq int64) (*tx
 	return &tx, nil
 }
 
+//GetOutChainTxs 按序号返回输出到outChainID的qcp tx, 从fromSeq开始最多limit个
+func GetOutChainTxs(ctx context.CLIContext, outChainID string, fromSeq int64, limit int) ([]*txs.TxQcp, error) {
+	path := fmt.Sprintf("/app/qcp/out-txs/%s/%d/%d", outChainID, fromSeq, limit)
+	bz, err := ctx.Query(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var txQcps []*txs.TxQcp
+	err = ctx.Codec.UnmarshalBinaryBare(bz, &txQcps)
+	if err != nil {
+		return nil, err
+	}
+
+	return txQcps, nil
+}
+
 func GetInChainSequence(ctx context.CLIContext, inChainID string) (int64, error) {
 	key := qcp.BuildInSequenceKey(inChainID)
 	bz, err := query(ctx, key)
@@ -0,0 +1,342 @@
+package qcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/QOSGroup/qbase/client/context"
+	"github.com/QOSGroup/qbase/qcp"
+	"github.com/QOSGroup/qbase/txs"
+	go_amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/libs/log"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+//RelayChain 中继连接的链
+type RelayChain interface {
+	ChainID() string
+
+	//GetOutSequence 返回需要输出到toChain的qcp tx最大序号
+	GetOutSequence(toChain string) (int64, error)
+
+	//GetInSequence 返回已接收的来自fromChain的qcp tx最大序号
+	GetInSequence(fromChain string) (int64, error)
+
+	//GetOutTxs 按序号返回输出到toChain的qcp tx
+	GetOutTxs(toChain string, fromSeq int64, limit int) ([]*txs.TxQcp, error)
+
+	//BroadcastTxQcp 广播qcp tx, 交易执行失败时返回error
+	BroadcastTxQcp(txQcp *txs.TxQcp) error
+}
+
+//ProofRelayChain 可查询qcp tx存在性证明的链, 用于轻客户端验证模式
+type ProofRelayChain interface {
+	RelayChain
+
+	//GetOutTxProof 返回输出到toChain的第sequence个qcp tx在最新可证明高度下的存在性证明
+	GetOutTxProof(toChain string, sequence int64) (*txs.TxQcpProof, error)
+}
+
+//RelaySigner 中继对qcp tx签名, 目标链需将中继公钥设置为来源链的信任公钥. 轻客户端验证模式下可为nil
+type RelaySigner func(data []byte) ([]byte, crypto.PubKey, error)
+
+//Relayer 在两条链之间双向中继qcp tx:
+//1. 查询来源链sequence/out/[目标链]与目标链sequence/in/[来源链]
+//2. 获取未中继的qcp tx, 使用中继私钥重新签名后广播至目标链
+//   轻客户端验证模式下附加tx存在性证明, 目标链不校验中继签名
+//3. 每个tx中继成功后保存进度至progressFile
+type Relayer struct {
+	chainA RelayChain
+	chainB RelayChain
+	signer RelaySigner
+	logger log.Logger
+
+	batchSize    int
+	prove        bool
+	progressFile string
+
+	mtx      sync.Mutex
+	progress map[string]int64
+}
+
+//NewRelayer 创建chainA, chainB之间的中继, progressFile为空时不保存进度
+func NewRelayer(chainA, chainB RelayChain, signer RelaySigner, progressFile string, logger log.Logger) (*Relayer, error) {
+	r := &Relayer{
+		chainA:       chainA,
+		chainB:       chainB,
+		signer:       signer,
+		logger:       logger,
+		batchSize:    100,
+		progressFile: progressFile,
+		progress:     make(map[string]int64),
+	}
+
+	if progressFile != "" {
+		bz, err := ioutil.ReadFile(progressFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(bz) > 0 {
+			if err := json.Unmarshal(bz, &r.progress); err != nil {
+				return nil, fmt.Errorf("invalid relay progress file %s: %v", progressFile, err)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+//SetBatchSize 设置每次从来源链获取的最大tx数量
+func (r *Relayer) SetBatchSize(size int) {
+	r.batchSize = size
+}
+
+//SetProve 设置是否为中继的tx附加存在性证明, 开启后两条链均需实现ProofRelayChain
+func (r *Relayer) SetProve(prove bool) {
+	r.prove = prove
+}
+
+//Progress 返回from->to已中继的最大序号
+func (r *Relayer) Progress(from, to string) int64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.progress[pathKey(from, to)]
+}
+
+//RelayOnce 双向中继一次, 返回中继的tx数量
+func (r *Relayer) RelayOnce() (int, error) {
+	n1, err := r.relay(r.chainA, r.chainB)
+	if err != nil {
+		return n1, err
+	}
+
+	n2, err := r.relay(r.chainB, r.chainA)
+	return n1 + n2, err
+}
+
+//Start 按interval循环中继, 直到stop关闭
+func (r *Relayer) Start(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayOnce()
+		if err != nil {
+			r.logger.Error("relay error", "err", err)
+		} else if n > 0 {
+			r.logger.Info("relayed qcp txs", "count", n)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relayer) relay(src, dst RelayChain) (int, error) {
+	outSeq, err := src.GetOutSequence(dst.ChainID())
+	if err != nil {
+		return 0, fmt.Errorf("query %s out sequence to %s error: %v", src.ChainID(), dst.ChainID(), err)
+	}
+
+	inSeq, err := dst.GetInSequence(src.ChainID())
+	if err != nil {
+		return 0, fmt.Errorf("query %s in sequence from %s error: %v", dst.ChainID(), src.ChainID(), err)
+	}
+
+	//目标链in sequence为准, 本地进度用于跳过已广播但尚未被目标链查询到的tx
+	fromSeq := inSeq
+	if progress := r.Progress(src.ChainID(), dst.ChainID()); progress > fromSeq && progress <= outSeq {
+		fromSeq = progress
+	}
+	fromSeq++
+	if fromSeq > outSeq {
+		return 0, nil
+	}
+
+	txQcps, err := src.GetOutTxs(dst.ChainID(), fromSeq, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("query %s out txs to %s error: %v", src.ChainID(), dst.ChainID(), err)
+	}
+
+	var proofChain ProofRelayChain
+	if r.prove {
+		var ok bool
+		if proofChain, ok = src.(ProofRelayChain); !ok {
+			return 0, fmt.Errorf("chain %s does not support qcp tx proof", src.ChainID())
+		}
+	}
+
+	relayed := 0
+	for _, txQcp := range txQcps {
+		if proofChain != nil {
+			proof, err := proofChain.GetOutTxProof(dst.ChainID(), txQcp.Sequence)
+			if err != nil {
+				return relayed, fmt.Errorf("query %s out tx proof to %s sequence %d error: %v", src.ChainID(), dst.ChainID(), txQcp.Sequence, err)
+			}
+			txQcp.Proof = proof
+		}
+
+		if r.signer != nil {
+			sig, pubkey, err := r.signer(txQcp.BuildSignatureBytes())
+			if err != nil {
+				return relayed, err
+			}
+			txQcp.Sig = txs.Signature{
+				Pubkey:    pubkey,
+				Signature: sig,
+			}
+		}
+
+		if err := dst.BroadcastTxQcp(txQcp); err != nil {
+			return relayed, fmt.Errorf("relay %s->%s sequence %d error: %v", src.ChainID(), dst.ChainID(), txQcp.Sequence, err)
+		}
+
+		if err := r.saveProgress(src.ChainID(), dst.ChainID(), txQcp.Sequence); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+
+	return relayed, nil
+}
+
+func (r *Relayer) saveProgress(from, to string, seq int64) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.progress[pathKey(from, to)] = seq
+	if r.progressFile == "" {
+		return nil
+	}
+
+	bz, err := json.MarshalIndent(r.progress, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.progressFile), 0755); err != nil {
+		return err
+	}
+
+	tmpFile := r.progressFile + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, bz, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, r.progressFile)
+}
+
+func pathKey(from, to string) string {
+	return from + "->" + to
+}
+
+//nodeChain 通过tendermint rpc连接的链
+type nodeChain struct {
+	chainID string
+	ctx     context.CLIContext
+}
+
+var _ ProofRelayChain = (*nodeChain)(nil)
+
+//NewNodeRelayChain 创建连接至nodeURI的RelayChain, tx以commit模式广播
+func NewNodeRelayChain(cdc *go_amino.Codec, chainID, nodeURI string) RelayChain {
+	ctx := context.CLIContext{
+		Codec:     cdc,
+		Client:    rpcclient.NewHTTP(nodeURI, "/websocket"),
+		NodeURI:   nodeURI,
+		ChainID:   chainID,
+		TrustNode: true,
+		Mode:      context.BroadcastBlock,
+	}
+	return &nodeChain{chainID: chainID, ctx: ctx}
+}
+
+func (c *nodeChain) ChainID() string {
+	return c.chainID
+}
+
+func (c *nodeChain) GetOutSequence(toChain string) (int64, error) {
+	return c.querySequence(qcp.BuildOutSequenceKey(toChain))
+}
+
+func (c *nodeChain) GetInSequence(fromChain string) (int64, error) {
+	return c.querySequence(qcp.BuildInSequenceKey(fromChain))
+}
+
+func (c *nodeChain) querySequence(key []byte) (int64, error) {
+	bz, err := query(c.ctx, key)
+	if err != nil || len(bz) == 0 {
+		return 0, err
+	}
+
+	var seq int64
+	err = c.ctx.Codec.UnmarshalBinaryBare(bz, &seq)
+	return seq, err
+}
+
+func (c *nodeChain) GetOutTxs(toChain string, fromSeq int64, limit int) ([]*txs.TxQcp, error) {
+	return GetOutChainTxs(c.ctx, toChain, fromSeq, limit)
+}
+
+func (c *nodeChain) BroadcastTxQcp(txQcp *txs.TxQcp) error {
+	res, err := c.ctx.BroadcastTx(c.ctx.Codec.MustMarshalBinaryBare(txQcp))
+	if err != nil {
+		return err
+	}
+	if res.CheckTx.Code != 0 {
+		return fmt.Errorf("check tx error. code: %d, log: %s", res.CheckTx.Code, res.CheckTx.Log)
+	}
+	if res.DeliverTx.Code != 0 {
+		return fmt.Errorf("deliver tx error. code: %d, log: %s", res.DeliverTx.Code, res.DeliverTx.Log)
+	}
+	return nil
+}
+
+//GetOutTxProof 查询最新区块的上一高度下的存在性证明, 最新区块头的AppHash即为该高度commit后的状态
+func (c *nodeChain) GetOutTxProof(toChain string, sequence int64) (*txs.TxQcpProof, error) {
+	status, err := c.ctx.Client.Status()
+	if err != nil {
+		return nil, err
+	}
+	height := status.SyncInfo.LatestBlockHeight
+	if height <= 1 {
+		return nil, fmt.Errorf("no provable height, latest height: %d", height)
+	}
+
+	key := qcp.BuildOutSequenceTxKey(toChain, sequence)
+	res, err := c.ctx.Client.ABCIQueryWithOptions(string(qcp.BuildQcpStoreQueryPath()), key,
+		rpcclient.ABCIQueryOptions{Height: height - 1, Prove: true})
+	if err != nil {
+		return nil, err
+	}
+	if !res.Response.IsOK() {
+		return nil, fmt.Errorf("query proof error. code: %d, log: %s", res.Response.Code, res.Response.Log)
+	}
+	if len(res.Response.Value) == 0 || res.Response.Proof == nil {
+		return nil, fmt.Errorf("tx not found at height %d, retry later", height-1)
+	}
+
+	commit, err := c.ctx.Client.Commit(&height)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := c.ctx.Client.Validators(&height)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txs.TxQcpProof{
+		Header:     commit.SignedHeader,
+		Validators: tmtypes.NewValidatorSet(validators.Validators),
+		Value:      res.Response.Value,
+		Proof:      res.Response.Proof,
+	}, nil
+}
@@ -0,0 +1,190 @@
+package qcp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/QOSGroup/qbase/baseabci"
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/qcp"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	go_amino "github.com/tendermint/go-amino"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+//crossTx 执行时向To链发起跨链交易
+type crossTx struct {
+	To string `json:"to"`
+}
+
+var _ txs.ITx = (*crossTx)(nil)
+
+func (tx *crossTx) ValidateData(ctx context.Context) error { return nil }
+func (tx *crossTx) Exec(ctx context.Context) (types.Result, *txs.TxQcp) {
+	if tx.To == "" {
+		return types.Result{}, nil
+	}
+	return types.Result{}, &txs.TxQcp{
+		TxStd: txs.NewTxStd(&crossTx{}, tx.To, types.NewInt(100000)),
+		To:    tx.To,
+	}
+}
+func (tx *crossTx) GetSigner() []types.AccAddress { return nil }
+func (tx *crossTx) CalcGas() types.BigInt         { return types.ZeroInt() }
+func (tx *crossTx) GetGasPayer() types.AccAddress { return nil }
+func (tx *crossTx) GetSignData() []byte           { return []byte(tx.To) }
+
+func registerCodec(cdc *go_amino.Codec) {
+	cdc.RegisterConcrete(&crossTx{}, "qbase/test/crossTx", nil)
+}
+
+//appChain 进程内的链
+type appChain struct {
+	app *baseabci.BaseApp
+	cdc *go_amino.Codec
+}
+
+var _ RelayChain = (*appChain)(nil)
+
+func newAppChain(t *testing.T, chainID string, relayPubKey crypto.PubKey, otherChainID string) *appChain {
+	app := baseabci.NewBaseApp(chainID, nil, log.NewNopLogger(), dbm.NewMemDB(), registerCodec)
+	app.RegisterTxQcpSigner(ed25519.GenPrivKey())
+	app.RegisterTxQcpResultHandler(func(ctx context.Context, txQcpResult interface{}) {})
+	require.Nil(t, app.LoadLatestVersion())
+
+	appState, err := app.GetCdc().MarshalJSON(types.GenesisState{
+		QCPs: []*types.QCPConfig{{Name: otherChainID, ChainId: otherChainID, PubKey: relayPubKey}},
+	})
+	require.Nil(t, err)
+
+	app.InitChain(abci.RequestInitChain{ChainId: chainID, AppStateBytes: appState})
+	app.Commit()
+
+	return &appChain{app: app, cdc: app.GetCdc()}
+}
+
+func (c *appChain) ChainID() string {
+	return c.app.Name()
+}
+
+func (c *appChain) GetOutSequence(toChain string) (int64, error) {
+	return c.querySequence(qcp.BuildOutSequenceKey(toChain))
+}
+
+func (c *appChain) GetInSequence(fromChain string) (int64, error) {
+	return c.querySequence(qcp.BuildInSequenceKey(fromChain))
+}
+
+func (c *appChain) querySequence(key []byte) (int64, error) {
+	res := c.app.Query(abci.RequestQuery{Path: string(qcp.BuildQcpStoreQueryPath()), Data: key})
+	var seq int64
+	if len(res.Value) == 0 {
+		return 0, nil
+	}
+	err := c.cdc.UnmarshalBinaryBare(res.Value, &seq)
+	return seq, err
+}
+
+func (c *appChain) GetOutTxs(toChain string, fromSeq int64, limit int) ([]*txs.TxQcp, error) {
+	res := c.app.Query(abci.RequestQuery{Path: fmt.Sprintf("/app/qcp/out-txs/%s/%d/%d", toChain, fromSeq, limit)})
+	var txQcps []*txs.TxQcp
+	err := c.cdc.UnmarshalBinaryBare(res.Value, &txQcps)
+	return txQcps, err
+}
+
+func (c *appChain) BroadcastTxQcp(txQcp *txs.TxQcp) error {
+	return c.deliver(txQcp)
+}
+
+func (c *appChain) deliver(tx types.Tx) error {
+	bz := c.cdc.MustMarshalBinaryBare(tx)
+	if res := c.app.CheckTx(abci.RequestCheckTx{Tx: bz}); res.Code != 0 {
+		return fmt.Errorf("check tx error: %s", res.Log)
+	}
+
+	height := c.app.LastBlockHeight() + 1
+	c.app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: c.ChainID(), Height: height}})
+	res := c.app.DeliverTx(abci.RequestDeliverTx{Tx: bz})
+	c.app.EndBlock(abci.RequestEndBlock{Height: height})
+	c.app.Commit()
+
+	if res.Code != 0 {
+		return fmt.Errorf("deliver tx error: %s", res.Log)
+	}
+	return nil
+}
+
+func TestRelayer(t *testing.T) {
+	relayKey := ed25519.GenPrivKey()
+	signer := func(data []byte) ([]byte, crypto.PubKey, error) {
+		sig, err := relayKey.Sign(data)
+		return sig, relayKey.PubKey(), err
+	}
+
+	chainA := newAppChain(t, "chain-a", relayKey.PubKey(), "chain-b")
+	chainB := newAppChain(t, "chain-b", relayKey.PubKey(), "chain-a")
+
+	dir, err := ioutil.TempDir("", "relay")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	progressFile := filepath.Join(dir, "progress.json")
+
+	relayer, err := NewRelayer(chainA, chainB, signer, progressFile, log.NewNopLogger())
+	require.Nil(t, err)
+	relayer.SetBatchSize(2)
+
+	n, err := relayer.RelayOnce()
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+
+	for i := 0; i < 3; i++ {
+		require.Nil(t, chainA.deliver(txs.NewTxStd(&crossTx{To: "chain-b"}, "chain-a", types.NewInt(100000))))
+	}
+
+	//a->b 每次最多中继2个tx, b执行后生成的result tx中继回a
+	n, err = relayer.RelayOnce()
+	require.Nil(t, err)
+	require.Equal(t, 4, n)
+	seq, _ := chainB.GetInSequence("chain-a")
+	require.Equal(t, int64(2), seq)
+	seq, _ = chainA.GetInSequence("chain-b")
+	require.Equal(t, int64(2), seq)
+
+	n, err = relayer.RelayOnce()
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+	seq, _ = chainB.GetInSequence("chain-a")
+	require.Equal(t, int64(3), seq)
+	seq, _ = chainA.GetInSequence("chain-b")
+	require.Equal(t, int64(3), seq)
+
+	n, err = relayer.RelayOnce()
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+
+	//重启后从进度文件加载
+	relayer, err = NewRelayer(chainA, chainB, signer, progressFile, log.NewNopLogger())
+	require.Nil(t, err)
+	require.Equal(t, int64(3), relayer.Progress("chain-a", "chain-b"))
+	require.Equal(t, int64(3), relayer.Progress("chain-b", "chain-a"))
+
+	//目标链不信任的签名者
+	require.Nil(t, chainA.deliver(txs.NewTxStd(&crossTx{To: "chain-b"}, "chain-a", types.NewInt(100000))))
+	otherKey := ed25519.GenPrivKey()
+	relayer, err = NewRelayer(chainA, chainB, func(data []byte) ([]byte, crypto.PubKey, error) {
+		sig, err := otherKey.Sign(data)
+		return sig, otherKey.PubKey(), err
+	}, "", log.NewNopLogger())
+	require.Nil(t, err)
+	_, err = relayer.RelayOnce()
+	require.NotNil(t, err)
+}
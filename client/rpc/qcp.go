@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QOSGroup/qbase/client/context"
+	"github.com/QOSGroup/qbase/client/qcp"
+	"github.com/gorilla/mux"
+)
+
+func registerQcpRoutes(ctx context.CLIContext, m *mux.Router) {
+	m.HandleFunc("/qcp/{chainId}/out-txs", queryQcpOutTxsHandleFunc(ctx)).Methods("GET")
+	m.HandleFunc("/qcp/{chainId}/ack", queryQcpOutAckHandleFunc(ctx)).Methods("GET")
+}
+
+//queryQcpOutTxsHandleFunc 按序号返回输出到chainId的qcp tx. 参数: from 起始序号, 默认1; limit 最大数量, 默认100
+func queryQcpOutTxsHandleFunc(cliContext context.CLIContext) func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		vars := mux.Vars(request)
+		chainId := vars["chainId"]
+
+		fromSeq := int64(1)
+		if fromStr := request.FormValue("from"); fromStr != "" {
+			var err error
+			if fromSeq, err = strconv.ParseInt(fromStr, 10, 64); err != nil {
+				WriteErrorResponse(writer, http.StatusBadRequest, "invalid from")
+				return
+			}
+		}
+
+		limit := 100
+		if limitStr := request.FormValue("limit"); limitStr != "" {
+			var err error
+			if limit, err = strconv.Atoi(limitStr); err != nil {
+				WriteErrorResponse(writer, http.StatusBadRequest, "invalid limit")
+				return
+			}
+		}
+
+		txQcps, err := qcp.GetOutChainTxs(cliContext, chainId, fromSeq, limit)
+		if err != nil {
+			Write40XErrorResponse(writer, err)
+			return
+		}
+
+		PostProcessResponseBare(writer, cliContext, txQcps)
+	}
+}
+
+//queryQcpOutAckHandleFunc 返回输出到chainId的qcp tx已确认的最大连续序号
+func queryQcpOutAckHandleFunc(cliContext context.CLIContext) func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		vars := mux.Vars(request)
+		chainId := vars["chainId"]
+
+		seq, err := qcp.GetOutChainAckSequence(cliContext, chainId)
+		if err != nil {
+			Write40XErrorResponse(writer, err)
+			return
+		}
+
+		PostProcessResponseBare(writer, cliContext, seq)
+	}
+}
@@ -27,6 +27,9 @@ type BaseRequest struct {
 	Height  int64  `json:"height"`
 	Indent  bool   `json:"indent"`
 	Mode    string `json:"mode"`
+
+	Memo          string `json:"memo"`
+	TimeoutHeight int64  `json:"timeout_height"`
 }
 
 type TxGenerateResponse struct {
@@ -56,7 +59,10 @@ func NewBaseRequest(from, chainId string, nonce, maxGas, height int64, indent bo
 }
 
 func (br BaseRequest) Sanitize() BaseRequest {
-	return NewBaseRequest(br.From, br.ChainId, br.Nonce, br.MaxGas, br.Height, br.Indent, br.Mode)
+	sbr := NewBaseRequest(br.From, br.ChainId, br.Nonce, br.MaxGas, br.Height, br.Indent, br.Mode)
+	sbr.Memo = strings.TrimSpace(br.Memo)
+	sbr.TimeoutHeight = br.TimeoutHeight
+	return sbr
 }
 
 func (br BaseRequest) ValidateBasic() error {
@@ -81,6 +87,10 @@ func (br BaseRequest) ValidateBasic() error {
 		return errors.New("height is less than zero")
 	}
 
+	if br.TimeoutHeight < int64(0) {
+		return errors.New("timeout height is less than zero")
+	}
+
 	return nil
 }
 
@@ -276,6 +286,8 @@ func WriteGenStdTxResponse(writer http.ResponseWriter, cliCtx context.CLIContext
 	}
 
 	stdTx := txs.NewTxStd(tx, cliCtx.ChainID, types.NewInt(maxGas))
+	stdTx.Memo = req.Memo
+	stdTx.TimeoutHeight = req.TimeoutHeight
 	stdTx.Signature = []txs.Signature{
 		txs.Signature{
 			Pubkey:    pubkey,
@@ -74,6 +74,7 @@ func ServerCommand(cdc *amino.Codec, registerRoutesFn func(*RestServer)) *cobra.
 			registerTxRoutes(rs.CliCtx, rs.Mux)
 			registerTxsRoutes(rs.CliCtx, rs.Mux)
 			registerQueryRoutes(rs.CliCtx, rs.Mux)
+			registerQcpRoutes(rs.CliCtx, rs.Mux)
 			registerTendermintRoutes(rs.CliCtx, rs.Mux)
 
 			registerRoutesFn(rs)
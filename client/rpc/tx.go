@@ -2,7 +2,6 @@ package rpc
 
 import (
 	"encoding/hex"
-	"fmt"
 	"github.com/QOSGroup/qbase/client/context"
 	"github.com/QOSGroup/qbase/types"
 	"github.com/gorilla/mux"
@@ -20,6 +19,7 @@ type TxResponse struct {
 	Code      uint32   `json:"code"`
 	Data      string   `json:"data,omitempty"`
 	RawLog    string   `json:"raw_log,omitempty"`
+	Logs      txLogs   `json:"logs,omitempty"`
 	Info      string   `json:"info,omitempty"`
 	GasWanted int64    `json:"gas_wanted,omitempty"`
 	GasUsed   int64    `json:"gas_used,omitempty"`
@@ -39,12 +39,23 @@ type events struct {
 	Attributes []event `json:"attributes"`
 }
 
+//txLog TxStd中每个ITx的执行日志及事件
+type txLog struct {
+	MsgIndex uint16   `json:"msg_index"`
+	Success  bool     `json:"success"`
+	Log      string   `json:"log"`
+	Events   []events `json:"events"`
+}
+
+type txLogs []txLog
+
 func NewResponseResultTx(res *ctypes.ResultTx, tx types.Tx, timestamp string) TxResponse {
 	if res == nil {
 		return TxResponse{}
 	}
 
 	es, _ := ParseEvents(res.TxResult.Events)
+	logs, _ := ParseLogs(res.TxResult.Log)
 
 	return TxResponse{
 		TxHash:    res.Hash.String(),
@@ -52,6 +63,7 @@ func NewResponseResultTx(res *ctypes.ResultTx, tx types.Tx, timestamp string) Tx
 		Code:      res.TxResult.Code,
 		Data:      strings.ToUpper(hex.EncodeToString(res.TxResult.Data)),
 		RawLog:    res.TxResult.Log,
+		Logs:      logs,
 		Info:      res.TxResult.Info,
 		GasWanted: res.TxResult.GasWanted,
 		GasUsed:   res.TxResult.GasUsed,
@@ -68,8 +80,6 @@ func ParseEvents(rte []atypes.Event) ([]events, error) {
 		e.Type = ev.Type
 
 		for _, av := range ev.Attributes {
-			fmt.Println(string(av.Key))
-			fmt.Println(string(av.Value))
 			e.Attributes = append(e.Attributes, event{string(av.Key), string(av.Value)})
 		}
 
@@ -79,6 +89,29 @@ func ParseEvents(rte []atypes.Event) ([]events, error) {
 	return es, nil
 }
 
+//ParseLogs 解析result.Log中按ITx记录的执行日志及事件. log不是ITx日志时(如签名校验失败)返回error
+func ParseLogs(log string) (txLogs, error) {
+	abciLogs, err := types.ParseABCILogs(log)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(txLogs, 0, len(abciLogs))
+	for _, l := range abciLogs {
+		es := make([]events, 0, len(l.Events))
+		for _, ev := range l.Events {
+			e := events{Type: ev.Type}
+			for _, attr := range ev.Attributes {
+				e.Attributes = append(e.Attributes, event{attr.Key, attr.Value})
+			}
+			es = append(es, e)
+		}
+		logs = append(logs, txLog{MsgIndex: l.MsgIndex, Success: l.Success, Log: l.Log, Events: es})
+	}
+
+	return logs, nil
+}
+
 func registerTxRoutes(ctx context.CLIContext, m *mux.Router) {
 	m.HandleFunc("/txs/{hash}", queryTxHashHandleFn(ctx)).Methods("GET")
 }
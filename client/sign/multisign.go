@@ -0,0 +1,115 @@
+package sign
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QOSGroup/qbase/client/context"
+	"github.com/QOSGroup/qbase/client/keys"
+	"github.com/QOSGroup/qbase/client/types"
+	qkeys "github.com/QOSGroup/qbase/keys"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/spf13/cobra"
+	"github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto/multisig"
+)
+
+const (
+	flagMultisig = "multisig"
+)
+
+func MultiSignCommand(cdc *amino.Codec) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "multisign [file] [name] [[signature]...]",
+		Short: "Generate multisig signatures for transactions generated offline",
+		Long: strings.TrimSpace(`
+Combine the signatures of multisig account members into a multisig signature,
+and print the tx with the multisig signature.
+
+Each signature file is generated by the member with:
+$ basecli sign [file] --signer [member] --multisig [name]
+
+example:
+$ basecli multisign tx.json treasury alice.json bob.json
+`),
+		RunE: makeMultiSignCmd(cdc),
+		Args: cobra.MinimumNArgs(3),
+	}
+
+	return types.GetCommands(cmd)[0]
+}
+
+func makeMultiSignCmd(cdc *amino.Codec) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := context.NewCLIContext().WithCodec(cdc)
+
+		implTx, err := readTxStd(cdc, args[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := keys.GetKeyInfo(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if info.GetType() != qkeys.TypeMulti {
+			return fmt.Errorf("%s is not a multisig key", args[1])
+		}
+
+		allSigners := implTx.GetSigners()
+		index, err := searchAddress(allSigners, info.GetAddress())
+		if err != nil {
+			return err
+		}
+
+		signature, err := combineSignatures(cdc, implTx, info.GetPubKey().(multisig.PubKeyMultisigThreshold), args[2:])
+		if err != nil {
+			return err
+		}
+
+		siges := implTx.Signature
+		if len(siges) == 0 {
+			siges = make([]txs.Signature, len(allSigners))
+		}
+		siges[index] = signature
+
+		implTx.Signature = siges
+		ctx.PrintResult(implTx)
+
+		return nil
+	}
+}
+
+//combineSignatures 校验各成员签名并合并为多签签名. 成员签名需使用相同的nonce
+func combineSignatures(cdc *amino.Codec, tx *txs.TxStd, multiPub multisig.PubKeyMultisigThreshold, sigFiles []string) (txs.Signature, error) {
+	multiSig := multisig.NewMultisig(len(multiPub.PubKeys))
+
+	var nonce int64
+	for i, sigFile := range sigFiles {
+		sig, err := readSignature(cdc, sigFile)
+		if err != nil {
+			return txs.Signature{}, err
+		}
+
+		if i == 0 {
+			nonce = sig.Nonce
+		} else if sig.Nonce != nonce {
+			return txs.Signature{}, fmt.Errorf("signature file: %s nonce not match. expect: %d, actual: %d", sigFile, nonce, sig.Nonce)
+		}
+
+		if sig.Pubkey == nil || !sig.Pubkey.VerifyBytes(tx.BuildSignatureBytes(nonce, tx.ChainID), sig.Signature) {
+			return txs.Signature{}, fmt.Errorf("signature file: %s verification failed", sigFile)
+		}
+
+		if err := multiSig.AddSignatureFromPubKey(sig.Signature, sig.Pubkey, multiPub.PubKeys); err != nil {
+			return txs.Signature{}, fmt.Errorf("signature file: %s error. err: %s", sigFile, err.Error())
+		}
+	}
+
+	return txs.Signature{
+		Pubkey:    multiPub,
+		Signature: cdc.MustMarshalBinaryBare(multiSig),
+		Nonce:     nonce,
+	}, nil
+}
@@ -13,13 +13,23 @@ import (
 	"github.com/spf13/viper"
 	"github.com/tendermint/go-amino"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	flagOutputDir = "output-dir"
 )
 
 func SignCommand(cdc *amino.Codec) *cobra.Command {
 
 	cmd := &cobra.Command{
-		Use:    "sign [file]",
-		Short:  "Sign transactions generated offline",
+		Use:   "sign [file]",
+		Short: "Sign transactions generated offline",
+		Long: `Sign transactions generated offline.
+If [file] is a directory, all *.json txs in it are signed in file name order.
+The nonce used for each tx is increased by one, so the txs must be broadcast in the same order.`,
 		PreRun: checkSignCmd,
 		RunE:   makeSignCmd(cdc),
 		Args:   cobra.ExactArgs(1),
@@ -30,6 +40,8 @@ func SignCommand(cdc *amino.Codec) *cobra.Command {
 	cmd.Flags().Bool(types.FlagSigOnly, false, "Print only the generated signature, then exit")
 	cmd.Flags().Bool(types.FlagOffline, false, "Offline mode; Do not query a full node. --nonce must be set if offline")
 	cmd.Flags().String(types.FlagSigner, "", "Signer address or keybase name")
+	cmd.Flags().String(flagMultisig, "", "Address or keybase name of the multisig account on behalf of which the tx is signed. Implies --sig-only")
+	cmd.Flags().String(flagOutputDir, "", "Write the result of each tx to the directory with the same file name, instead of stdout")
 	cmd.MarkFlagRequired(types.FlagSigner)
 
 	return cmd
@@ -41,12 +53,11 @@ func makeSignCmd(cdc *amino.Codec) func(cmd *cobra.Command, args []string) error
 		isOffline := viper.GetBool(types.FlagOffline)
 		nonce := viper.GetInt64(types.FlagNonce)
 		isSignOnly := viper.GetBool(types.FlagSigOnly)
+		outputDir := viper.GetString(flagOutputDir)
 
-		txFile := args[0]
-
-		bz, err := ioutil.ReadFile(txFile)
+		txFiles, err := listTxFiles(args[0])
 		if err != nil {
-			return fmt.Errorf("read sign file: %s error. err: %s", txFile, err.Error())
+			return err
 		}
 
 		signerAddress, err := account.GetAddrFromFlag(ctx, types.FlagSigner)
@@ -54,51 +65,68 @@ func makeSignCmd(cdc *amino.Codec) func(cmd *cobra.Command, args []string) error
 			return err
 		}
 
-		var tx qtypes.Tx
-		if err := cdc.UnmarshalJSON(bz, &tx); err != nil {
-			return errors.New("unmarshalJSON sign file error")
+		//多签账户: signer为多签成员, 使用多签账户的nonce, 仅输出成员签名, 由multisign命令合并
+		accountAddress := signerAddress
+		if viper.GetString(flagMultisig) != "" {
+			if accountAddress, err = account.GetAddrFromFlag(ctx, flagMultisig); err != nil {
+				return err
+			}
+			isSignOnly = true
 		}
 
-		implTx, ok := tx.(*txs.TxStd)
-		if !ok {
-			return errors.New("not support tx type.")
+		if !isOffline {
+			if nonce, err = account.GetAccountNonce(ctx, accountAddress); err != nil {
+				return err
+			}
 		}
 
-		allSigners := implTx.GetSigners()
-		index, err := searchAddress(allSigners, signerAddress)
+		signer, err := clientTx.NewAddressSigner(ctx, signerAddress)
 		if err != nil {
 			return err
 		}
 
-		if !isOffline {
-			if nonce, err = account.GetAccountNonce(ctx, signerAddress); err != nil {
+		for _, txFile := range txFiles {
+			implTx, err := readTxStd(cdc, txFile)
+			if err != nil {
 				return err
 			}
-		}
 
-		actualNonce := nonce + 1
-		data := implTx.BuildSignatureBytes(actualNonce, implTx.ChainID)
-		sig, pubKey := clientTx.SignDataFromAddress(ctx, signerAddress, data)
+			allSigners := implTx.GetSigners()
+			index, err := searchAddress(allSigners, accountAddress)
+			if err != nil {
+				return fmt.Errorf("%s: %s", txFile, err.Error())
+			}
 
-		signature := txs.Signature{
-			Pubkey:    pubKey,
-			Signature: sig,
-			Nonce:     actualNonce,
-		}
+			//批量签名时依次增加nonce
+			nonce++
+			data := implTx.BuildSignatureBytes(nonce, implTx.ChainID)
+			sig, pubKey, err := signer(data)
+			if err != nil {
+				return fmt.Errorf("%s: sign error. err: %s", txFile, err.Error())
+			}
 
-		if isSignOnly {
-			ctx.PrintResult(signature)
-			return nil
-		}
+			signature := txs.Signature{
+				Pubkey:    pubKey,
+				Signature: sig,
+				Nonce:     nonce,
+			}
 
-		siges := implTx.Signature
-		if len(siges) == 0 {
-			siges = make([]txs.Signature, len(allSigners))
-		}
-		siges[index] = signature
+			var result interface{} = signature
+			if !isSignOnly {
+				siges := implTx.Signature
+				if len(siges) == 0 {
+					siges = make([]txs.Signature, len(allSigners))
+				}
+				siges[index] = signature
 
-		implTx.Signature = siges
-		ctx.PrintResult(implTx)
+				implTx.Signature = siges
+				result = implTx
+			}
+
+			if err := printResult(ctx, outputDir, txFile, result); err != nil {
+				return err
+			}
+		}
 
 		return nil
 	}
@@ -110,6 +138,78 @@ func checkSignCmd(cmd *cobra.Command, _ []string) {
 	}
 }
 
+//listTxFiles path为目录时按文件名顺序返回其中的*.json文件
+func listTxFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tx file found in %s", path)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func readTxStd(cdc *amino.Codec, txFile string) (*txs.TxStd, error) {
+	bz, err := ioutil.ReadFile(txFile)
+	if err != nil {
+		return nil, fmt.Errorf("read sign file: %s error. err: %s", txFile, err.Error())
+	}
+
+	var tx qtypes.Tx
+	if err := cdc.UnmarshalJSON(bz, &tx); err != nil {
+		return nil, errors.New("unmarshalJSON sign file error")
+	}
+
+	implTx, ok := tx.(*txs.TxStd)
+	if !ok {
+		return nil, errors.New("not support tx type.")
+	}
+
+	return implTx, nil
+}
+
+func readSignature(cdc *amino.Codec, sigFile string) (sig txs.Signature, err error) {
+	bz, err := ioutil.ReadFile(sigFile)
+	if err != nil {
+		return sig, fmt.Errorf("read signature file: %s error. err: %s", sigFile, err.Error())
+	}
+
+	if err := cdc.UnmarshalJSON(bz, &sig); err != nil {
+		return sig, fmt.Errorf("unmarshalJSON signature file: %s error. err: %s", sigFile, err.Error())
+	}
+
+	return sig, nil
+}
+
+//printResult outputDir不为空时将结果写入outputDir下与txFile同名的文件, 否则输出结果
+func printResult(ctx context.CLIContext, outputDir, txFile string, result interface{}) error {
+	if outputDir == "" {
+		return ctx.PrintResult(result)
+	}
+
+	bz, err := ctx.JSONResult(result)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(outputDir, filepath.Base(txFile)), bz, 0644)
+}
+
 func searchAddress(addresses []qtypes.AccAddress, searchAddress qtypes.AccAddress) (index int, err error) {
 	if len(addresses) == 0 {
 		return -1, errors.New("search addresses list is empty.")
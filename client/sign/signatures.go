@@ -0,0 +1,204 @@
+package sign
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/QOSGroup/qbase/client/account"
+	"github.com/QOSGroup/qbase/client/context"
+	"github.com/QOSGroup/qbase/client/types"
+	"github.com/QOSGroup/qbase/txs"
+	qtypes "github.com/QOSGroup/qbase/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/go-amino"
+)
+
+const (
+	sigStatusOK       = "ok"
+	sigStatusMissing  = "missing"
+	sigStatusMismatch = "pubkey mismatch"
+	sigStatusInvalid  = "invalid signature"
+	sigStatusNonce    = "nonce mismatch"
+)
+
+func MergeSignaturesCommand(cdc *amino.Codec) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "merge-signatures [file] [[signature]...]",
+		Short: "Merge signature-only files into the tx generated offline",
+		Long: strings.TrimSpace(`
+Merge signatures generated by 'sign --sig-only' into the tx, and print the tx.
+Each signature is placed at the position of the signer matching its pubkey.
+
+example:
+$ basecli merge-signatures tx.json alice.json bob.json
+`),
+		RunE: makeMergeSignaturesCmd(cdc),
+		Args: cobra.MinimumNArgs(2),
+	}
+
+	return types.GetCommands(cmd)[0]
+}
+
+func makeMergeSignaturesCmd(cdc *amino.Codec) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := context.NewCLIContext().WithCodec(cdc)
+
+		implTx, err := readTxStd(cdc, args[0])
+		if err != nil {
+			return err
+		}
+
+		sigs := make([]txs.Signature, 0, len(args)-1)
+		for _, sigFile := range args[1:] {
+			sig, err := readSignature(cdc, sigFile)
+			if err != nil {
+				return err
+			}
+			sigs = append(sigs, sig)
+		}
+
+		if err := mergeSignatures(implTx, sigs); err != nil {
+			return err
+		}
+
+		return ctx.PrintResult(implTx)
+	}
+}
+
+//mergeSignatures 按签名公钥地址将签名放入tx中对应签名者的位置
+func mergeSignatures(tx *txs.TxStd, sigs []txs.Signature) error {
+	allSigners := tx.GetSigners()
+	siges := tx.Signature
+	if len(siges) == 0 {
+		siges = make([]txs.Signature, len(allSigners))
+	}
+	if len(siges) != len(allSigners) {
+		return fmt.Errorf("signatures and signers not match. signatures count: %d , signers count: %d ", len(siges), len(allSigners))
+	}
+
+	for _, sig := range sigs {
+		if sig.Pubkey == nil {
+			return errors.New("pubkey of signature is empty")
+		}
+
+		index, err := searchAddress(allSigners, qtypes.AccAddress(sig.Pubkey.Address()))
+		if err != nil {
+			return err
+		}
+
+		if !sig.Pubkey.VerifyBytes(tx.BuildSignatureBytes(sig.Nonce, tx.ChainID), sig.Signature) {
+			return fmt.Errorf("signature of %s verification failed", allSigners[index])
+		}
+
+		siges[index] = sig
+	}
+
+	tx.Signature = siges
+	return nil
+}
+
+func ValidateSignaturesCommand(cdc *amino.Codec) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "validate-signatures [file]",
+		Short: "Validate the signatures of the tx generated offline",
+		Long: strings.TrimSpace(`
+Print the signature status of every signer of the tx, and report the signers still missing.
+Nonces are checked against the accounts on chain unless --offline is set.
+
+example:
+$ basecli validate-signatures tx.json
+`),
+		RunE: makeValidateSignaturesCmd(cdc),
+		Args: cobra.ExactArgs(1),
+	}
+
+	cmd = types.GetCommands(cmd)[0]
+	cmd.Flags().Bool(types.FlagOffline, false, "Offline mode; Do not query a full node")
+
+	return cmd
+}
+
+func makeValidateSignaturesCmd(cdc *amino.Codec) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := context.NewCLIContext().WithCodec(cdc)
+
+		implTx, err := readTxStd(cdc, args[0])
+		if err != nil {
+			return err
+		}
+
+		statuses := checkSignatures(implTx)
+
+		if !viper.GetBool(types.FlagOffline) {
+			for i, status := range statuses {
+				if status.Status != sigStatusOK {
+					continue
+				}
+				nonce, err := account.GetAccountNonce(ctx, status.Signer)
+				if err != nil {
+					return err
+				}
+				if status.Nonce != nonce+1 {
+					statuses[i].Status = sigStatusNonce
+				}
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+		fmt.Fprintln(w, "|Signer\tNonce\tStatus\t")
+		fmt.Fprintln(w, "|------\t-----\t------\t")
+		invalid := 0
+		for _, status := range statuses {
+			fmt.Fprintf(w, "|%s\t%d\t%s\t\n", status.Signer, status.Nonce, status.Status)
+			if status.Status != sigStatusOK {
+				invalid++
+			}
+		}
+		w.Flush()
+
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d signatures are missing or invalid", invalid, len(statuses))
+		}
+		return nil
+	}
+}
+
+type signatureStatus struct {
+	Signer qtypes.AccAddress
+	Nonce  int64
+	Status string
+}
+
+//checkSignatures 按签名者顺序校验tx中的签名, 签名需包含公钥
+func checkSignatures(tx *txs.TxStd) []signatureStatus {
+	allSigners := tx.GetSigners()
+	statuses := make([]signatureStatus, len(allSigners))
+
+	for i, signer := range allSigners {
+		statuses[i].Signer = signer
+
+		if i >= len(tx.Signature) || len(tx.Signature[i].Signature) == 0 {
+			statuses[i].Status = sigStatusMissing
+			continue
+		}
+
+		sig := tx.Signature[i]
+		statuses[i].Nonce = sig.Nonce
+		switch {
+		case sig.Pubkey == nil || !signer.Equals(qtypes.AccAddress(sig.Pubkey.Address())):
+			statuses[i].Status = sigStatusMismatch
+		case !sig.Pubkey.VerifyBytes(tx.BuildSignatureBytes(sig.Nonce, tx.ChainID), sig.Signature):
+			statuses[i].Status = sigStatusInvalid
+		default:
+			statuses[i].Status = sigStatusOK
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,77 @@
+package sign
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	qtypes "github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+type testSignersTx struct {
+	Signers []qtypes.AccAddress
+}
+
+var _ txs.ITx = (*testSignersTx)(nil)
+
+func (tx *testSignersTx) ValidateData(ctx context.Context) error { return nil }
+func (tx *testSignersTx) Exec(ctx context.Context) (qtypes.Result, *txs.TxQcp) {
+	return qtypes.Result{}, nil
+}
+func (tx *testSignersTx) GetSigner() []qtypes.AccAddress { return tx.Signers }
+func (tx *testSignersTx) CalcGas() qtypes.BigInt         { return qtypes.ZeroInt() }
+func (tx *testSignersTx) GetGasPayer() qtypes.AccAddress { return tx.Signers[0] }
+func (tx *testSignersTx) GetSignData() []byte            { return nil }
+
+func signTestTx(t *testing.T, tx *txs.TxStd, key crypto.PrivKey, nonce int64) txs.Signature {
+	sig, err := key.Sign(tx.BuildSignatureBytes(nonce, tx.ChainID))
+	require.Nil(t, err)
+	return txs.Signature{Pubkey: key.PubKey(), Signature: sig, Nonce: nonce}
+}
+
+func TestMergeAndCheckSignatures(t *testing.T) {
+	keys := []crypto.PrivKey{ed25519.GenPrivKey(), ed25519.GenPrivKey(), ed25519.GenPrivKey()}
+	signers := make([]qtypes.AccAddress, len(keys))
+	for i, key := range keys {
+		signers[i] = qtypes.AccAddress(key.PubKey().Address())
+	}
+	tx := txs.NewTxStd(&testSignersTx{Signers: signers}, "test", qtypes.NewInt(100))
+
+	statuses := checkSignatures(tx)
+	require.Len(t, statuses, 3)
+	for _, status := range statuses {
+		require.Equal(t, sigStatusMissing, status.Status)
+	}
+
+	//非签名者的签名
+	require.NotNil(t, mergeSignatures(tx, []txs.Signature{signTestTx(t, tx, ed25519.GenPrivKey(), 1)}))
+
+	//签名内容错误
+	badSig := signTestTx(t, tx, keys[0], 1)
+	badSig.Nonce = 2
+	require.NotNil(t, mergeSignatures(tx, []txs.Signature{badSig}))
+
+	//乱序合并
+	require.Nil(t, mergeSignatures(tx, []txs.Signature{signTestTx(t, tx, keys[2], 3), signTestTx(t, tx, keys[0], 1)}))
+	statuses = checkSignatures(tx)
+	require.Equal(t, sigStatusOK, statuses[0].Status)
+	require.Equal(t, int64(1), statuses[0].Nonce)
+	require.Equal(t, sigStatusMissing, statuses[1].Status)
+	require.Equal(t, sigStatusOK, statuses[2].Status)
+
+	//签名公钥与签名者不一致
+	tx.Signature[1] = signTestTx(t, tx, keys[2], 1)
+	require.Equal(t, sigStatusMismatch, checkSignatures(tx)[1].Status)
+
+	tx.Signature[1] = signTestTx(t, tx, keys[1], 1)
+	tx.Signature[1].Nonce = 5
+	require.Equal(t, sigStatusInvalid, checkSignatures(tx)[1].Status)
+
+	require.Nil(t, mergeSignatures(tx, []txs.Signature{signTestTx(t, tx, keys[1], 5)}))
+	for _, status := range checkSignatures(tx) {
+		require.Equal(t, sigStatusOK, status.Status)
+	}
+}
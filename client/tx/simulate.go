@@ -0,0 +1,74 @@
+package tx
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/QOSGroup/qbase/client/context"
+	cliTypes "github.com/QOSGroup/qbase/client/types"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/go-amino"
+)
+
+const (
+	flagSkipSigVerify = "skip-sig"
+)
+
+func SimulateCmd(cdc *amino.Codec) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "simulate [file]",
+		Short: "simulate tx file and estimate gas used",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.NewCLIContext().WithCodec(cdc)
+			txBytes, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("read tx file err. err: %s", err.Error())
+			}
+
+			var tx types.Tx
+			err = cdc.UnmarshalJSON(txBytes, &tx)
+			if err != nil {
+				return fmt.Errorf("tx file UnmarshalJSON err. err: %s", err.Error())
+			}
+
+			result, err := SimulateTx(ctx, tx, viper.GetBool(flagSkipSigVerify))
+			if err != nil {
+				return err
+			}
+
+			return ctx.PrintResult(result)
+		},
+	}
+
+	cmd.Flags().Bool(flagSkipSigVerify, false, "skip signature verification. used for unsigned tx")
+	return cliTypes.GetCommands(cmd)[0]
+}
+
+//SimulateTx 通过`/app/simulate`模拟执行交易,返回执行结果及gas消耗
+func SimulateTx(ctx context.CLIContext, tx types.Tx, skipSigVerify bool) (types.Result, error) {
+	txBytes, err := ctx.Codec.MarshalBinaryBare(tx)
+	if err != nil {
+		return types.Result{}, err
+	}
+
+	path := "/app/simulate"
+	if skipSigVerify {
+		path = path + "/skipsig"
+	}
+
+	bz, err := ctx.Query(path, txBytes)
+	if err != nil {
+		return types.Result{}, err
+	}
+
+	var result types.Result
+	if err = ctx.Codec.UnmarshalBinaryBare(bz, &result); err != nil {
+		return types.Result{}, err
+	}
+
+	return result, nil
+}
@@ -188,6 +188,8 @@ func BuildAndSignStdTx(ctx context.CLIContext, tXs []txs.ITx, fromChainID, toCha
 	qcpMode := viper.GetBool(cflags.FlagQcp)
 
 	txStd := txs.NewTxsStd(toChainID, types.NewInt(ctx.GetMaxGas()), tXs...)
+	txStd.Memo = viper.GetString(cflags.FlagMemo)
+	txStd.TimeoutHeight = viper.GetInt64(cflags.FlagTimeoutHeight)
 	if isGenOnly && !qcpMode {
 		ctx.PrintResult(txStd)
 		return nil, nil
@@ -275,17 +277,39 @@ func signData(ctx context.CLIContext, name string, data []byte) ([]byte, crypto.
 }
 
 func SignDataFromAddress(ctx context.CLIContext, addr types.AccAddress, data []byte) ([]byte, crypto.PubKey) {
+	signer, err := NewAddressSigner(ctx, addr)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	sig, pubkey, err := signer(data)
+	if err != nil {
+		panic(fmt.Errorf("Sign data error.err:%s", err.Error()))
+	}
+
+	return sig, pubkey
+}
+
+//NewAddressSigner 返回使用addr对应key签名的函数. 密码仅读取一次, 用于批量签名
+func NewAddressSigner(ctx context.CLIContext, addr types.AccAddress) (func(data []byte) ([]byte, crypto.PubKey, error), error) {
 	keybase, err := keys.GetKeyBase(ctx)
 	if err != nil {
-		panic(fmt.Errorf("Open keybase error.err:%s", err.Error()))
+		return nil, err
 	}
 
 	info, err := keybase.GetByAddress(addr)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+
+	pass, err := keys.GetPassphrase(ctx, info.GetName())
+	if err != nil {
+		return nil, err
 	}
 
-	return signData(ctx, info.GetName(), data)
+	return func(data []byte) ([]byte, crypto.PubKey, error) {
+		return keybase.Sign(info.GetName(), pass, data)
+	}, nil
 }
 
 func getSigners(ctx context.CLIContext, txSignerAddrs []types.AccAddress) []string {
@@ -15,6 +15,9 @@ const (
 	FlagJSONIndet = "indent"
 	FlagNonceNode = "nonce-node"
 
+	FlagMemo          = "memo"
+	FlagTimeoutHeight = "timeout-height"
+
 	//qcp flag
 	FlagQcp            = "qcp" //启用QCP模式,发送txQcp消息
 	FlagQcpSigner      = "qcp-signer"
@@ -81,6 +84,8 @@ func PostCustomMaxGasCommands(cmds []*cobra.Command, defaultGases []int64) []*co
 
 		c.Flags().Int64(FlagNonce, 0, "account nonce to sign the tx")
 		c.Flags().Int64(FlagMaxGas, defaultGas, "gas limit to set per tx")
+		c.Flags().String(FlagMemo, "", "memo to send along with the tx")
+		c.Flags().Int64(FlagTimeoutHeight, 0, "block height after which the tx is no longer valid. 0 for no timeout")
 		c.Flags().String(FlagChainID, "", "Chain ID of tendermint node")
 		c.Flags().String(FlagNode, "tcp://localhost:26657", "<host>:<port> to tendermint rpc interface for this chain")
 		c.Flags().Bool(FlagTrustNode, false, "Trust connected full node (don't verify proofs for responses)")
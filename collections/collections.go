@@ -0,0 +1,102 @@
+//Package collections 基于store.KVStore的类型化集合.
+//
+//集合只保存前缀及key/value编码方式, 不持有store, 一般声明为mapper的包级变量, 使用时传入mapper的store:
+//
+//	var balances = collections.NewMap([]byte("balance/"), collections.AccAddressKey, collections.Int64Value)
+//
+//	func (m *BankMapper) GetBalance(addr types.AccAddress) (int64, error) {
+//		return balances.Get(m.GetStore(), addr)
+//	}
+//
+//context中mapper的store已计量gas, 集合的读写同样消耗gas. 同一store中各集合的前缀不能互为前缀
+package collections
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/store/prefix"
+)
+
+//ErrNotFound key不存在
+var ErrNotFound = errors.New("collections: not found")
+
+//ErrEncoding key或value编解码失败
+var ErrEncoding = errors.New("collections: encoding error")
+
+func prefixStore(parent store.KVStore, p []byte) store.KVStore {
+	return prefix.NewStore(parent, p)
+}
+
+func checkPrefix(p []byte) {
+	if len(p) == 0 {
+		panic("collections: empty prefix")
+	}
+}
+
+func encodingError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrEncoding, fmt.Sprintf(format, args...))
+}
+
+//Ranger 集合的遍历范围, start或end为nil时不限制
+type Ranger[K any] interface {
+	RangeBytes(kc KeyCodec[K]) (start, end []byte, err error)
+}
+
+//Range key在[start, end)中的遍历范围
+type Range[K any] struct {
+	start, end *K
+}
+
+func NewRange[K any]() *Range[K] {
+	return &Range[K]{}
+}
+
+func (r *Range[K]) StartInclusive(start K) *Range[K] {
+	r.start = &start
+	return r
+}
+
+func (r *Range[K]) EndExclusive(end K) *Range[K] {
+	r.end = &end
+	return r
+}
+
+func (r *Range[K]) RangeBytes(kc KeyCodec[K]) (start, end []byte, err error) {
+	if r.start != nil {
+		if start, err = kc.Encode(*r.start); err != nil {
+			return
+		}
+	}
+	if r.end != nil {
+		end, err = kc.Encode(*r.end)
+	}
+	return
+}
+
+//iterate 遍历集合store中ranger范围内的key/value
+func iterate[K any](s store.KVStore, kc KeyCodec[K], ranger Ranger[K], process func(key K, value []byte) (stop bool, err error)) error {
+	var start, end []byte
+	if ranger != nil {
+		var err error
+		if start, end, err = ranger.RangeBytes(kc); err != nil {
+			return err
+		}
+	}
+
+	iter := s.Iterator(start, end)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		key, err := kc.Decode(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		stop, err := process(key, iter.Value())
+		if err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package collections
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	go_amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cryptoAmino "github.com/tendermint/tendermint/crypto/encoding/amino"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/types"
+)
+
+func newStore() store.KVStore {
+	storeKey := types.NewKVStoreKey("base")
+
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(storeKey, types.StoreTypeIAVL, db)
+	cms.LoadLatestVersion()
+
+	return cms.GetKVStore(storeKey)
+}
+
+func TestMap(t *testing.T) {
+	s := newStore()
+	m := NewMap([]byte("m/"), StringKey, Int64Value)
+
+	_, err := m.Get(s, "a")
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	require.Nil(t, m.Set(s, "b", 2))
+	require.Nil(t, m.Set(s, "a", 1))
+	require.Nil(t, m.Set(s, "c", 3))
+
+	v, err := m.Get(s, "a")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), v)
+	has, err := m.Has(s, "c")
+	require.Nil(t, err)
+	require.True(t, has)
+
+	require.Nil(t, m.Remove(s, "c"))
+	has, _ = m.Has(s, "c")
+	require.False(t, has)
+	require.Nil(t, m.Remove(s, "c"))
+
+	var keys []string
+	require.Nil(t, m.Iterate(s, nil, func(key string, value int64) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	require.Equal(t, []string{"a", "b"}, keys)
+
+	//前缀外的数据不受影响
+	require.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 2}, s.Get([]byte("m/b")))
+
+	//value解码失败
+	s.Set([]byte("m/d"), []byte{1})
+	_, err = m.Get(s, "d")
+	require.True(t, errors.Is(err, ErrEncoding))
+	require.True(t, errors.Is(m.Iterate(s, nil, func(string, int64) bool { return false }), ErrEncoding))
+}
+
+func TestMapRange(t *testing.T) {
+	s := newStore()
+	m := NewMap([]byte("m/"), Int64Key, StringValue)
+
+	for _, i := range []int64{3, -1, 0, 7, -300, 256} {
+		require.Nil(t, m.Set(s, i, "v"))
+	}
+
+	collect := func(ranger Ranger[int64]) (keys []int64) {
+		require.Nil(t, m.Iterate(s, ranger, func(key int64, value string) bool {
+			keys = append(keys, key)
+			return false
+		}))
+		return
+	}
+
+	require.Equal(t, []int64{-300, -1, 0, 3, 7, 256}, collect(nil))
+	require.Equal(t, []int64{0, 3, 7}, collect(NewRange[int64]().StartInclusive(0).EndExclusive(256)))
+	require.Equal(t, []int64{-300, -1}, collect(NewRange[int64]().EndExclusive(0)))
+	require.Equal(t, []int64{7, 256}, collect(NewRange[int64]().StartInclusive(4)))
+}
+
+func TestPairKeys(t *testing.T) {
+	s := newStore()
+	addr1, addr2 := types.AccAddress([]byte("addr1")), types.AccAddress([]byte("addr2"))
+	m := NewMap([]byte("m/"), PairKeyCodec(AccAddressKey, StringKey), Int64Value)
+
+	require.Nil(t, m.Set(s, Join(addr2, "a"), 1))
+	require.Nil(t, m.Set(s, Join(addr1, "b"), 2))
+	require.Nil(t, m.Set(s, Join(addr1, "a"), 3))
+
+	v, err := m.Get(s, Join(addr1, "b"))
+	require.Nil(t, err)
+	require.Equal(t, int64(2), v)
+
+	var keys []Pair[types.AccAddress, string]
+	require.Nil(t, m.Iterate(s, PairPrefix[types.AccAddress, string](addr1), func(key Pair[types.AccAddress, string], value int64) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	require.Equal(t, []Pair[types.AccAddress, string]{Join(addr1, "a"), Join(addr1, "b")}, keys)
+
+	keys = nil
+	require.Nil(t, m.Iterate(s, nil, func(key Pair[types.AccAddress, string], value int64) bool {
+		keys = append(keys, key)
+		return len(keys) == 3
+	}))
+	require.Equal(t, Join(addr2, "a"), keys[2])
+
+	sm := NewMap([]byte("s/"), PairKeyCodec(StringKey, Int64Key), Int64Value)
+	require.True(t, errors.Is(sm.Set(s, Join("a\x00", int64(1)), 1), ErrEncoding))
+}
+
+func TestKeyCodecs(t *testing.T) {
+	for _, key := range []int64{0, 1, -1, 1 << 62, -1 << 63} {
+		bz, err := Int64Key.Encode(key)
+		require.Nil(t, err)
+		decoded, err := Int64Key.Decode(bz)
+		require.Nil(t, err)
+		require.Equal(t, key, decoded)
+	}
+	_, err := Int64Key.Decode([]byte{1})
+	require.NotNil(t, err)
+
+	bz, err := BytesKey.EncodeNonTerminal([]byte("abc"))
+	require.Nil(t, err)
+	n, key, err := BytesKey.DecodeNonTerminal(append(bz, 'd'))
+	require.Nil(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, []byte("abc"), key)
+	_, _, err = BytesKey.DecodeNonTerminal([]byte{5, 'a'})
+	require.NotNil(t, err)
+
+	n, str, err := StringKey.DecodeNonTerminal([]byte("ab\x00c"))
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, "ab", str)
+}
+
+func TestKeySet(t *testing.T) {
+	s := newStore()
+	ks := NewKeySet([]byte("k/"), AccAddressKey)
+	addr := types.AccAddress([]byte("addr"))
+
+	has, err := ks.Has(s, addr)
+	require.Nil(t, err)
+	require.False(t, has)
+
+	require.Nil(t, ks.Set(s, addr))
+	has, _ = ks.Has(s, addr)
+	require.True(t, has)
+
+	var keys []types.AccAddress
+	require.Nil(t, ks.Iterate(s, nil, func(key types.AccAddress) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	require.Equal(t, []types.AccAddress{addr}, keys)
+
+	require.Nil(t, ks.Remove(s, addr))
+	has, _ = ks.Has(s, addr)
+	require.False(t, has)
+}
+
+func TestItemAndSequence(t *testing.T) {
+	s := newStore()
+
+	cdc := go_amino.NewCodec()
+	cryptoAmino.RegisterAmino(cdc)
+	item := NewItem([]byte("pubkey"), AminoValue[crypto.PubKey](cdc))
+
+	_, err := item.Get(s)
+	require.True(t, errors.Is(err, ErrNotFound))
+
+	pubkey := ed25519.GenPrivKey().PubKey()
+	require.Nil(t, item.Set(s, pubkey))
+	require.True(t, item.Has(s))
+	v, err := item.Get(s)
+	require.Nil(t, err)
+	require.Equal(t, pubkey, v)
+
+	//与BaseMapper保存的数据相同
+	require.Equal(t, cdc.MustMarshalBinaryBare(pubkey), s.Get([]byte("pubkey")))
+
+	item.Remove(s)
+	require.False(t, item.Has(s))
+
+	seq := NewSequence([]byte("seq"))
+	cur, err := seq.Peek(s)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), cur)
+
+	next, err := seq.Next(s)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), next)
+	next, _ = seq.Next(s)
+	require.Equal(t, int64(2), next)
+
+	require.Nil(t, seq.Set(s, 10))
+	cur, _ = seq.Peek(s)
+	require.Equal(t, int64(10), cur)
+}
+
+func TestEmptyPrefix(t *testing.T) {
+	require.Panics(t, func() { NewMap(nil, StringKey, StringValue) })
+	require.Panics(t, func() { NewSequence([]byte{}) })
+}
@@ -0,0 +1,81 @@
+package collections
+
+import (
+	"fmt"
+
+	"github.com/QOSGroup/qbase/store"
+)
+
+//Item 保存在key下的单个值
+type Item[V any] struct {
+	key []byte
+	vc  ValueCodec[V]
+}
+
+func NewItem[V any](key []byte, vc ValueCodec[V]) Item[V] {
+	checkPrefix(key)
+	return Item[V]{key: key, vc: vc}
+}
+
+func (item Item[V]) GetKey() []byte {
+	return item.key
+}
+
+//Get 未设置时返回ErrNotFound
+func (item Item[V]) Get(s store.KVStore) (value V, err error) {
+	bz := s.Get(item.key)
+	if bz == nil {
+		return value, fmt.Errorf("%w: item %s", ErrNotFound, item.key)
+	}
+	return item.vc.Decode(bz)
+}
+
+func (item Item[V]) Has(s store.KVStore) bool {
+	return s.Has(item.key)
+}
+
+func (item Item[V]) Set(s store.KVStore, value V) error {
+	bz, err := item.vc.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	s.Set(item.key, bz)
+	return nil
+}
+
+func (item Item[V]) Remove(s store.KVStore) {
+	s.Delete(item.key)
+}
+
+//Sequence 自增序号, 未设置时为0
+type Sequence struct {
+	item Item[int64]
+}
+
+func NewSequence(key []byte) Sequence {
+	return Sequence{item: NewItem(key, Int64Value)}
+}
+
+//Peek 返回当前序号
+func (seq Sequence) Peek(s store.KVStore) (int64, error) {
+	if !seq.item.Has(s) {
+		return 0, nil
+	}
+	return seq.item.Get(s)
+}
+
+//Next 序号加1并返回新的序号
+func (seq Sequence) Next(s store.KVStore) (int64, error) {
+	value, err := seq.Peek(s)
+	if err != nil {
+		return 0, err
+	}
+
+	value++
+	return value, seq.item.Set(s, value)
+}
+
+func (seq Sequence) Set(s store.KVStore, value int64) error {
+	return seq.item.Set(s, value)
+}
@@ -0,0 +1,241 @@
+package collections
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/types"
+)
+
+//KeyCodec key编码. 编码结果的字节序即集合的遍历顺序
+//key位于组合key(Pair)中且不是最后一部分时使用NonTerminal编码, 以便解析出该部分的长度
+type KeyCodec[K any] interface {
+	Encode(key K) ([]byte, error)
+	Decode(bz []byte) (K, error)
+	EncodeNonTerminal(key K) ([]byte, error)
+	DecodeNonTerminal(bz []byte) (n int, key K, err error)
+	Stringify(key K) string
+}
+
+var (
+	//StringKey 保存为字符串字节, NonTerminal编码以0x00结尾, 字符串不能包含0x00
+	StringKey KeyCodec[string] = stringKey{}
+	//Int64Key 保存为8字节大端序, 符号位取反, 按数值顺序遍历
+	Int64Key KeyCodec[int64] = int64Key{}
+	//BytesKey 保存为原始字节, NonTerminal编码以1字节长度为前缀
+	BytesKey KeyCodec[[]byte] = bytesKey{}
+	//AccAddressKey 同BytesKey
+	AccAddressKey KeyCodec[types.AccAddress] = accAddressKey{}
+)
+
+type stringKey struct{}
+
+func (stringKey) Encode(key string) ([]byte, error) {
+	return []byte(key), nil
+}
+
+func (stringKey) Decode(bz []byte) (string, error) {
+	return string(bz), nil
+}
+
+func (stringKey) EncodeNonTerminal(key string) ([]byte, error) {
+	if bytes.IndexByte([]byte(key), 0x00) >= 0 {
+		return nil, encodingError("string key %q contains 0x00", key)
+	}
+	return append([]byte(key), 0x00), nil
+}
+
+func (stringKey) DecodeNonTerminal(bz []byte) (int, string, error) {
+	i := bytes.IndexByte(bz, 0x00)
+	if i < 0 {
+		return 0, "", encodingError("string key %X is not terminated", bz)
+	}
+	return i + 1, string(bz[:i]), nil
+}
+
+func (stringKey) Stringify(key string) string {
+	return key
+}
+
+type int64Key struct{}
+
+func (int64Key) Encode(key int64) ([]byte, error) {
+	bz := make([]byte, 8)
+	binary.BigEndian.PutUint64(bz, uint64(key)^(1<<63))
+	return bz, nil
+}
+
+func (kc int64Key) Decode(bz []byte) (int64, error) {
+	if len(bz) != 8 {
+		return 0, encodingError("invalid int64 key length: %d", len(bz))
+	}
+	_, key, err := kc.DecodeNonTerminal(bz)
+	return key, err
+}
+
+func (kc int64Key) EncodeNonTerminal(key int64) ([]byte, error) {
+	return kc.Encode(key)
+}
+
+func (int64Key) DecodeNonTerminal(bz []byte) (int, int64, error) {
+	if len(bz) < 8 {
+		return 0, 0, encodingError("invalid int64 key length: %d", len(bz))
+	}
+	return 8, int64(binary.BigEndian.Uint64(bz) ^ (1 << 63)), nil
+}
+
+func (int64Key) Stringify(key int64) string {
+	return fmt.Sprintf("%d", key)
+}
+
+type bytesKey struct{}
+
+func (bytesKey) Encode(key []byte) ([]byte, error) {
+	return key, nil
+}
+
+func (bytesKey) Decode(bz []byte) ([]byte, error) {
+	return append([]byte(nil), bz...), nil
+}
+
+func (bytesKey) EncodeNonTerminal(key []byte) ([]byte, error) {
+	if len(key) > 255 {
+		return nil, encodingError("bytes key too long: %d", len(key))
+	}
+	return append([]byte{byte(len(key))}, key...), nil
+}
+
+func (bytesKey) DecodeNonTerminal(bz []byte) (int, []byte, error) {
+	if len(bz) == 0 || len(bz) < 1+int(bz[0]) {
+		return 0, nil, encodingError("invalid bytes key: %X", bz)
+	}
+	n := 1 + int(bz[0])
+	return n, append([]byte(nil), bz[1:n]...), nil
+}
+
+func (bytesKey) Stringify(key []byte) string {
+	return fmt.Sprintf("%X", key)
+}
+
+type accAddressKey struct{}
+
+func (accAddressKey) Encode(key types.AccAddress) ([]byte, error) {
+	return BytesKey.Encode(key)
+}
+
+func (accAddressKey) Decode(bz []byte) (types.AccAddress, error) {
+	return BytesKey.Decode(bz)
+}
+
+func (accAddressKey) EncodeNonTerminal(key types.AccAddress) ([]byte, error) {
+	return BytesKey.EncodeNonTerminal(key)
+}
+
+func (accAddressKey) DecodeNonTerminal(bz []byte) (int, types.AccAddress, error) {
+	return BytesKey.DecodeNonTerminal(bz)
+}
+
+func (accAddressKey) Stringify(key types.AccAddress) string {
+	return key.String()
+}
+
+//Pair 由两部分组成的key, 按K1, K2顺序遍历
+type Pair[K1, K2 any] struct {
+	k1 K1
+	k2 K2
+}
+
+func Join[K1, K2 any](k1 K1, k2 K2) Pair[K1, K2] {
+	return Pair[K1, K2]{k1: k1, k2: k2}
+}
+
+func (p Pair[K1, K2]) K1() K1 {
+	return p.k1
+}
+
+func (p Pair[K1, K2]) K2() K2 {
+	return p.k2
+}
+
+//PairKeyCodec Pair的key编码: K1的NonTerminal编码 + K2的编码
+func PairKeyCodec[K1, K2 any](kc1 KeyCodec[K1], kc2 KeyCodec[K2]) KeyCodec[Pair[K1, K2]] {
+	return pairKeyCodec[K1, K2]{kc1: kc1, kc2: kc2}
+}
+
+type pairKeyCodec[K1, K2 any] struct {
+	kc1 KeyCodec[K1]
+	kc2 KeyCodec[K2]
+}
+
+func (kc pairKeyCodec[K1, K2]) Encode(key Pair[K1, K2]) ([]byte, error) {
+	bz1, err := kc.kc1.EncodeNonTerminal(key.k1)
+	if err != nil {
+		return nil, err
+	}
+	bz2, err := kc.kc2.Encode(key.k2)
+	if err != nil {
+		return nil, err
+	}
+	return append(bz1, bz2...), nil
+}
+
+func (kc pairKeyCodec[K1, K2]) Decode(bz []byte) (Pair[K1, K2], error) {
+	n, k1, err := kc.kc1.DecodeNonTerminal(bz)
+	if err != nil {
+		return Pair[K1, K2]{}, err
+	}
+	k2, err := kc.kc2.Decode(bz[n:])
+	if err != nil {
+		return Pair[K1, K2]{}, err
+	}
+	return Join(k1, k2), nil
+}
+
+func (kc pairKeyCodec[K1, K2]) EncodeNonTerminal(key Pair[K1, K2]) ([]byte, error) {
+	bz1, err := kc.kc1.EncodeNonTerminal(key.k1)
+	if err != nil {
+		return nil, err
+	}
+	bz2, err := kc.kc2.EncodeNonTerminal(key.k2)
+	if err != nil {
+		return nil, err
+	}
+	return append(bz1, bz2...), nil
+}
+
+func (kc pairKeyCodec[K1, K2]) DecodeNonTerminal(bz []byte) (int, Pair[K1, K2], error) {
+	n1, k1, err := kc.kc1.DecodeNonTerminal(bz)
+	if err != nil {
+		return 0, Pair[K1, K2]{}, err
+	}
+	n2, k2, err := kc.kc2.DecodeNonTerminal(bz[n1:])
+	if err != nil {
+		return 0, Pair[K1, K2]{}, err
+	}
+	return n1 + n2, Join(k1, k2), nil
+}
+
+func (kc pairKeyCodec[K1, K2]) Stringify(key Pair[K1, K2]) string {
+	return fmt.Sprintf("(%s, %s)", kc.kc1.Stringify(key.k1), kc.kc2.Stringify(key.k2))
+}
+
+//PairPrefix 遍历K1为k1的全部Pair key
+func PairPrefix[K1, K2 any](k1 K1) Ranger[Pair[K1, K2]] {
+	return pairPrefix[K1, K2]{k1: k1}
+}
+
+type pairPrefix[K1, K2 any] struct {
+	k1 K1
+}
+
+func (r pairPrefix[K1, K2]) RangeBytes(kc KeyCodec[Pair[K1, K2]]) (start, end []byte, err error) {
+	pkc, ok := kc.(pairKeyCodec[K1, K2])
+	if !ok {
+		return nil, nil, fmt.Errorf("collections: PairPrefix requires a PairKeyCodec, got %T", kc)
+	}
+	if start, err = pkc.kc1.EncodeNonTerminal(r.k1); err != nil {
+		return
+	}
+	return start, types.PrefixEndBytes(start), nil
+}
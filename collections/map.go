@@ -0,0 +1,118 @@
+package collections
+
+import (
+	"fmt"
+
+	"github.com/QOSGroup/qbase/store"
+)
+
+//Map 保存在prefix下的key/value集合: {prefix}{key} -> value
+type Map[K, V any] struct {
+	prefix []byte
+	kc     KeyCodec[K]
+	vc     ValueCodec[V]
+}
+
+func NewMap[K, V any](prefix []byte, kc KeyCodec[K], vc ValueCodec[V]) Map[K, V] {
+	checkPrefix(prefix)
+	return Map[K, V]{prefix: prefix, kc: kc, vc: vc}
+}
+
+func (m Map[K, V]) GetPrefix() []byte {
+	return m.prefix
+}
+
+func (m Map[K, V]) KeyCodec() KeyCodec[K] {
+	return m.kc
+}
+
+func (m Map[K, V]) ValueCodec() ValueCodec[V] {
+	return m.vc
+}
+
+//Get key不存在时返回ErrNotFound
+func (m Map[K, V]) Get(s store.KVStore, key K) (value V, err error) {
+	bz, err := m.kc.Encode(key)
+	if err != nil {
+		return
+	}
+
+	valueBz := prefixStore(s, m.prefix).Get(bz)
+	if valueBz == nil {
+		return value, fmt.Errorf("%w: key %s", ErrNotFound, m.kc.Stringify(key))
+	}
+	return m.vc.Decode(valueBz)
+}
+
+func (m Map[K, V]) Has(s store.KVStore, key K) (bool, error) {
+	bz, err := m.kc.Encode(key)
+	if err != nil {
+		return false, err
+	}
+	return prefixStore(s, m.prefix).Has(bz), nil
+}
+
+func (m Map[K, V]) Set(s store.KVStore, key K, value V) error {
+	bz, err := m.kc.Encode(key)
+	if err != nil {
+		return err
+	}
+	valueBz, err := m.vc.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	prefixStore(s, m.prefix).Set(bz, valueBz)
+	return nil
+}
+
+//Remove key不存在时不返回错误
+func (m Map[K, V]) Remove(s store.KVStore, key K) error {
+	bz, err := m.kc.Encode(key)
+	if err != nil {
+		return err
+	}
+
+	prefixStore(s, m.prefix).Delete(bz)
+	return nil
+}
+
+//Iterate 按key顺序遍历ranger范围内的key/value, ranger为nil时遍历全部
+//遍历过程中不能修改集合
+func (m Map[K, V]) Iterate(s store.KVStore, ranger Ranger[K], process func(key K, value V) (stop bool)) error {
+	return iterate(prefixStore(s, m.prefix), m.kc, ranger, func(key K, bz []byte) (bool, error) {
+		value, err := m.vc.Decode(bz)
+		if err != nil {
+			return true, err
+		}
+		return process(key, value), nil
+	})
+}
+
+//KeySet 保存在prefix下的key集合: {prefix}{key} -> []
+type KeySet[K any] struct {
+	m Map[K, []byte]
+}
+
+func NewKeySet[K any](prefix []byte, kc KeyCodec[K]) KeySet[K] {
+	return KeySet[K]{m: NewMap(prefix, kc, BytesValue)}
+}
+
+func (ks KeySet[K]) Has(s store.KVStore, key K) (bool, error) {
+	return ks.m.Has(s, key)
+}
+
+func (ks KeySet[K]) Set(s store.KVStore, key K) error {
+	return ks.m.Set(s, key, []byte{})
+}
+
+func (ks KeySet[K]) Remove(s store.KVStore, key K) error {
+	return ks.m.Remove(s, key)
+}
+
+//Iterate 按key顺序遍历ranger范围内的key, ranger为nil时遍历全部
+func (ks KeySet[K]) Iterate(s store.KVStore, ranger Ranger[K], process func(key K) (stop bool)) error {
+	return iterate(prefixStore(s, ks.m.prefix), ks.m.kc, ranger, func(key K, _ []byte) (bool, error) {
+		return process(key), nil
+	})
+}
@@ -0,0 +1,81 @@
+package collections
+
+import (
+	"encoding/binary"
+
+	go_amino "github.com/tendermint/go-amino"
+)
+
+//ValueCodec value编码
+type ValueCodec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(bz []byte) (V, error)
+}
+
+var (
+	//StringValue 保存为字符串字节
+	StringValue ValueCodec[string] = stringValue{}
+	//Int64Value 保存为8字节大端序
+	Int64Value ValueCodec[int64] = int64Value{}
+	//BytesValue 保存为原始字节
+	BytesValue ValueCodec[[]byte] = bytesValue{}
+)
+
+type stringValue struct{}
+
+func (stringValue) Encode(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+func (stringValue) Decode(bz []byte) (string, error) {
+	return string(bz), nil
+}
+
+type int64Value struct{}
+
+func (int64Value) Encode(value int64) ([]byte, error) {
+	bz := make([]byte, 8)
+	binary.BigEndian.PutUint64(bz, uint64(value))
+	return bz, nil
+}
+
+func (int64Value) Decode(bz []byte) (int64, error) {
+	if len(bz) != 8 {
+		return 0, encodingError("invalid int64 value length: %d", len(bz))
+	}
+	return int64(binary.BigEndian.Uint64(bz)), nil
+}
+
+type bytesValue struct{}
+
+func (bytesValue) Encode(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (bytesValue) Decode(bz []byte) ([]byte, error) {
+	return append([]byte(nil), bz...), nil
+}
+
+//AminoValue 使用amino binary bare编码, 与BaseMapper.Set/Get保存的数据相同. V可以为cdc中注册的interface类型
+func AminoValue[V any](cdc *go_amino.Codec) ValueCodec[V] {
+	return aminoValue[V]{cdc: cdc}
+}
+
+type aminoValue[V any] struct {
+	cdc *go_amino.Codec
+}
+
+func (vc aminoValue[V]) Encode(value V) ([]byte, error) {
+	bz, err := vc.cdc.MarshalBinaryBare(value)
+	if err != nil {
+		return nil, encodingError("%v", err)
+	}
+	return bz, nil
+}
+
+func (vc aminoValue[V]) Decode(bz []byte) (value V, err error) {
+	if err = vc.cdc.UnmarshalBinaryBare(bz, &value); err != nil {
+		err = encodingError("%v", err)
+	}
+	return
+}
@@ -47,6 +47,7 @@ func NewContext(ms store.MultiStore, header abci.Header, isCheckTx bool, logger
 		gen:     0,
 	}
 	c = c.WithGasMeter(types.NewInfiniteGasMeter())
+	c = c.WithBlockGasMeter(types.NewInfiniteGasMeter())
 	c = c.WithMultiStore(ms)
 	c = c.WithBlockHeader(header)
 	c = c.WithBlockHeight(header.Height)
@@ -57,6 +58,8 @@ func NewContext(ms store.MultiStore, header abci.Header, isCheckTx bool, logger
 	c = c.WithVoteInfos(nil)
 	c = c.WithConsensusParams(nil)
 	c = c.WithMinimumFees([]types.Coin{&types.BaseCoin{}})
+	c = c.WithEventManager(types.NewEventManager())
+	c = c.WithMaxMemoLength(DefaultMaxMemoLength)
 	c = c.withRegisteredMap(registSeedMapper)
 	c = c.copyKVStoreMapperFromSeed()
 	return c
@@ -167,8 +170,12 @@ const (
 	contextKeyRegisteredMapper   //注册的mapper
 	contextKeyCurrentRegisteredMapper
 	contextKeyEventManager
+	contextKeyMaxMemoLength //TxStd memo最大长度
 )
 
+//DefaultMaxMemoLength TxStd memo默认最大长度
+const DefaultMaxMemoLength = 256
+
 //ContextKeySigners 用于保存tx中签名的账户
 const ContextKeySigners contextKey = 99999
 
@@ -199,10 +206,22 @@ func (c Context) Logger() log.Logger { return c.Value(contextKeyLogger).(log.Log
 
 func (c Context) GasMeter() types.GasMeter { return c.Value(contextKeyGasMeter).(types.GasMeter) }
 
+func (c Context) BlockGasMeter() types.GasMeter {
+	return c.Value(contextKeyBlockGasMeter).(types.GasMeter)
+}
+
 func (c Context) IsCheckTx() bool { return c.Value(contextKeyIsCheckTx).(bool) }
 
 func (c Context) MinimumFees() []types.Coin { return c.Value(contextKeyMinimumFees).([]types.Coin) }
 
+func (c Context) MaxMemoLength() int {
+	maxMemoLength := c.Value(contextKeyMaxMemoLength)
+	if maxMemoLength == nil {
+		return DefaultMaxMemoLength
+	}
+	return maxMemoLength.(int)
+}
+
 func (c Context) BlockTxIndex() int64 {
 	index := c.Value(contextKeyBlockTxIndex)
 	if index == nil {
@@ -305,6 +324,10 @@ func (c Context) WithMinimumFees(minFees []types.Coin) Context {
 	return c.withValue(contextKeyMinimumFees, minFees)
 }
 
+func (c Context) WithMaxMemoLength(maxMemoLength int) Context {
+	return c.withValue(contextKeyMaxMemoLength, maxMemoLength)
+}
+
 func (c Context) WithBlockTxIndex(blockTxIndex int64) Context {
 	return c.withValue(contextKeyBlockTxIndex, blockTxIndex)
 }
@@ -180,6 +180,7 @@ func TestContextWithCustom(t *testing.T) {
 		WithTxBytes(txbytes).
 		WithVoteInfos(signvals).
 		WithGasMeter(meter).
+		WithBlockGasMeter(meter).
 		WithMinimumFees(minFees).
 		WithBlockTxIndex(blockTxIndex).
 		WithTxQcpResultHandler(handerler)
@@ -191,6 +192,7 @@ func TestContextWithCustom(t *testing.T) {
 	require.Equal(t, logger, ctx.Logger())
 	require.Equal(t, signvals, ctx.VoteInfos())
 	require.Equal(t, meter, ctx.GasMeter())
+	require.Equal(t, meter, ctx.BlockGasMeter())
 	require.Equal(t, blockTxIndex, ctx.BlockTxIndex())
 
 	h := ctx.TxQcpResultHandler()
@@ -1,12 +1,16 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/feegrant"
 	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/txs"
 	btypes "github.com/QOSGroup/qbase/types"
+	"github.com/QOSGroup/qbase/upgrade"
 
 	"github.com/QOSGroup/qbase/baseabci"
 	"github.com/QOSGroup/qbase/context"
@@ -29,9 +33,12 @@ type BaseCoinApp struct {
 	*baseabci.BaseApp
 }
 
-func NewApp(cfg *cfg.Config, logger log.Logger, db dbm.DB, traceStore io.Writer) *BaseCoinApp {
+func NewApp(cfg *cfg.Config, logger log.Logger, db dbm.DB, traceStore io.Writer, baseAppOptions ...func(*baseabci.BaseApp)) *BaseCoinApp {
 
-	baseApp := baseabci.NewBaseApp(appName, cfg, logger, db, RegisterCodec, baseabci.SetPruning(store.PruneSyncable))
+	// feegrant, upgrade mapper为新增store, 加载不含这两个store的旧版本数据时以空store挂载
+	storeUpgrades := &store.StoreUpgrades{Added: []string{feegrant.MapperName, upgrade.MapperName}}
+	baseAppOptions = append([]func(*baseabci.BaseApp){baseabci.SetPruning(store.PruneSyncable), baseabci.SetStoreUpgrades(storeUpgrades)}, baseAppOptions...)
+	baseApp := baseabci.NewBaseApp(appName, cfg, logger, db, RegisterCodec, baseAppOptions...)
 	baseApp.SetCommitMultiStoreTracer(traceStore)
 
 	//baseApp.
@@ -43,14 +50,32 @@ func NewApp(cfg *cfg.Config, logger log.Logger, db dbm.DB, traceStore io.Writer)
 	// 设置 InitChainer
 	app.SetInitChainer(app.initChainer)
 
+	// 设置状态导出
+	app.SetExportAppStateFunc(app.exportAppState)
+
 	app.SetGasHandler(app.gasHandler)
 
+	// 手续费授权额度需足够支付MaxGas对应的gas费
+	app.SetMaxFeeHandler(app.maxFeeHandler)
+
+	// checkTx阶段拒绝MaxGas对应的gas费低于minimum_fees的tx
+	app.SetFeeCheckHandler(app.feeCheckHandler)
+
 	// 账户mapper
 	app.RegisterAccountProto(types.NewAppAccount)
 
 	// QCP mapper
 	// 默认已注入
 
+	// 手续费授权mapper
+	app.RegisterMapper(feegrant.NewFeeGrantMapper(app.GetCdc()))
+
+	// 升级mapper
+	app.RegisterMapper(upgrade.NewUpgradeMapper(app.GetCdc()))
+	app.RegisterQueryHandler(upgrade.MapperName, upgrade.Query)
+	// 新版本程序在此注册升级计划的数据迁移:
+	// app.RegisterUpgradeHandler("v2", func(ctx context.Context) { ... })
+
 	// Mount stores and load the latest state.
 	err := app.LoadLatestVersion()
 	if err != nil {
@@ -81,13 +106,73 @@ func (app *BaseCoinApp) initChainer(ctx context.Context, req abci.RequestInitCha
 		accountMapper.SetAccount(acc)
 	}
 
+	// 升级授权地址
+	if len(genesisState.UpgradeAuthority) > 0 {
+		upgrade.GetUpgradeMapper(ctx).SetAuthority(genesisState.UpgradeAuthority)
+	}
+
+	// 手续费授权额度
+	feeGrantMapper := baseabci.GetFeeGrantMapper(ctx)
+	for _, allowance := range genesisState.FeeAllowances {
+		feeGrantMapper.SetAllowance(allowance)
+	}
+
+	// 未执行的升级计划
+	if genesisState.UpgradePlan != nil {
+		if err := upgrade.GetUpgradeMapper(ctx).ScheduleUpgrade(ctx, *genesisState.UpgradePlan); err != nil {
+			panic(err)
+		}
+	}
+
 	return abci.ResponseInitChain{}
 }
 
+// 导出状态, 与initChainer对应
+func (app *BaseCoinApp) exportAppState(ctx context.Context) (json.RawMessage, error) {
+	accountMapper := baseabci.GetAccountMapper(ctx)
+
+	genesisState := &types.GenesisState{}
+	accountMapper.IterateAccounts(func(acc account.Account) bool {
+		genesisState.Accounts = append(genesisState.Accounts, types.NewGenesisAccount(acc.(*types.AppAccount)))
+		return false
+	})
+	genesisState.UpgradeAuthority, _ = upgrade.GetUpgradeMapper(ctx).GetAuthority()
+	if plan, exists := upgrade.GetUpgradeMapper(ctx).GetPlan(); exists {
+		genesisState.UpgradePlan = &plan
+	}
+	baseabci.GetFeeGrantMapper(ctx).IterateAllAllowances(func(allowance feegrant.Allowance) bool {
+		genesisState.FeeAllowances = append(genesisState.FeeAllowances, allowance)
+		return false
+	})
+
+	return app.BaseApp.GetCdc().MarshalJSONIndent(genesisState, "", "  ")
+}
+
+// tx最多消耗的gas费
+func (app *BaseCoinApp) maxFeeHandler(ctx context.Context, tx *txs.TxStd) btypes.BaseCoins {
+	return btypes.BaseCoins{btypes.NewInt64BaseCoin("qstar", tx.MaxGas.Int64()/gasPerUnitCost)}
+}
+
+// tx最多支付的gas费需不低于节点配置的最低手续费
+func (app *BaseCoinApp) feeCheckHandler(ctx context.Context, tx *txs.TxStd, minimumFees []btypes.Coin) btypes.Error {
+	fees := app.maxFeeHandler(ctx, tx)
+	for _, minimumFee := range minimumFees {
+		if fees.AmountOf(minimumFee.GetName()).LT(minimumFee.GetAmount()) {
+			return btypes.ErrInsufficientFee(fmt.Sprintf("insufficient fee. minimum: %s, max gas fee: %s", minimumFee, fees))
+		}
+	}
+	return nil
+}
+
 func (app *BaseCoinApp) gasHandler(ctx context.Context, payer btypes.AccAddress) (gasUsed uint64, err btypes.Error) {
 	gasFeeUsed := int64(ctx.GasMeter().GasConsumed()) / gasPerUnitCost
 
 	if gasFeeUsed > 0 {
+		// payer非签名者时, 扣除payer授予签名者的额度
+		if err := feegrant.UseGrantedFees(ctx, payer, btypes.BaseCoins{btypes.NewInt64BaseCoin("qstar", gasFeeUsed)}); err != nil {
+			return uint64(gasFeeUsed * gasPerUnitCost), err
+		}
+
 		accountMapper := ctx.Mapper(account.AccountMapperName).(*account.AccountMapper)
 		account := accountMapper.GetAccount(payer).(*types.AppAccount)
 
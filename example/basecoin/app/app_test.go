@@ -0,0 +1,57 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/baseabci"
+	"github.com/QOSGroup/qbase/example/basecoin/tx"
+	"github.com/QOSGroup/qbase/example/basecoin/types"
+	"github.com/QOSGroup/qbase/txs"
+	btypes "github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const testChainID = "basecoin-test"
+
+func TestMinimumFees(t *testing.T) {
+	app := NewApp(nil, log.NewNopLogger(), dbm.NewMemDB(), nil,
+		baseabci.SetMinimumFees(btypes.BaseCoins{btypes.NewInt64BaseCoin("qstar", 100)}))
+
+	privKey := ed25519.GenPrivKey()
+	from := btypes.AccAddress(privKey.PubKey().Address())
+	to := btypes.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+	genesisState := types.GenesisState{
+		Accounts: []*types.GenesisAccount{{Address: from, Coins: btypes.BaseCoins{btypes.NewInt64BaseCoin("qstar", 1000000)}}},
+	}
+	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: app.GetCdc().MustMarshalJSON(genesisState)})
+	app.Commit()
+
+	//MaxGas对应的gas费为maxGas/gasPerUnitCost qstar
+	buildTx := func(maxGas int64, nonce int64) []byte {
+		sendTx := tx.NewSendTx(from, to, *btypes.NewInt64BaseCoin("qstar", 1))
+		stdTx := txs.NewTxStd(&sendTx, testChainID, btypes.NewInt(maxGas))
+		signature, err := stdTx.SignTx(privKey, nonce, "", testChainID)
+		require.Nil(t, err)
+		stdTx.Signature = []txs.Signature{{Pubkey: privKey.PubKey(), Signature: signature, Nonce: nonce}}
+		return app.GetCdc().MustMarshalBinaryBare(stdTx)
+	}
+
+	//gas费低于最低手续费, checkTx拒绝
+	underpriced := buildTx(99*gasPerUnitCost, 1)
+	res := app.CheckTx(abci.RequestCheckTx{Tx: underpriced})
+	require.Equal(t, uint32(btypes.CodeInsufficientFee), res.Code, res.Log)
+
+	res = app.CheckTx(abci.RequestCheckTx{Tx: buildTx(100*gasPerUnitCost, 1)})
+	require.Equal(t, uint32(btypes.CodeOK), res.Code, res.Log)
+
+	//deliverTx不校验最低手续费
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: testChainID}})
+	deliverRes := app.DeliverTx(abci.RequestDeliverTx{Tx: underpriced})
+	require.Equal(t, uint32(btypes.CodeOK), deliverRes.Code, deliverRes.Log)
+	app.EndBlock(abci.RequestEndBlock{})
+	app.Commit()
+}
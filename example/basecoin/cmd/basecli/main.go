@@ -34,6 +34,7 @@ func main() {
 	//tx
 	txCommand := bcli.TxCommand()
 	txCommand.AddCommand(ctypes.PostCommands(client.Commands(cdc)...)...)
+	txCommand.AddCommand(bcli.TxCommands(cdc)...)
 
 	rootCmd.AddCommand(
 		config.Cmd(types.DefaultCLIHome),
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
 
+	"github.com/QOSGroup/qbase/baseabci"
 	"github.com/QOSGroup/qbase/example/basecoin/app"
 	"github.com/QOSGroup/qbase/example/basecoin/types"
 	"github.com/QOSGroup/qbase/server"
+	"github.com/QOSGroup/qbase/server/config"
 	btypes "github.com/QOSGroup/qbase/types"
 	"github.com/QOSGroup/qbase/version"
 	"github.com/spf13/cobra"
@@ -42,7 +45,7 @@ func main() {
 	//add init command
 	rootCmd.AddCommand(server.InitCmd(ctx, cdc, genBaseCoindGenesisDoc, types.DefaultNodeHome))
 
-	server.AddCommands(ctx, cdc, rootCmd, newApp)
+	server.AddCommands(ctx, cdc, rootCmd, newApp, exportAppState)
 
 	executor := cli.PrepareBaseCmd(rootCmd, "basecoin", types.DefaultNodeHome)
 
@@ -53,7 +56,23 @@ func main() {
 }
 
 func newApp(cfg *cfg.Config, logger log.Logger, db dbm.DB, storeTracer io.Writer) abci.Application {
-	return app.NewApp(cfg, logger, db, storeTracer)
+	appConf, err := config.ParseConfig()
+	if err != nil {
+		panic(err)
+	}
+	return app.NewApp(cfg, logger, db, storeTracer, baseabci.SetMinimumFees(appConf.MinimumFees()), baseabci.SetHaltHeight(appConf.HaltHeight))
+}
+
+func exportAppState(cfg *cfg.Config, logger log.Logger, db dbm.DB, storeTracer io.Writer, height int64) (json.RawMessage, *abci.ConsensusParams, int64, error) {
+	bApp := app.NewApp(cfg, logger, db, storeTracer)
+	if height != -1 {
+		if err := bApp.LoadVersion(height); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	appState, consParams, err := bApp.ExportAppState()
+	return appState, consParams, bApp.LastBlockHeight(), err
 }
 
 func genBaseCoindGenesisDoc(ctx *server.Context, cdc *go_amino.Codec, chainID string, nodeValidatorPubKey crypto.PubKey) (tmtypes.GenesisDoc, error) {
@@ -8,8 +8,10 @@ import (
 
 	"github.com/QOSGroup/qbase/account"
 	clikeys "github.com/QOSGroup/qbase/client/keys"
+	"github.com/QOSGroup/qbase/feegrant"
 	"github.com/QOSGroup/qbase/keys"
 	"github.com/QOSGroup/qbase/types"
+	"github.com/QOSGroup/qbase/upgrade"
 	"github.com/tendermint/go-amino"
 	"github.com/tendermint/tendermint/crypto"
 
@@ -30,6 +32,12 @@ var (
 type GenesisState struct {
 	CAPubKey crypto.PubKey     `json:"pub_key"`
 	Accounts []*GenesisAccount `json:"accounts"`
+	//可设置升级计划的地址, 为空时不能设置升级计划
+	UpgradeAuthority types.AccAddress `json:"upgrade_authority,omitempty"`
+	//手续费授权额度
+	FeeAllowances []feegrant.Allowance `json:"fee_allowances,omitempty"`
+	//未执行的升级计划
+	UpgradePlan *upgrade.Plan `json:"upgrade_plan,omitempty"`
 }
 
 // 初始账户
@@ -0,0 +1,10 @@
+package feegrant
+
+import (
+	go_amino "github.com/tendermint/go-amino"
+)
+
+func RegisterCodec(cdc *go_amino.Codec) {
+	cdc.RegisterConcrete(&TxGrantAllowance{}, "qbase/feegrant/TxGrantAllowance", nil)
+	cdc.RegisterConcrete(&TxRevokeAllowance{}, "qbase/feegrant/TxRevokeAllowance", nil)
+}
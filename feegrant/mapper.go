@@ -0,0 +1,202 @@
+package feegrant
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/types"
+	go_amino "github.com/tendermint/go-amino"
+)
+
+//手续费授权:
+//granter授权grantee在额度及有效期内使用granter账户支付gas费
+//ITx.GetGasPayer()不是tx签名者时, 需由payer授权给tx签名者, 否则tx在AnteHandler中被拒绝
+const (
+	MapperName = "feegrant"
+	//granter授予grantee的额度
+	allowancePrefixKey = "allowance/"
+	allowanceKey       = allowancePrefixKey + "%s/%s"
+)
+
+func BuildFeeGrantStoreQueryPath() []byte {
+	return []byte(fmt.Sprintf("/store/%s/key", MapperName))
+}
+
+func BuildAllowanceKey(granter, grantee types.AccAddress) []byte {
+	return []byte(fmt.Sprintf(allowanceKey, granter.String(), grantee.String()))
+}
+
+func BuildAllowancePrefixKey(granter types.AccAddress) []byte {
+	return []byte(fmt.Sprintf(allowancePrefixKey+"%s/", granter.String()))
+}
+
+//Allowance granter授予grantee的手续费额度
+type Allowance struct {
+	Granter    types.AccAddress `json:"granter"`
+	Grantee    types.AccAddress `json:"grantee"`
+	SpendLimit types.BaseCoins  `json:"spend_limit"` //剩余额度, 为空时不限额度
+	Expiration time.Time        `json:"expiration"`  //过期时间, 为零值时永不过期
+}
+
+//IsExpired 区块时间blockTime时额度是否已过期
+func (a Allowance) IsExpired(blockTime time.Time) bool {
+	return !a.Expiration.IsZero() && !blockTime.Before(a.Expiration)
+}
+
+//accept 校验并扣除fee, 返回扣除后的额度及额度是否已用尽
+func (a Allowance) accept(fee types.BaseCoins, blockTime time.Time) (Allowance, bool, error) {
+	if a.IsExpired(blockTime) {
+		return a, false, fmt.Errorf("fee allowance from %s to %s expired at %s", a.Granter, a.Grantee, a.Expiration)
+	}
+
+	if len(a.SpendLimit) == 0 {
+		return a, false, nil
+	}
+
+	if !a.SpendLimit.IsGTE(fee) {
+		return a, false, fmt.Errorf("fee allowance from %s to %s exceeded. left: %s, fee: %s", a.Granter, a.Grantee, a.SpendLimit, fee)
+	}
+
+	a.SpendLimit = a.SpendLimit.Minus(fee)
+	return a, a.SpendLimit.IsZero(), nil
+}
+
+type FeeGrantMapper struct {
+	*mapper.BaseMapper
+}
+
+var _ mapper.IMapper = (*FeeGrantMapper)(nil)
+
+func NewFeeGrantMapper(cdc *go_amino.Codec) *FeeGrantMapper {
+	var feeGrantMapper = FeeGrantMapper{}
+	feeGrantMapper.BaseMapper = mapper.NewBaseMapper(cdc, MapperName)
+	return &feeGrantMapper
+}
+
+func (mapper *FeeGrantMapper) Copy() mapper.IMapper {
+	cpyMapper := &FeeGrantMapper{}
+	cpyMapper.BaseMapper = mapper.BaseMapper.Copy()
+	return cpyMapper
+}
+
+func (mapper *FeeGrantMapper) GetAllowance(granter, grantee types.AccAddress) (allowance Allowance, exists bool) {
+	exists = mapper.Get(BuildAllowanceKey(granter, grantee), &allowance)
+	return
+}
+
+func (mapper *FeeGrantMapper) SetAllowance(allowance Allowance) {
+	mapper.Set(BuildAllowanceKey(allowance.Granter, allowance.Grantee), allowance)
+}
+
+func (mapper *FeeGrantMapper) DelAllowance(granter, grantee types.AccAddress) {
+	mapper.Del(BuildAllowanceKey(granter, grantee))
+}
+
+//IterateAllowances 遍历granter授予的全部额度
+func (mapper *FeeGrantMapper) IterateAllowances(granter types.AccAddress, process func(allowance Allowance) (stop bool)) {
+	mapper.Iterator(BuildAllowancePrefixKey(granter), func(bz []byte) bool {
+		var allowance Allowance
+		mapper.DecodeObject(bz, &allowance)
+		return process(allowance)
+	})
+}
+
+//IterateAllAllowances 遍历全部额度, 用于导出状态
+func (mapper *FeeGrantMapper) IterateAllAllowances(process func(allowance Allowance) (stop bool)) {
+	mapper.Iterator([]byte(allowancePrefixKey), func(bz []byte) bool {
+		var allowance Allowance
+		mapper.DecodeObject(bz, &allowance)
+		return process(allowance)
+	})
+}
+
+//Covers 额度是否足够支付fee. fee为nil时fee未知, 仅不限额度时返回true
+func (a Allowance) Covers(fee types.BaseCoins) bool {
+	if len(a.SpendLimit) == 0 {
+		return true
+	}
+	return fee != nil && a.SpendLimit.IsGTE(fee)
+}
+
+//FindGrantee 按签名者顺序返回第一个持有granter有效且足够支付maxFee额度的签名者
+//maxFee为tx最多消耗的gas费, 为nil时仅接受不限额度的授权
+func (mapper *FeeGrantMapper) FindGrantee(ctx context.Context, granter types.AccAddress, signers []types.AccAddress, maxFee types.BaseCoins) (types.AccAddress, error) {
+	for _, signer := range signers {
+		allowance, exists := mapper.GetAllowance(granter, signer)
+		if exists && !allowance.IsExpired(ctx.BlockHeader().Time) && allowance.Covers(maxFee) {
+			return signer, nil
+		}
+	}
+	return nil, fmt.Errorf("gas payer %s is not a signer and has not granted a fee allowance covering max fee %s to any signer", granter, maxFee)
+}
+
+//UseGrantedFees 从granter授予grantee的额度中扣除fee, 额度用尽时删除
+func (mapper *FeeGrantMapper) UseGrantedFees(ctx context.Context, granter, grantee types.AccAddress, fee types.BaseCoins) error {
+	allowance, exists := mapper.GetAllowance(granter, grantee)
+	if !exists {
+		return fmt.Errorf("fee allowance from %s to %s not found", granter, grantee)
+	}
+
+	allowance, used, err := allowance.accept(fee, ctx.BlockHeader().Time)
+	if err != nil {
+		return err
+	}
+
+	if used {
+		mapper.DelAllowance(granter, grantee)
+	} else {
+		mapper.SetAllowance(allowance)
+	}
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeUseAllowance,
+		types.NewAttribute(AttributeKeyGranter, granter.String()),
+		types.NewAttribute(AttributeKeyGrantee, grantee.String()),
+		types.NewAttribute(AttributeKeyFee, fee.String()),
+	))
+	return nil
+}
+
+//feeGranteeKey AnteHandler校验通过后, 代付gas费的签名者保存在ctx中
+type feeGranteeKey struct{}
+
+//WithFeeGrantee 设置使用gas payer授权额度的签名者
+func WithFeeGrantee(ctx context.Context, grantee types.AccAddress) context.Context {
+	return ctx.WithValue(feeGranteeKey{}, grantee)
+}
+
+//GetFeeGrantee 返回使用gas payer授权额度的签名者, gas payer为签名者时返回空
+func GetFeeGrantee(ctx context.Context) types.AccAddress {
+	grantee, _ := ctx.Value(feeGranteeKey{}).(types.AccAddress)
+	return grantee
+}
+
+//UseGrantedFees 供gasPreHandler, gasHandler在扣除payer账户gas费前调用:
+//payer为tx签名者时直接返回; 否则从payer授予签名者的额度中扣除fee, 额度不足或过期时返回错误
+func UseGrantedFees(ctx context.Context, payer types.AccAddress, fee types.BaseCoins) types.Error {
+	grantee := GetFeeGrantee(ctx)
+	if len(grantee) == 0 {
+		return nil
+	}
+
+	mapper, err := getFeeGrantMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error())
+	}
+
+	if err := mapper.UseGrantedFees(ctx, payer, grantee, fee); err != nil {
+		return types.ErrUnauthorized(err.Error())
+	}
+	return nil
+}
+
+func getFeeGrantMapper(ctx context.Context) (*FeeGrantMapper, error) {
+	mapper, ok := ctx.Mapper(MapperName).(*FeeGrantMapper)
+	if !ok {
+		return nil, errors.New("feegrant mapper not found")
+	}
+	return mapper, nil
+}
@@ -0,0 +1,117 @@
+package feegrant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	go_amino "github.com/tendermint/go-amino"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func newTestFeeGrantContext(blockTime time.Time) (context.Context, *FeeGrantMapper) {
+	cdc := go_amino.NewCodec()
+	feeGrantMapper := NewFeeGrantMapper(cdc)
+	feeGrantMapper.SetCodec(cdc)
+
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(feeGrantMapper.GetStoreKey(), types.StoreTypeIAVL, db)
+	cms.LoadLatestVersion()
+	ctx := context.NewContext(cms, abci.Header{Time: blockTime}, false, log.NewNopLogger(), map[string]mapper.IMapper{MapperName: feeGrantMapper})
+	return ctx, ctx.Mapper(MapperName).(*FeeGrantMapper)
+}
+
+func newTestAddress() types.AccAddress {
+	return types.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+}
+
+func Test_FeeGrantMapper(t *testing.T) {
+	now := time.Now().UTC()
+	ctx, feeGrantMapper := newTestFeeGrantContext(now)
+
+	granter, grantee, other := newTestAddress(), newTestAddress(), newTestAddress()
+	qstar := func(amount int64) types.BaseCoins {
+		return types.BaseCoins{types.NewInt64BaseCoin("qstar", amount)}
+	}
+
+	feeGrantMapper.SetAllowance(Allowance{Granter: granter, Grantee: grantee, SpendLimit: qstar(10)})
+	feeGrantMapper.SetAllowance(Allowance{Granter: granter, Grantee: other, Expiration: now})
+
+	var allowances []Allowance
+	feeGrantMapper.IterateAllowances(granter, func(allowance Allowance) bool {
+		allowances = append(allowances, allowance)
+		return false
+	})
+	require.Equal(t, 2, len(allowances))
+
+	//已过期的额度不可使用
+	found, err := feeGrantMapper.FindGrantee(ctx, granter, []types.AccAddress{other, grantee}, qstar(10))
+	require.Nil(t, err)
+	require.Equal(t, grantee, found)
+	require.NotNil(t, feeGrantMapper.UseGrantedFees(ctx, granter, other, qstar(1)))
+	_, err = feeGrantMapper.FindGrantee(ctx, granter, []types.AccAddress{other}, qstar(1))
+	require.NotNil(t, err)
+
+	//额度需足够支付max fee, max fee未知时仅接受不限额度
+	_, err = feeGrantMapper.FindGrantee(ctx, granter, []types.AccAddress{grantee}, qstar(11))
+	require.NotNil(t, err)
+	_, err = feeGrantMapper.FindGrantee(ctx, granter, []types.AccAddress{grantee}, nil)
+	require.NotNil(t, err)
+
+	//扣除额度, 用尽后删除
+	require.Nil(t, feeGrantMapper.UseGrantedFees(ctx, granter, grantee, qstar(6)))
+	require.NotNil(t, feeGrantMapper.UseGrantedFees(ctx, granter, grantee, qstar(6)))
+	allowance, exists := feeGrantMapper.GetAllowance(granter, grantee)
+	require.True(t, exists)
+	require.Equal(t, int64(4), allowance.SpendLimit.AmountOf("qstar").Int64())
+	require.Nil(t, feeGrantMapper.UseGrantedFees(ctx, granter, grantee, qstar(4)))
+	_, exists = feeGrantMapper.GetAllowance(granter, grantee)
+	require.False(t, exists)
+
+	//不限额度
+	feeGrantMapper.SetAllowance(Allowance{Granter: granter, Grantee: grantee, Expiration: now.Add(time.Hour)})
+	require.Nil(t, UseGrantedFees(WithFeeGrantee(ctx, grantee), granter, qstar(1000)))
+	_, exists = feeGrantMapper.GetAllowance(granter, grantee)
+	require.True(t, exists)
+
+	//gas payer为签名者时不使用额度
+	require.Nil(t, UseGrantedFees(ctx, other, qstar(1000)))
+}
+
+func Test_TxGrantAllowance(t *testing.T) {
+	now := time.Now().UTC()
+	ctx, feeGrantMapper := newTestFeeGrantContext(now)
+	granter, grantee := newTestAddress(), newTestAddress()
+
+	tx := &TxGrantAllowance{Granter: granter, Grantee: granter}
+	require.NotNil(t, tx.ValidateData(ctx))
+	tx.Grantee = grantee
+	tx.Expiration = now
+	require.NotNil(t, tx.ValidateData(ctx))
+	tx.Expiration = now.Add(time.Hour)
+	tx.SpendLimit = types.BaseCoins{types.NewInt64BaseCoin("qstar", -1)}
+	require.NotNil(t, tx.ValidateData(ctx))
+	tx.SpendLimit = types.BaseCoins{types.NewInt64BaseCoin("qstar", 10)}
+	require.Nil(t, tx.ValidateData(ctx))
+
+	result, _ := tx.Exec(ctx)
+	require.True(t, result.IsOK())
+	allowance, exists := feeGrantMapper.GetAllowance(granter, grantee)
+	require.True(t, exists)
+	require.Equal(t, tx.Expiration, allowance.Expiration)
+
+	revokeTx := &TxRevokeAllowance{Granter: granter, Grantee: grantee}
+	require.Nil(t, revokeTx.ValidateData(ctx))
+	result, _ = revokeTx.Exec(ctx)
+	require.True(t, result.IsOK())
+	result, _ = revokeTx.Exec(ctx)
+	require.False(t, result.IsOK())
+}
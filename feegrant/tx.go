@@ -0,0 +1,147 @@
+package feegrant
+
+import (
+	"errors"
+	"time"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+)
+
+const (
+	EventTypeGrantAllowance  = "grant_allowance"
+	EventTypeRevokeAllowance = "revoke_allowance"
+	EventTypeUseAllowance    = "use_allowance"
+
+	AttributeKeyGranter = "granter"
+	AttributeKeyGrantee = "grantee"
+	AttributeKeyFee     = "fee"
+)
+
+//TxGrantAllowance granter授权grantee使用granter账户支付gas费, 覆盖已有额度. 需由granter签名
+type TxGrantAllowance struct {
+	Granter    types.AccAddress `json:"granter"`
+	Grantee    types.AccAddress `json:"grantee"`
+	SpendLimit types.BaseCoins  `json:"spend_limit"` //额度, 为空时不限额度
+	Expiration time.Time        `json:"expiration"`  //过期时间, 为零值时永不过期
+}
+
+var _ txs.ITx = (*TxGrantAllowance)(nil)
+
+func (tx *TxGrantAllowance) ValidateData(ctx context.Context) error {
+	if err := validateGranterAndGrantee(tx.Granter, tx.Grantee); err != nil {
+		return err
+	}
+	if !tx.SpendLimit.IsValid() || !tx.SpendLimit.IsNotNegative() {
+		return errors.New("invalid spend limit")
+	}
+	if !tx.Expiration.IsZero() && !ctx.BlockHeader().Time.Before(tx.Expiration) {
+		return errors.New("expiration is before block time")
+	}
+	return nil
+}
+
+func (tx *TxGrantAllowance) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper, err := getFeeGrantMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	mapper.SetAllowance(Allowance{
+		Granter:    tx.Granter,
+		Grantee:    tx.Grantee,
+		SpendLimit: tx.SpendLimit,
+		Expiration: tx.Expiration,
+	})
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeGrantAllowance,
+		types.NewAttribute(AttributeKeyGranter, tx.Granter.String()),
+		types.NewAttribute(AttributeKeyGrantee, tx.Grantee.String()),
+	))
+	return
+}
+
+func (tx *TxGrantAllowance) GetSigner() []types.AccAddress {
+	return []types.AccAddress{tx.Granter}
+}
+
+func (tx *TxGrantAllowance) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxGrantAllowance) GetGasPayer() types.AccAddress {
+	return tx.Granter
+}
+
+func (tx *TxGrantAllowance) GetSignData() []byte {
+	ret := append([]byte{}, tx.Granter.Bytes()...)
+	ret = append(ret, tx.Grantee.Bytes()...)
+	ret = append(ret, []byte(tx.SpendLimit.String())...)
+	if !tx.Expiration.IsZero() {
+		ret = append(ret, types.Int2Byte(tx.Expiration.UnixNano())...)
+	}
+	return ret
+}
+
+//TxRevokeAllowance granter撤销授予grantee的额度. 需由granter签名
+type TxRevokeAllowance struct {
+	Granter types.AccAddress `json:"granter"`
+	Grantee types.AccAddress `json:"grantee"`
+}
+
+var _ txs.ITx = (*TxRevokeAllowance)(nil)
+
+func (tx *TxRevokeAllowance) ValidateData(ctx context.Context) error {
+	return validateGranterAndGrantee(tx.Granter, tx.Grantee)
+}
+
+func (tx *TxRevokeAllowance) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper, err := getFeeGrantMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	if _, exists := mapper.GetAllowance(tx.Granter, tx.Grantee); !exists {
+		return types.ErrInternal("fee allowance not found").Result(), nil
+	}
+	mapper.DelAllowance(tx.Granter, tx.Grantee)
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeRevokeAllowance,
+		types.NewAttribute(AttributeKeyGranter, tx.Granter.String()),
+		types.NewAttribute(AttributeKeyGrantee, tx.Grantee.String()),
+	))
+	return
+}
+
+func (tx *TxRevokeAllowance) GetSigner() []types.AccAddress {
+	return []types.AccAddress{tx.Granter}
+}
+
+func (tx *TxRevokeAllowance) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxRevokeAllowance) GetGasPayer() types.AccAddress {
+	return tx.Granter
+}
+
+func (tx *TxRevokeAllowance) GetSignData() []byte {
+	ret := append([]byte{}, tx.Granter.Bytes()...)
+	return append(ret, tx.Grantee.Bytes()...)
+}
+
+func validateGranterAndGrantee(granter, grantee types.AccAddress) error {
+	if len(granter) == 0 {
+		return errors.New("granter is empty")
+	}
+	if len(grantee) == 0 {
+		return errors.New("grantee is empty")
+	}
+	if granter.Equals(grantee) {
+		return errors.New("granter and grantee are the same")
+	}
+	return nil
+}
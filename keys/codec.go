@@ -9,4 +9,5 @@ func RegisterCodec(cdc *go_amino.Codec) {
 	cdc.RegisterConcrete(&localInfo{}, "qbase/keys/localInfo", nil)
 	cdc.RegisterConcrete(&offlineInfo{}, "qbase/keys/offlineInfo", nil)
 	cdc.RegisterConcrete(&importInfo{}, "qbase/keys/importInfo", nil)
+	cdc.RegisterConcrete(&multiInfo{}, "qbase/keys/multiInfo", nil)
 }
@@ -13,6 +13,7 @@ import (
 
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/multisig"
 
 	"github.com/tyler-smith/go-bip39"
 
@@ -93,6 +94,15 @@ func (kb Keybase) CreateOffline(name string, pub crypto.PubKey) (Info, error) {
 	return kb.writeOfflineKey(pub, name), nil
 }
 
+// CreateMulti creates a new reference to a multisig (offline) keypair. It
+// returns the created key info
+func (kb Keybase) CreateMulti(name string, pub crypto.PubKey) (Info, error) {
+	if _, ok := pub.(multisig.PubKeyMultisigThreshold); !ok {
+		return nil, fmt.Errorf("%s is not a multisig pubkey", name)
+	}
+	return kb.writeMultisigKey(pub, name), nil
+}
+
 func (kb Keybase) CreateImportInfo(name, passwd string, priv crypto.PrivKey) (Info, error) {
 	return kb.writeImportInfoKey(priv, name, passwd), nil
 }
@@ -202,6 +212,8 @@ func (kb Keybase) Sign(name, passphrase string, msg []byte) (sig []byte, pub cry
 		}
 		kb.cdc.MustUnmarshalBinaryLengthPrefixed([]byte(signed), sig)
 		return sig, linfo.GetPubKey(), nil
+	case *multiInfo:
+		return nil, nil, fmt.Errorf("multisig key %s cannot sign directly, sign with each member key and combine the signatures", name)
 	}
 	sig, err = priv.Sign(msg)
 	if err != nil {
@@ -337,7 +349,7 @@ func (kb Keybase) Delete(name, passphrase string) error {
 		kb.db.DeleteSync(infoKey(name))
 		return nil
 
-	case *offlineInfo:
+	case *offlineInfo, *multiInfo:
 		if passphrase != "yes" {
 			return fmt.Errorf("enter 'yes' exactly to delete the key - this cannot be undone")
 		}
@@ -427,6 +439,12 @@ func (kb Keybase) writeOfflineKey(pub crypto.PubKey, name string) Info {
 	return info
 }
 
+func (kb Keybase) writeMultisigKey(pub crypto.PubKey, name string) Info {
+	info := newMultiInfo(name, pub)
+	kb.writeInfo(info, name)
+	return info
+}
+
 func (kb Keybase) writeInfo(info Info, name string) {
 	// write the info by key
 	key := infoKey(name)
@@ -451,6 +469,7 @@ const (
 	TypeLocal   KeyType = 0 //从命令行生成或从助记符中恢复
 	TypeOffline KeyType = 1
 	TypeImport  KeyType = 2 //以私钥的方式导入
+	TypeMulti   KeyType = 3 //多签公钥, 由多个成员公钥及门限组成
 
 )
 
@@ -458,6 +477,7 @@ var keyTypes = map[KeyType]string{
 	TypeLocal:   "local",
 	TypeOffline: "offline",
 	TypeImport:  "import",
+	TypeMulti:   "multi",
 }
 
 // String implements the stringer interface for KeyType.
@@ -480,6 +500,7 @@ type Info interface {
 var _ Info = &localInfo{}
 var _ Info = &offlineInfo{}
 var _ Info = &importInfo{}
+var _ Info = &multiInfo{}
 
 type importInfo struct {
 	Name           string        `json:"name"`
@@ -573,6 +594,35 @@ func (i offlineInfo) GetAddress() types.AccAddress {
 	return types.AccAddress(i.GetPubKey().Address().Bytes())
 }
 
+// multiInfo is the public information about a multisig key
+type multiInfo struct {
+	Name   string        `json:"name"`
+	PubKey crypto.PubKey `json:"pubkey"`
+}
+
+func newMultiInfo(name string, pub crypto.PubKey) Info {
+	return &multiInfo{
+		Name:   name,
+		PubKey: pub,
+	}
+}
+
+func (i multiInfo) GetType() KeyType {
+	return TypeMulti
+}
+
+func (i multiInfo) GetName() string {
+	return i.Name
+}
+
+func (i multiInfo) GetPubKey() crypto.PubKey {
+	return i.PubKey
+}
+
+func (i multiInfo) GetAddress() types.AccAddress {
+	return types.AccAddress(i.GetPubKey().Address().Bytes())
+}
+
 // encoding info
 func writeInfo(cdc *go_amino.Codec, i Info) []byte {
 	return cdc.MustMarshalBinaryLengthPrefixed(i)
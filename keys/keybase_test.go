@@ -12,6 +12,7 @@ import (
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cryptoAmino "github.com/tendermint/tendermint/crypto/encoding/amino"
+	"github.com/tendermint/tendermint/crypto/multisig"
 
 	go_amino "github.com/tendermint/go-amino"
 	dbm "github.com/tendermint/tm-db"
@@ -257,6 +258,38 @@ func TestExportImportPubKey(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+// TestCreateMulti makes sure multisig keys can be stored but not used to sign
+func TestCreateMulti(t *testing.T) {
+	db := dbm.NewMemDB()
+	cstore := New(db, MakeCodec())
+
+	pubkeys := []crypto.PubKey{ed25519.GenPrivKey().PubKey(), ed25519.GenPrivKey().PubKey(), ed25519.GenPrivKey().PubKey()}
+	multiPub := multisig.NewPubKeyMultisigThreshold(2, pubkeys)
+
+	_, err := cstore.CreateMulti("single", pubkeys[0])
+	require.NotNil(t, err)
+
+	info, err := cstore.CreateMulti("treasury", multiPub)
+	require.NoError(t, err)
+	require.Equal(t, TypeMulti, info.GetType())
+	require.Equal(t, "multi", info.GetType().String())
+
+	treasury, err := cstore.Get("treasury")
+	require.NoError(t, err)
+	require.True(t, multiPub.Equals(treasury.GetPubKey()))
+	byAddr, err := cstore.GetByAddress(types.AccAddress(multiPub.Address()))
+	require.NoError(t, err)
+	require.Equal(t, "treasury", byAddr.GetName())
+
+	_, _, err = cstore.Sign("treasury", "", []byte("msg"))
+	require.NotNil(t, err)
+
+	require.NotNil(t, cstore.Delete("treasury", "no"))
+	require.NoError(t, cstore.Delete("treasury", "yes"))
+	_, err = cstore.Get("treasury")
+	require.NotNil(t, err)
+}
+
 // TestAdvancedKeyManagement verifies update, import, export functionality
 func TestAdvancedKeyManagement(t *testing.T) {
 
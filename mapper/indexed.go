@@ -0,0 +1,315 @@
+package mapper
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/QOSGroup/qbase/types"
+)
+
+var (
+	collectionDataPrefix  = []byte{0x00}
+	collectionIndexPrefix = []byte{0x01}
+	indexValue            = []byte{0x01}
+)
+
+//Index 值的二级索引
+type Index struct {
+	Name   string //索引名称, 不能包含0x00
+	Unique bool   //唯一索引, 不同主键的值不能有相同的索引key
+	//IndexKeys 返回值的索引key, 空key不建立索引. value与IndexedCollection.Set的value类型相同
+	IndexKeys func(value interface{}) [][]byte
+}
+
+//NewUniqueIndex 返回每个值最多一个索引key的唯一索引, indexKey返回nil时不建立索引
+func NewUniqueIndex(name string, indexKey func(value interface{}) []byte) Index {
+	return Index{Name: name, Unique: true, IndexKeys: singleIndexKey(indexKey)}
+}
+
+//NewMultiIndex 返回每个值最多一个索引key的普通索引, indexKey返回nil时不建立索引
+func NewMultiIndex(name string, indexKey func(value interface{}) []byte) Index {
+	return Index{Name: name, IndexKeys: singleIndexKey(indexKey)}
+}
+
+func singleIndexKey(indexKey func(value interface{}) []byte) func(value interface{}) [][]byte {
+	return func(value interface{}) [][]byte {
+		if key := indexKey(value); len(key) > 0 {
+			return [][]byte{key}
+		}
+		return nil
+	}
+}
+
+//IndexedCollection 带二级索引的集合: 在mapper store的prefix下按主键保存值, 并在Set/Del时自动维护值的二级索引. 索引与值保存在同一store中, 计入app hash
+//数据:     {prefix}0x00{primaryKey} -> value
+//唯一索引: {prefix}0x01{indexName}0x00{indexKey} -> primaryKey
+//普通索引: {prefix}0x01{indexName}0x00{indexKey}{primaryKey}{len(primaryKey)} -> 0x01
+//mapper在不同context中会被复制, IndexedCollection需基于当前mapper的BaseMapper创建, 如:
+//func (m *XMapper) txs() *mapper.IndexedCollection {
+//	return mapper.NewIndexedCollection(m.BaseMapper, []byte("txs/"), newTx, txByHash)
+//}
+type IndexedCollection struct {
+	mapper  *BaseMapper
+	prefix  []byte
+	proto   func() interface{}
+	indexes []Index
+}
+
+//NewIndexedCollection proto返回值类型的指针, 用于解码store中的值
+func NewIndexedCollection(baseMapper *BaseMapper, prefix []byte, proto func() interface{}, indexes ...Index) *IndexedCollection {
+	coll := &IndexedCollection{
+		mapper:  baseMapper,
+		prefix:  prefix,
+		proto:   proto,
+		indexes: make([]Index, 0, len(indexes)),
+	}
+
+	for _, index := range indexes {
+		if len(index.Name) == 0 || bytes.IndexByte([]byte(index.Name), 0x00) >= 0 {
+			panic(fmt.Sprintf("invalid index name: %q", index.Name))
+		}
+		for _, declared := range coll.indexes {
+			if declared.Name == index.Name {
+				panic(fmt.Sprintf("duplicate index: %s", index.Name))
+			}
+		}
+		coll.indexes = append(coll.indexes, index)
+	}
+	return coll
+}
+
+func indexKeys(index Index, value interface{}) (keys [][]byte) {
+	for _, key := range index.IndexKeys(value) {
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+func (coll *IndexedCollection) dataKey(pk []byte) []byte {
+	return concat(coll.prefix, collectionDataPrefix, pk)
+}
+
+func (coll *IndexedCollection) indexPrefix(name string) []byte {
+	return concat(coll.prefix, collectionIndexPrefix, []byte(name), []byte{0x00})
+}
+
+func (coll *IndexedCollection) index(name string) Index {
+	for _, index := range coll.indexes {
+		if index.Name == name {
+			return index
+		}
+	}
+	panic(fmt.Sprintf("unknown index: %s", name))
+}
+
+//indexEntry 值在索引中的key. 普通索引的key以主键及主键长度结尾
+func (coll *IndexedCollection) indexEntry(index Index, indexKey, pk []byte) []byte {
+	if index.Unique {
+		return concat(coll.indexPrefix(index.Name), indexKey)
+	}
+	return concat(coll.indexPrefix(index.Name), indexKey, pk, []byte{byte(len(pk))})
+}
+
+//splitIndexEntry 从普通索引的key中解析索引key及主键
+func splitIndexEntry(key []byte) (indexKey, pk []byte) {
+	pkLen := int(key[len(key)-1])
+	return key[:len(key)-1-pkLen], key[len(key)-1-pkLen : len(key)-1]
+}
+
+func (coll *IndexedCollection) decode(bz []byte) interface{} {
+	value := coll.proto()
+	if reflect.ValueOf(value).Kind() != reflect.Ptr {
+		panic("proto of IndexedCollection must return a pointer")
+	}
+	coll.mapper.DecodeObject(bz, value)
+	return value
+}
+
+//Get 按主键查询, value为proto返回的类型
+func (coll *IndexedCollection) Get(pk []byte) (value interface{}, exists bool) {
+	bz := coll.mapper.GetStore().Get(coll.dataKey(pk))
+	if bz == nil {
+		return nil, false
+	}
+	return coll.decode(bz), true
+}
+
+func (coll *IndexedCollection) Has(pk []byte) bool {
+	return coll.mapper.GetStore().Has(coll.dataKey(pk))
+}
+
+//Set 保存主键pk的值并更新索引. value需与proto返回的类型相同
+//唯一索引key已被其他主键使用时返回错误, 不做修改
+func (coll *IndexedCollection) Set(pk []byte, value interface{}) error {
+	if !coll.mapper.isRegistered() {
+		panic("mapper it's not prepared to work. you may forgot to register this mapper")
+	}
+	if len(pk) == 0 || len(pk) > 255 {
+		return fmt.Errorf("invalid primary key length: %d", len(pk))
+	}
+
+	store := coll.mapper.GetStore()
+	for _, index := range coll.indexes {
+		if !index.Unique {
+			continue
+		}
+		for _, indexKey := range indexKeys(index, value) {
+			existing := store.Get(coll.indexEntry(index, indexKey, pk))
+			if existing != nil && !bytes.Equal(existing, pk) {
+				return fmt.Errorf("unique index %s: key %X is used by %X", index.Name, indexKey, existing)
+			}
+		}
+	}
+
+	if old, exists := coll.Get(pk); exists {
+		coll.removeIndexes(pk, old)
+	}
+
+	store.Set(coll.dataKey(pk), coll.mapper.EncodeObject(value))
+	for _, index := range coll.indexes {
+		for _, indexKey := range indexKeys(index, value) {
+			if index.Unique {
+				store.Set(coll.indexEntry(index, indexKey, pk), pk)
+			} else {
+				store.Set(coll.indexEntry(index, indexKey, pk), indexValue)
+			}
+		}
+	}
+	return nil
+}
+
+//Del 删除主键pk的值及其索引, 返回值是否存在
+func (coll *IndexedCollection) Del(pk []byte) bool {
+	if !coll.mapper.isRegistered() {
+		panic("mapper it's not prepared to work. you may forgot to register this mapper")
+	}
+
+	old, exists := coll.Get(pk)
+	if !exists {
+		return false
+	}
+	coll.removeIndexes(pk, old)
+	coll.mapper.GetStore().Delete(coll.dataKey(pk))
+	return true
+}
+
+func (coll *IndexedCollection) removeIndexes(pk []byte, value interface{}) {
+	store := coll.mapper.GetStore()
+	for _, index := range coll.indexes {
+		for _, indexKey := range indexKeys(index, value) {
+			store.Delete(coll.indexEntry(index, indexKey, pk))
+		}
+	}
+}
+
+//Iterate 按主键顺序遍历
+func (coll *IndexedCollection) Iterate(process func(pk []byte, value interface{}) (stop bool)) {
+	prefix := concat(coll.prefix, collectionDataPrefix)
+	coll.mapper.IteratorWithKV(prefix, func(key []byte, value []byte) bool {
+		return process(key[len(prefix):], coll.decode(value))
+	})
+}
+
+//GetByUniqueIndex 按唯一索引查询
+func (coll *IndexedCollection) GetByUniqueIndex(name string, indexKey []byte) (pk []byte, value interface{}, exists bool) {
+	index := coll.index(name)
+	if !index.Unique {
+		panic(fmt.Sprintf("index %s is not unique", name))
+	}
+
+	pk = coll.mapper.GetStore().Get(coll.indexEntry(index, indexKey, nil))
+	if pk == nil {
+		return nil, nil, false
+	}
+	value, exists = coll.Get(pk)
+	return pk, value, exists
+}
+
+//IterateIndex 按主键顺序遍历索引key为indexKey的值
+func (coll *IndexedCollection) IterateIndex(name string, indexKey []byte, process func(pk []byte, value interface{}) (stop bool)) {
+	index := coll.index(name)
+	if index.Unique {
+		if pk, value, exists := coll.GetByUniqueIndex(name, indexKey); exists {
+			process(pk, value)
+		}
+		return
+	}
+
+	prefix := concat(coll.indexPrefix(name), indexKey)
+	coll.iterateIndexEntries(index, prefix, types.PrefixEndBytes(prefix), func(key, pk []byte) bool {
+		//前缀匹配的索引key可能更长
+		if !bytes.Equal(key, indexKey) {
+			return false
+		}
+		value, _ := coll.Get(pk)
+		return process(pk, value)
+	})
+}
+
+//IterateIndexRange 遍历索引key在[start, end)中的值, start或end为nil时不限制, end为空时范围为空
+//按store中索引项的顺序遍历, 索引key定长时(如地址, 大端序高度)即为索引key顺序
+func (coll *IndexedCollection) IterateIndexRange(name string, start, end []byte, process func(indexKey, pk []byte, value interface{}) (stop bool)) {
+	index := coll.index(name)
+	prefix := coll.indexPrefix(name)
+	if end != nil && len(end) == 0 {
+		return
+	}
+
+	iterStart, iterEnd := concat(prefix, start), types.PrefixEndBytes(prefix)
+	if end != nil {
+		if index.Unique {
+			iterEnd = concat(prefix, end)
+		} else if bound := types.PrefixEndBytes(end[:1]); bound != nil {
+			//普通索引的key以主键结尾, 索引key为end前缀的项位于prefix+end之后, 但均以end[0]开头
+			iterEnd = concat(prefix, bound)
+		}
+	}
+
+	coll.iterateIndexEntries(index, iterStart, iterEnd, func(indexKey, pk []byte) bool {
+		//普通索引的key以主键结尾, 需按索引key过滤: 索引key小于start的项可能位于prefix+start之后
+		if bytes.Compare(indexKey, start) < 0 {
+			return false
+		}
+		if end != nil && bytes.Compare(indexKey, end) >= 0 {
+			return false
+		}
+		value, _ := coll.Get(pk)
+		return process(indexKey, pk, value)
+	})
+}
+
+func (coll *IndexedCollection) iterateIndexEntries(index Index, start, end []byte, process func(indexKey, pk []byte) (stop bool)) {
+	prefixLen := len(coll.indexPrefix(index.Name))
+
+	iter := coll.mapper.GetStore().Iterator(start, end)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		key := iter.Key()[prefixLen:]
+
+		var indexKey, pk []byte
+		if index.Unique {
+			indexKey, pk = key, iter.Value()
+		} else {
+			indexKey, pk = splitIndexEntry(key)
+		}
+
+		if process(indexKey, pk) {
+			return
+		}
+	}
+}
+
+func concat(bzs ...[]byte) []byte {
+	var n int
+	for _, bz := range bzs {
+		n += len(bz)
+	}
+	ret := make([]byte, 0, n)
+	for _, bz := range bzs {
+		ret = append(ret, bz...)
+	}
+	return ret
+}
@@ -0,0 +1,138 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type indexedStruct struct {
+	Name  string
+	Owner string
+	Nonce int64
+}
+
+var (
+	byName = NewUniqueIndex("name", func(value interface{}) []byte {
+		return []byte(value.(*indexedStruct).Name)
+	})
+	byOwner = NewMultiIndex("owner", func(value interface{}) []byte {
+		return []byte(value.(*indexedStruct).Owner)
+	})
+)
+
+func newIndexedCollection(baseMapper *BaseMapper) *IndexedCollection {
+	return NewIndexedCollection(baseMapper, []byte("coll/"), func() interface{} {
+		return &indexedStruct{}
+	}, byName, byOwner)
+}
+
+func collectOwner(t *testing.T, coll *IndexedCollection, owner string) (pks []string) {
+	coll.IterateIndex("owner", []byte(owner), func(pk []byte, value interface{}) bool {
+		require.Equal(t, owner, value.(*indexedStruct).Owner)
+		pks = append(pks, string(pk))
+		return false
+	})
+	return
+}
+
+func TestIndexedCollection(t *testing.T) {
+	baseMapper := getMapper()
+	coll := newIndexedCollection(baseMapper)
+
+	require.Nil(t, coll.Set([]byte("1"), &indexedStruct{"a", "alice", 1}))
+	require.Nil(t, coll.Set([]byte("2"), &indexedStruct{"b", "bob", 2}))
+	require.Nil(t, coll.Set([]byte("3"), &indexedStruct{"c", "alice", 3}))
+	require.Nil(t, coll.Set([]byte("4"), &indexedStruct{"d", "alice2", 4}))
+
+	v, exists := coll.Get([]byte("2"))
+	require.True(t, exists)
+	require.Equal(t, &indexedStruct{"b", "bob", 2}, v)
+	_, exists = coll.Get([]byte("5"))
+	require.False(t, exists)
+
+	pk, v, exists := coll.GetByUniqueIndex("name", []byte("c"))
+	require.True(t, exists)
+	require.Equal(t, []byte("3"), pk)
+	require.Equal(t, int64(3), v.(*indexedStruct).Nonce)
+
+	//"alice2"与"alice"前缀相同
+	require.Equal(t, []string{"1", "3"}, collectOwner(t, coll, "alice"))
+	require.Equal(t, []string{"4"}, collectOwner(t, coll, "alice2"))
+
+	//唯一索引冲突
+	require.NotNil(t, coll.Set([]byte("5"), &indexedStruct{"a", "carol", 5}))
+	require.False(t, coll.Has([]byte("5")))
+	require.Nil(t, collectOwner(t, coll, "carol"))
+
+	//更新时删除旧索引
+	require.Nil(t, coll.Set([]byte("1"), &indexedStruct{"e", "bob", 6}))
+	_, _, exists = coll.GetByUniqueIndex("name", []byte("a"))
+	require.False(t, exists)
+	pk, _, exists = coll.GetByUniqueIndex("name", []byte("e"))
+	require.True(t, exists)
+	require.Equal(t, []byte("1"), pk)
+	require.Equal(t, []string{"3"}, collectOwner(t, coll, "alice"))
+	require.Equal(t, []string{"1", "2"}, collectOwner(t, coll, "bob"))
+
+	//保留唯一索引key更新
+	require.Nil(t, coll.Set([]byte("1"), &indexedStruct{"e", "bob", 7}))
+
+	require.True(t, coll.Del([]byte("3")))
+	require.False(t, coll.Del([]byte("3")))
+	_, _, exists = coll.GetByUniqueIndex("name", []byte("c"))
+	require.False(t, exists)
+	require.Nil(t, collectOwner(t, coll, "alice"))
+
+	var pks []string
+	coll.Iterate(func(pk []byte, value interface{}) bool {
+		pks = append(pks, string(pk))
+		return false
+	})
+	require.Equal(t, []string{"1", "2", "4"}, pks)
+
+	require.Panics(t, func() { coll.IterateIndex("unknown", nil, nil) })
+	require.Panics(t, func() { coll.GetByUniqueIndex("owner", []byte("bob")) })
+}
+
+func TestIndexedCollectionRange(t *testing.T) {
+	baseMapper := getMapper()
+	coll := newIndexedCollection(baseMapper)
+
+	require.Nil(t, coll.Set([]byte("1"), &indexedStruct{"a", "b", 1}))
+	require.Nil(t, coll.Set([]byte("2"), &indexedStruct{"b", "a", 2}))
+	require.Nil(t, coll.Set([]byte("3"), &indexedStruct{"c", "c", 3}))
+	require.Nil(t, coll.Set([]byte("4"), &indexedStruct{"d", "b", 4}))
+
+	collect := func(name string, start, end []byte) (keys []string) {
+		coll.IterateIndexRange(name, start, end, func(indexKey, pk []byte, value interface{}) bool {
+			keys = append(keys, string(indexKey)+string(pk))
+			return false
+		})
+		return
+	}
+
+	require.Equal(t, []string{"a1", "b2", "c3", "d4"}, collect("name", nil, nil))
+	require.Equal(t, []string{"b2", "c3"}, collect("name", []byte("b"), []byte("d")))
+	require.Equal(t, []string{"a2", "b1", "b4", "c3"}, collect("owner", nil, nil))
+	require.Equal(t, []string{"a2", "b1", "b4"}, collect("owner", nil, []byte("c")))
+	require.Equal(t, []string{"b1", "b4", "c3"}, collect("owner", []byte("b"), nil))
+	require.Equal(t, []string{"b1", "b4"}, collect("owner", []byte("b"), []byte("b\x00")))
+
+	//索引key小于start, 但索引项位于prefix+start之后
+	require.Nil(t, coll.Set([]byte("z"), &indexedStruct{"e", "a", 5}))
+	require.Equal(t, []string{"b1", "b4", "c3"}, collect("owner", []byte("ab"), nil))
+	require.Equal(t, []string{"a2", "az"}, collect("owner", []byte("a"), []byte("ab")))
+
+	//end为空时范围为空
+	require.Nil(t, collect("name", nil, []byte{}))
+	require.Nil(t, collect("owner", []byte("a"), []byte{}))
+}
+
+func TestIndexedCollectionUnregistered(t *testing.T) {
+	coll := newIndexedCollection(NewBaseMapper(nil, "unregistered"))
+	require.Panics(t, func() { coll.Set([]byte("1"), &indexedStruct{}) })
+	require.Panics(t, func() {
+		NewIndexedCollection(getMapper(), nil, nil, byName, byName)
+	})
+}
@@ -0,0 +1,11 @@
+package qcp
+
+import (
+	go_amino "github.com/tendermint/go-amino"
+)
+
+func RegisterCodec(cdc *go_amino.Codec) {
+	cdc.RegisterConcrete(&TxSetTrustKeys{}, "qbase/qcp/TxSetTrustKeys", nil)
+	cdc.RegisterConcrete(&TxRevokeTrustKey{}, "qbase/qcp/TxRevokeTrustKey", nil)
+	cdc.RegisterConcrete(&TxQcpTimeout{}, "qbase/qcp/TxQcpTimeout", nil)
+}
@@ -0,0 +1,103 @@
+package qcp
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	"github.com/QOSGroup/qbase/collections"
+	"github.com/QOSGroup/qbase/txs"
+)
+
+//OutChainState 输出到chainId的未完成跨链状态, 导出状态时保存在app_state的qcp_out中
+type OutChainState struct {
+	ChainId     string       `json:"chain_id"`
+	AckSequence int64        `json:"ack_sequence,omitempty"` //已确认的最大连续序号
+	Acked       []int64      `json:"acked,omitempty"`        //已确认序号之后乱序收到执行结果的序号
+	Timeouts    []int64      `json:"timeouts,omitempty"`     //已通过超时证明处理的序号
+	Txs         []*txs.TxQcp `json:"txs,omitempty"`          //未删除的qcp tx, 按序号排序
+}
+
+//ExportOutChainStates 导出所有链的未完成跨链输出状态, 按chainId排序
+func ExportOutChainStates(mapper *QcpMapper) []*OutChainState {
+	states := make(map[string]*OutChainState)
+	get := func(chainId string) *OutChainState {
+		if _, ok := states[chainId]; !ok {
+			states[chainId] = &OutChainState{ChainId: chainId}
+		}
+		return states[chainId]
+	}
+
+	mapper.iterateSequences(outAckSequences, func(outChain string, sequence int64) bool {
+		get(outChain).AckSequence = sequence
+		return false
+	})
+	if err := outAckedTxs.Iterate(mapper.GetStore(), nil, func(key collections.Pair[string, int64]) bool {
+		state := get(key.K1())
+		state.Acked = append(state.Acked, key.K2())
+		return false
+	}); err != nil {
+		panic(err)
+	}
+	iterateChainSequenceKeys(mapper, []byte(outTimeoutPrefixKey), func(outChain string, sequence int64, value []byte) {
+		state := get(outChain)
+		state.Timeouts = append(state.Timeouts, sequence)
+	})
+	mapper.IteratorWithKV([]byte(outSequenceTxPrefixKey), func(key []byte, value []byte) bool {
+		outChain, _, ok := parseOutSequenceTxKey(key)
+		if !ok {
+			return false
+		}
+		var txQcp txs.TxQcp
+		mapper.DecodeObject(value, &txQcp)
+		state := get(outChain)
+		state.Txs = append(state.Txs, &txQcp)
+		return false
+	})
+
+	result := make([]*OutChainState, 0, len(states))
+	for _, state := range states {
+		sort.Slice(state.Acked, func(i, j int) bool { return state.Acked[i] < state.Acked[j] })
+		sort.Slice(state.Timeouts, func(i, j int) bool { return state.Timeouts[i] < state.Timeouts[j] })
+		sort.Slice(state.Txs, func(i, j int) bool { return state.Txs[i].Sequence < state.Txs[j].Sequence })
+		result = append(result, state)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ChainId < result[j].ChainId })
+
+	return result
+}
+
+//ImportOutChainState 导入ExportOutChainStates导出的跨链输出状态, 在InitChain中调用
+func ImportOutChainState(mapper *QcpMapper, state *OutChainState) {
+	if state.AckSequence > 0 {
+		mapper.SetChainOutAckSequence(state.ChainId, state.AckSequence)
+	}
+	for _, sequence := range state.Acked {
+		if err := outAckedTxs.Set(mapper.GetStore(), collections.Join(state.ChainId, sequence)); err != nil {
+			panic(err)
+		}
+	}
+	for _, sequence := range state.Timeouts {
+		mapper.SetChainOutTxTimeout(state.ChainId, sequence)
+	}
+	for _, txQcp := range state.Txs {
+		mapper.SetChainOutTxs(state.ChainId, txQcp.Sequence, txQcp)
+	}
+}
+
+//iterateChainSequenceKeys 遍历{prefix}{chainId}/{十进制sequence}格式的key
+func iterateChainSequenceKeys(mapper *QcpMapper, prefix []byte, process func(chain string, sequence int64, value []byte)) {
+	mapper.IteratorWithKV(prefix, func(key []byte, value []byte) bool {
+		key = key[len(prefix):]
+		i := bytes.LastIndexByte(key, '/')
+		if i < 0 {
+			return false
+		}
+		sequence, err := strconv.ParseInt(string(key[i+1:]), 10, 64)
+		if err != nil {
+			return false
+		}
+		process(string(key[:i]), sequence, value)
+		return false
+	})
+}
@@ -0,0 +1,47 @@
+package qcp
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportOutChainStates(t *testing.T) {
+	_, qcpMapper := newTestQcpContext()
+
+	for i := 1; i <= 12; i++ {
+		qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "qos", To: "qsc", IsResult: i == 2}, nil)
+	}
+	qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "qos", To: "q/sc"}, nil)
+
+	//1, 2已确认, 4乱序确认, 11超时
+	qcpMapper.AckChainOutTx("qsc", 1)
+	qcpMapper.AckChainOutTx("qsc", 4)
+	qcpMapper.SetChainOutTxTimeout("qsc", 11)
+
+	states := ExportOutChainStates(qcpMapper)
+	require.Len(t, states, 2)
+	require.Equal(t, "q/sc", states[0].ChainId)
+	require.Len(t, states[0].Txs, 1)
+
+	state := states[1]
+	require.Equal(t, "qsc", state.ChainId)
+	require.Equal(t, int64(2), state.AckSequence)
+	require.Equal(t, []int64{4}, state.Acked)
+	require.Equal(t, []int64{11}, state.Timeouts)
+	require.Len(t, state.Txs, 9)
+	require.Equal(t, int64(3), state.Txs[0].Sequence)
+	require.Equal(t, int64(12), state.Txs[8].Sequence)
+
+	_, imported := newTestQcpContext()
+	imported.SetMaxChainOutSequence("qsc", 12)
+	for _, state := range states {
+		ImportOutChainState(imported, state)
+	}
+	require.Equal(t, states, ExportOutChainStates(imported))
+
+	//导入的乱序确认在已确认序号推进时生效
+	imported.AckChainOutTx("qsc", 3)
+	require.Equal(t, int64(4), imported.GetChainOutAckSequence("qsc"))
+}
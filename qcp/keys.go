@@ -0,0 +1,336 @@
+package qcp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/multisig"
+)
+
+//信任公钥管理:
+//来源链的信任公钥保存在pubkey/in/[chainId]下, 多个公钥时保存为M-of-N多签公钥, TxQcp需由其中M个公钥签名
+//信任公钥可由管理员账户或当前信任公钥授权的TxSetTrustKeys, TxRevokeTrustKey更新
+const (
+	//信任公钥管理员账户
+	adminKey = "admin"
+	//来源链"chainId"信任公钥更新次数, 用于防止授权签名重放
+	inKeyNoncePrefixKey = "keynonce/in/"
+	inKeyNonceKey       = inKeyNoncePrefixKey + "%s"
+)
+
+func BuildAdminKey() []byte {
+	return []byte(adminKey)
+}
+
+func BuildInKeyNonceKey(inChainID string) []byte {
+	return []byte(fmt.Sprintf(inKeyNonceKey, inChainID))
+}
+
+func (mapper *QcpMapper) GetAdmin() (admin types.AccAddress) {
+	mapper.Get(BuildAdminKey(), &admin)
+	return
+}
+
+func (mapper *QcpMapper) SetAdmin(admin types.AccAddress) {
+	mapper.Set(BuildAdminKey(), admin)
+}
+
+func (mapper *QcpMapper) GetChainInKeyNonce(inChain string) (nonce int64) {
+	mapper.Get(BuildInKeyNonceKey(inChain), &nonce)
+	return
+}
+
+func (mapper *QcpMapper) SetChainInKeyNonce(inChain string, nonce int64) {
+	mapper.Set(BuildInKeyNonceKey(inChain), nonce)
+}
+
+//GetChainInTrustPubKeys 返回来源链的信任公钥集合及签名门限
+func (mapper *QcpMapper) GetChainInTrustPubKeys(inChain string) (pubkeys []crypto.PubKey, threshold int) {
+	return SplitTrustPubKey(mapper.GetChainInTrustPubKey(inChain))
+}
+
+//SetChainInTrustPubKeys 设置来源链的信任公钥集合, TxQcp需由其中threshold个公钥签名
+func (mapper *QcpMapper) SetChainInTrustPubKeys(inChain string, pubkeys []crypto.PubKey, threshold int) {
+	mapper.SetChainInTrustPubKey(inChain, NewTrustPubKey(pubkeys, threshold))
+}
+
+//DelChainInTrustPubKey 删除来源链的信任公钥, 之后不再接受来自该链的TxQcp
+func (mapper *QcpMapper) DelChainInTrustPubKey(inChain string) {
+	mapper.Del(BuildInPubkeyKey(inChain))
+}
+
+//NewTrustPubKey 单个公钥且门限为1时返回该公钥, 否则返回M-of-N多签公钥
+func NewTrustPubKey(pubkeys []crypto.PubKey, threshold int) crypto.PubKey {
+	if len(pubkeys) == 1 && threshold == 1 {
+		return pubkeys[0]
+	}
+	return multisig.NewPubKeyMultisigThreshold(threshold, pubkeys)
+}
+
+//SplitTrustPubKey 返回信任公钥包含的公钥集合及签名门限
+func SplitTrustPubKey(pubkey crypto.PubKey) (pubkeys []crypto.PubKey, threshold int) {
+	switch pk := pubkey.(type) {
+	case nil:
+		return nil, 0
+	case multisig.PubKeyMultisigThreshold:
+		return pk.PubKeys, int(pk.K)
+	default:
+		return []crypto.PubKey{pk}, 1
+	}
+}
+
+//TrustKeysAuth 信任公钥更新授权, 二选一:
+//Admin: 管理员账户授权, 需由管理员签名TxStd
+//Signature: 当前信任公钥授权, 为当前信任公钥(M-of-N时为多签)对AuthSignData的签名
+type TrustKeysAuth struct {
+	Admin     types.AccAddress `json:"admin"`
+	Signature []byte           `json:"signature"`
+}
+
+func (auth TrustKeysAuth) validate() error {
+	if (len(auth.Admin) == 0) == (len(auth.Signature) == 0) {
+		return errors.New("exactly one of admin and signature is required")
+	}
+	return nil
+}
+
+func (auth TrustKeysAuth) signers() []types.AccAddress {
+	if len(auth.Admin) == 0 {
+		return nil
+	}
+	return []types.AccAddress{auth.Admin}
+}
+
+func (auth TrustKeysAuth) signData() []byte {
+	ret := append([]byte{}, auth.Admin.Bytes()...)
+	return append(ret, auth.Signature...)
+}
+
+//authorize 校验授权, 通过后增加来源链信任公钥更新次数
+func (auth TrustKeysAuth) authorize(ctx context.Context, mapper *QcpMapper, inChain string, authSignData []byte) error {
+	if len(auth.Admin) > 0 {
+		if admin := mapper.GetAdmin(); len(admin) == 0 || !admin.Equals(auth.Admin) {
+			return fmt.Errorf("%s is not qcp admin", auth.Admin)
+		}
+		if !isSigner(ctx, auth.Admin) {
+			return fmt.Errorf("qcp admin %s signature not found", auth.Admin)
+		}
+	} else {
+		trustPubkey := mapper.GetChainInTrustPubKey(inChain)
+		if trustPubkey == nil {
+			return fmt.Errorf("chain: %s trust pubkey not found", inChain)
+		}
+		if !trustPubkey.VerifyBytes(authSignData, auth.Signature) {
+			return errors.New("trust pubkey signature verification failed")
+		}
+	}
+
+	mapper.SetChainInKeyNonce(inChain, mapper.GetChainInKeyNonce(inChain)+1)
+	return nil
+}
+
+//isSigner addr是否为已通过签名校验的TxStd签名者
+func isSigner(ctx context.Context, addr types.AccAddress) bool {
+	signers, _ := ctx.Value(context.ContextKeySigners).([]account.Account)
+	for _, acc := range signers {
+		if acc.GetAddress().Equals(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+//buildAuthSignData 信任公钥授权签名数据: 当前链chainId, 来源链chainId, 更新次数及更新内容
+func buildAuthSignData(ctx context.Context, mapper *QcpMapper, inChain string, data []byte) []byte {
+	ret := []byte(ctx.ChainID())
+	ret = append(ret, []byte(inChain)...)
+	ret = append(ret, types.Int2Byte(mapper.GetChainInKeyNonce(inChain)+1)...)
+	return append(ret, data...)
+}
+
+func getQcpMapper(ctx context.Context) (*QcpMapper, error) {
+	mapper, ok := ctx.Mapper(MapperName).(*QcpMapper)
+	if !ok {
+		return nil, errors.New("qcp mapper not found")
+	}
+	return mapper, nil
+}
+
+//TxSetTrustKeys 新增来源链或轮换来源链的信任公钥集合
+//新增来源链时只能由管理员授权
+type TxSetTrustKeys struct {
+	ChainID   string          `json:"chain_id"`  //来源链chainId
+	PubKeys   []crypto.PubKey `json:"pub_keys"`  //信任公钥集合
+	Threshold int             `json:"threshold"` //签名门限
+	Auth      TrustKeysAuth   `json:"auth"`
+}
+
+var _ txs.ITx = (*TxSetTrustKeys)(nil)
+
+func (tx *TxSetTrustKeys) ValidateData(ctx context.Context) error {
+	if tx.ChainID == "" {
+		return errors.New("chain id is empty")
+	}
+	if len(tx.PubKeys) == 0 {
+		return errors.New("pub keys is empty")
+	}
+	if tx.Threshold <= 0 || tx.Threshold > len(tx.PubKeys) {
+		return fmt.Errorf("invalid threshold %d, expect 1 to %d", tx.Threshold, len(tx.PubKeys))
+	}
+
+	seen := make(map[string]bool, len(tx.PubKeys))
+	for _, pubkey := range tx.PubKeys {
+		if pubkey == nil {
+			return errors.New("pub key is nil")
+		}
+		if seen[string(pubkey.Bytes())] {
+			return fmt.Errorf("duplicate pub key %X", pubkey.Bytes())
+		}
+		seen[string(pubkey.Bytes())] = true
+	}
+
+	return tx.Auth.validate()
+}
+
+func (tx *TxSetTrustKeys) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper, err := getQcpMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	if err := tx.Auth.authorize(ctx, mapper, tx.ChainID, tx.AuthSignData(ctx)); err != nil {
+		return types.ErrUnauthorized(err.Error()).Result(), nil
+	}
+
+	mapper.SetChainInTrustPubKeys(tx.ChainID, tx.PubKeys, tx.Threshold)
+	return
+}
+
+//AuthSignData 当前信任公钥授权时需签名的数据
+func (tx *TxSetTrustKeys) AuthSignData(ctx context.Context) []byte {
+	mapper, err := getQcpMapper(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var data []byte
+	for _, pubkey := range tx.PubKeys {
+		data = append(data, pubkey.Bytes()...)
+	}
+	data = append(data, types.Int2Byte(int64(tx.Threshold))...)
+	return buildAuthSignData(ctx, mapper, tx.ChainID, data)
+}
+
+func (tx *TxSetTrustKeys) GetSigner() []types.AccAddress {
+	return tx.Auth.signers()
+}
+
+func (tx *TxSetTrustKeys) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxSetTrustKeys) GetGasPayer() types.AccAddress {
+	return tx.Auth.Admin
+}
+
+func (tx *TxSetTrustKeys) GetSignData() []byte {
+	ret := []byte(tx.ChainID)
+	for _, pubkey := range tx.PubKeys {
+		ret = append(ret, pubkey.Bytes()...)
+	}
+	ret = append(ret, types.Int2Byte(int64(tx.Threshold))...)
+	return append(ret, tx.Auth.signData()...)
+}
+
+//TxRevokeTrustKey 移除来源链的一个信任公钥, 并设置剩余公钥的签名门限
+//移除最后一个公钥后不再接受来自该链的TxQcp
+type TxRevokeTrustKey struct {
+	ChainID   string        `json:"chain_id"`  //来源链chainId
+	PubKey    crypto.PubKey `json:"pub_key"`   //移除的公钥
+	Threshold int           `json:"threshold"` //剩余公钥的签名门限
+	Auth      TrustKeysAuth `json:"auth"`
+}
+
+var _ txs.ITx = (*TxRevokeTrustKey)(nil)
+
+func (tx *TxRevokeTrustKey) ValidateData(ctx context.Context) error {
+	if tx.ChainID == "" {
+		return errors.New("chain id is empty")
+	}
+	if tx.PubKey == nil {
+		return errors.New("pub key is nil")
+	}
+	if tx.Threshold < 0 {
+		return fmt.Errorf("invalid threshold %d", tx.Threshold)
+	}
+
+	return tx.Auth.validate()
+}
+
+func (tx *TxRevokeTrustKey) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper, err := getQcpMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	pubkeys, _ := mapper.GetChainInTrustPubKeys(tx.ChainID)
+	remain := make([]crypto.PubKey, 0, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		if !pubkey.Equals(tx.PubKey) {
+			remain = append(remain, pubkey)
+		}
+	}
+	if len(remain) == len(pubkeys) {
+		return types.ErrInvalidPubKey(fmt.Sprintf("%X is not trust pub key of chain: %s", tx.PubKey.Bytes(), tx.ChainID)).Result(), nil
+	}
+	if (len(remain) == 0 && tx.Threshold != 0) || (len(remain) > 0 && (tx.Threshold <= 0 || tx.Threshold > len(remain))) {
+		return types.ErrInternal(fmt.Sprintf("invalid threshold %d, expect 1 to %d", tx.Threshold, len(remain))).Result(), nil
+	}
+
+	if err := tx.Auth.authorize(ctx, mapper, tx.ChainID, tx.AuthSignData(ctx)); err != nil {
+		return types.ErrUnauthorized(err.Error()).Result(), nil
+	}
+
+	if len(remain) == 0 {
+		mapper.DelChainInTrustPubKey(tx.ChainID)
+	} else {
+		mapper.SetChainInTrustPubKeys(tx.ChainID, remain, tx.Threshold)
+	}
+	return
+}
+
+//AuthSignData 当前信任公钥授权时需签名的数据
+func (tx *TxRevokeTrustKey) AuthSignData(ctx context.Context) []byte {
+	mapper, err := getQcpMapper(ctx)
+	if err != nil {
+		return nil
+	}
+
+	data := append([]byte("revoke"), tx.PubKey.Bytes()...)
+	data = append(data, types.Int2Byte(int64(tx.Threshold))...)
+	return buildAuthSignData(ctx, mapper, tx.ChainID, data)
+}
+
+func (tx *TxRevokeTrustKey) GetSigner() []types.AccAddress {
+	return tx.Auth.signers()
+}
+
+func (tx *TxRevokeTrustKey) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxRevokeTrustKey) GetGasPayer() types.AccAddress {
+	return tx.Auth.Admin
+}
+
+func (tx *TxRevokeTrustKey) GetSignData() []byte {
+	ret := []byte(tx.ChainID)
+	ret = append(ret, tx.PubKey.Bytes()...)
+	ret = append(ret, types.Int2Byte(int64(tx.Threshold))...)
+	return append(ret, tx.Auth.signData()...)
+}
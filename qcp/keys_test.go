@@ -0,0 +1,115 @@
+package qcp
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/account"
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/multisig"
+)
+
+func newTestQcpContext() (context.Context, *QcpMapper) {
+	cdc := defaultCdc()
+	qcpMapper := NewQcpMapper(cdc)
+	qcpMapper.SetCodec(cdc)
+	mappers := map[string]mapper.IMapper{qcpMapper.MapperName(): qcpMapper}
+	ctx := defaultContext(qcpMapper.GetStoreKey(), mappers).WithChainID("qos")
+	return ctx, ctx.Mapper(MapperName).(*QcpMapper)
+}
+
+func multiSign(t *testing.T, data []byte, pubkeys []crypto.PubKey, signers ...crypto.PrivKey) []byte {
+	mSig := multisig.NewMultisig(len(pubkeys))
+	for _, signer := range signers {
+		sig, err := signer.Sign(data)
+		require.Nil(t, err)
+		require.Nil(t, mSig.AddSignatureFromPubKey(sig, signer.PubKey(), pubkeys))
+	}
+	return defaultCdc().MustMarshalBinaryBare(mSig)
+}
+
+func Test_TrustKeys(t *testing.T) {
+	ctx, qcpMapper := newTestQcpContext()
+
+	admin := types.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+	qcpMapper.SetAdmin(admin)
+	adminAcc := account.ProtoBaseAccount()
+	adminAcc.SetAddress(admin)
+	adminCtx := ctx.WithValue(context.ContextKeySigners, []account.Account{adminAcc})
+
+	key := ed25519.GenPrivKey()
+
+	//新增来源链只能由管理员授权
+	addTx := &TxSetTrustKeys{ChainID: "qsc", PubKeys: []crypto.PubKey{key.PubKey()}, Threshold: 1, Auth: TrustKeysAuth{Admin: admin}}
+	require.Nil(t, addTx.ValidateData(ctx))
+	result, _ := addTx.Exec(ctx)
+	require.False(t, result.IsOK())
+
+	result, _ = addTx.Exec(adminCtx)
+	require.True(t, result.IsOK())
+	require.Equal(t, key.PubKey(), qcpMapper.GetChainInTrustPubKey("qsc"))
+	require.Equal(t, int64(1), qcpMapper.GetChainInKeyNonce("qsc"))
+
+	other := types.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+	addTx.Auth.Admin = other
+	result, _ = addTx.Exec(adminCtx)
+	require.False(t, result.IsOK())
+
+	//当前信任公钥授权轮换为2-of-3
+	keys := []crypto.PrivKey{ed25519.GenPrivKey(), ed25519.GenPrivKey(), ed25519.GenPrivKey()}
+	pubkeys := []crypto.PubKey{keys[0].PubKey(), keys[1].PubKey(), keys[2].PubKey()}
+	rotateTx := &TxSetTrustKeys{ChainID: "qsc", PubKeys: pubkeys, Threshold: 2}
+	require.NotNil(t, rotateTx.ValidateData(ctx))
+	sig, err := key.Sign(rotateTx.AuthSignData(ctx))
+	require.Nil(t, err)
+	rotateTx.Auth.Signature = sig
+	require.Nil(t, rotateTx.ValidateData(ctx))
+	result, _ = rotateTx.Exec(ctx)
+	require.True(t, result.IsOK())
+
+	trustPubKeys, threshold := qcpMapper.GetChainInTrustPubKeys("qsc")
+	require.Equal(t, pubkeys, trustPubKeys)
+	require.Equal(t, 2, threshold)
+
+	//授权签名不可重放
+	result, _ = rotateTx.Exec(ctx)
+	require.False(t, result.IsOK())
+
+	//TxQcp需由2个信任公钥签名
+	data := []byte("txqcp")
+	trustPubKey := qcpMapper.GetChainInTrustPubKey("qsc")
+	require.False(t, trustPubKey.VerifyBytes(data, multiSign(t, data, pubkeys, keys[0])))
+	require.True(t, trustPubKey.VerifyBytes(data, multiSign(t, data, pubkeys, keys[0], keys[2])))
+
+	//2-of-3多签授权移除公钥
+	revokeTx := &TxRevokeTrustKey{ChainID: "qsc", PubKey: pubkeys[1], Threshold: 1}
+	revokeTx.Auth.Signature = multiSign(t, revokeTx.AuthSignData(ctx), pubkeys, keys[1])
+	result, _ = revokeTx.Exec(ctx)
+	require.False(t, result.IsOK())
+
+	revokeTx.Auth.Signature = multiSign(t, revokeTx.AuthSignData(ctx), pubkeys, keys[0], keys[1])
+	result, _ = revokeTx.Exec(ctx)
+	require.True(t, result.IsOK())
+	trustPubKeys, threshold = qcpMapper.GetChainInTrustPubKeys("qsc")
+	require.Equal(t, []crypto.PubKey{pubkeys[0], pubkeys[2]}, trustPubKeys)
+	require.Equal(t, 1, threshold)
+
+	//门限超过剩余公钥数量
+	revokeTx = &TxRevokeTrustKey{ChainID: "qsc", PubKey: pubkeys[0], Threshold: 2, Auth: TrustKeysAuth{Admin: admin}}
+	result, _ = revokeTx.Exec(adminCtx)
+	require.False(t, result.IsOK())
+
+	//管理员移除全部公钥
+	revokeTx.Threshold = 1
+	result, _ = revokeTx.Exec(adminCtx)
+	require.True(t, result.IsOK())
+	revokeTx = &TxRevokeTrustKey{ChainID: "qsc", PubKey: pubkeys[2], Threshold: 0, Auth: TrustKeysAuth{Admin: admin}}
+	result, _ = revokeTx.Exec(adminCtx)
+	require.True(t, result.IsOK())
+	require.Nil(t, qcpMapper.GetChainInTrustPubKey("qsc"))
+	require.Equal(t, int64(5), qcpMapper.GetChainInKeyNonce("qsc"))
+}
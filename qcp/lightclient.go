@@ -0,0 +1,174 @@
+package qcp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/store/rootmulti"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+//轻客户端验证模式:
+//接收链保存来源链可信的验证人集合, 以及已验证的区块头hash.
+//TxQcp携带来源链区块头及merkle证明, 证明其保存在来源链tx/out/[接收链]/[sequence]下, 中继无需被信任
+const (
+	//来源链"chainId"可信的验证人集合
+	inValidatorsPrefixKey = "validators/in/"
+	inValidatorsKey       = inValidatorsPrefixKey + "%s"
+	//来源链"chainId"已验证的区块头hash
+	inHeaderPrefixKey = "header/in/"
+	inHeaderKey       = inHeaderPrefixKey + "%s/%d"
+	//来源链"chainId"已验证的区块头最大高度
+	inHeaderHeightPrefixKey = "height/in/"
+	inHeaderHeightKey       = inHeaderHeightPrefixKey + "%s"
+)
+
+func BuildInValidatorsKey(inChainID string) []byte {
+	return []byte(fmt.Sprintf(inValidatorsKey, inChainID))
+}
+
+func BuildInValidatorsPrefixKey() []byte {
+	return []byte(inValidatorsPrefixKey)
+}
+
+func BuildInHeaderKey(inChainID string, height int64) []byte {
+	return []byte(fmt.Sprintf(inHeaderKey, inChainID, height))
+}
+
+func BuildInHeaderHeightKey(inChainID string) []byte {
+	return []byte(fmt.Sprintf(inHeaderHeightKey, inChainID))
+}
+
+//BuildOutTxKeyPath 返回tx/out/[outChain]/[sequence]在来源链multistore中的merkle key path
+func BuildOutTxKeyPath(outChain string, sequence int64) string {
+	return merkle.KeyPath{}.
+		AppendKey([]byte(MapperName), merkle.KeyEncodingURL).
+		AppendKey(BuildOutSequenceTxKey(outChain, sequence), merkle.KeyEncodingURL).
+		String()
+}
+
+//GetChainInValidators 返回来源链可信的验证人集合, 未设置时来源链不使用轻客户端验证模式
+func (mapper *QcpMapper) GetChainInValidators(inChain string) *tmtypes.ValidatorSet {
+	var valSet tmtypes.ValidatorSet
+	if exists := mapper.Get(BuildInValidatorsKey(inChain), &valSet); !exists {
+		return nil
+	}
+	return &valSet
+}
+
+func (mapper *QcpMapper) SetChainInValidators(inChain string, valSet *tmtypes.ValidatorSet) {
+	mapper.Set(BuildInValidatorsKey(inChain), *valSet)
+}
+
+func (mapper *QcpMapper) GetChainInHeaderHash(inChain string, height int64) (hash []byte) {
+	mapper.Get(BuildInHeaderKey(inChain, height), &hash)
+	return
+}
+
+func (mapper *QcpMapper) SetChainInHeaderHash(inChain string, height int64, hash []byte) {
+	mapper.Set(BuildInHeaderKey(inChain, height), hash)
+}
+
+func (mapper *QcpMapper) GetMaxChainInHeaderHeight(inChain string) (height int64) {
+	mapper.Get(BuildInHeaderHeightKey(inChain), &height)
+	return
+}
+
+func (mapper *QcpMapper) SetMaxChainInHeaderHeight(inChain string, height int64) {
+	mapper.Set(BuildInHeaderHeightKey(inChain), height)
+}
+
+//IterateChainInValidators 遍历所有链的可信验证人集合
+func (mapper *QcpMapper) IterateChainInValidators(process func(inChain string, valSet *tmtypes.ValidatorSet) (stop bool)) {
+	prefix := BuildInValidatorsPrefixKey()
+	mapper.IteratorWithKV(prefix, func(key []byte, value []byte) bool {
+		var valSet tmtypes.ValidatorSet
+		mapper.DecodeObject(value, &valSet)
+		return process(string(key[len(prefix):]), &valSet)
+	})
+}
+
+//VerifyTxQcpProof 轻客户端模式下校验txQcp:
+//1. 区块头由可信验证人集合签名, 验证人集合变更时需新旧验证人均超过2/3签名
+//2. Value在区块头AppHash下的merkle证明合法
+//3. Value与txQcp除Sig及Proof外的字段一致
+func (mapper *QcpMapper) VerifyTxQcpProof(txQcp *txs.TxQcp) error {
+	proof := txQcp.Proof
+	if proof == nil {
+		return errors.New("txqcp's proof is nil")
+	}
+	if proof.Proof == nil || len(proof.Value) == 0 {
+		return errors.New("txqcp's merkle proof or value is empty")
+	}
+
+	if err := mapper.verifyHeader(txQcp.From, proof); err != nil {
+		return err
+	}
+
+	keyPath := BuildOutTxKeyPath(txQcp.To, txQcp.Sequence)
+	err := rootmulti.DefaultProofRuntime().VerifyValue(proof.Proof, proof.Header.AppHash, keyPath, proof.Value)
+	if err != nil {
+		return fmt.Errorf("verify merkle proof error: %v", err)
+	}
+
+	var srcTxQcp txs.TxQcp
+	if err := mapper.GetCodec().UnmarshalBinaryBare(proof.Value, &srcTxQcp); err != nil {
+		return fmt.Errorf("decode proof value error: %v", err)
+	}
+	//签名数据不是唯一编码, 比较不含Sig及Proof的完整编码
+	if !bytes.Equal(mapper.encodeUnsignedTxQcp(srcTxQcp), mapper.encodeUnsignedTxQcp(*txQcp)) {
+		return errors.New("txqcp does not match proof value")
+	}
+
+	return nil
+}
+
+func (mapper *QcpMapper) encodeUnsignedTxQcp(txQcp txs.TxQcp) []byte {
+	txQcp.Sig = txs.Signature{}
+	txQcp.Proof = nil
+	return mapper.EncodeObject(txQcp)
+}
+
+func (mapper *QcpMapper) verifyHeader(inChain string, proof *txs.TxQcpProof) error {
+	header := proof.Header
+	if err := header.ValidateBasic(inChain); err != nil {
+		return err
+	}
+
+	//已验证过的区块头
+	hash := header.Hash()
+	if bytes.Equal(mapper.GetChainInHeaderHash(inChain, header.Height), hash) {
+		return nil
+	}
+
+	trusted := mapper.GetChainInValidators(inChain)
+	if trusted == nil {
+		return fmt.Errorf("chain: %s trust validators not found", inChain)
+	}
+	valSet := proof.Validators
+	if valSet == nil || !bytes.Equal(valSet.Hash(), header.ValidatorsHash) {
+		return errors.New("validators not match header's validators hash")
+	}
+
+	var err error
+	if bytes.Equal(trusted.Hash(), header.ValidatorsHash) {
+		err = trusted.VerifyCommit(inChain, header.Commit.BlockID, header.Height, header.Commit)
+	} else {
+		err = trusted.VerifyFutureCommit(valSet, inChain, header.Commit.BlockID, header.Height, header.Commit)
+	}
+	if err != nil {
+		return fmt.Errorf("verify header commit error: %v", err)
+	}
+
+	mapper.SetChainInHeaderHash(inChain, header.Height, hash)
+	//仅使用更高区块头的验证人集合更新可信验证人集合
+	if header.Height > mapper.GetMaxChainInHeaderHeight(inChain) {
+		mapper.SetMaxChainInHeaderHeight(inChain, header.Height)
+		mapper.SetChainInValidators(inChain, valSet)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package qcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func newTestQcpMapper() *QcpMapper {
+	cdc := defaultCdc()
+	qcpMapper := NewQcpMapper(cdc)
+	qcpMapper.SetCodec(cdc)
+	mappers := map[string]mapper.IMapper{qcpMapper.MapperName(): qcpMapper}
+	ctx := defaultContext(qcpMapper.GetStoreKey(), mappers)
+	return ctx.Mapper(qcpMapper.MapperName()).(*QcpMapper)
+}
+
+func newTestValidators(n int, power int64) ([]tmtypes.PrivValidator, *tmtypes.ValidatorSet) {
+	privVals := make([]tmtypes.PrivValidator, 0, n)
+	vals := make([]*tmtypes.Validator, 0, n)
+	for i := 0; i < n; i++ {
+		pv := tmtypes.NewMockPV()
+		privVals = append(privVals, pv)
+		vals = append(vals, tmtypes.NewValidator(pv.GetPubKey(), power))
+	}
+	return privVals, tmtypes.NewValidatorSet(vals)
+}
+
+//signHeader 构造AppHash为appHash的区块头, 并由privVals中属于valSet的验证人签名
+func signHeader(t *testing.T, chainID string, height int64, appHash []byte, valSet *tmtypes.ValidatorSet, privVals []tmtypes.PrivValidator) tmtypes.SignedHeader {
+	header := &tmtypes.Header{
+		ChainID:            chainID,
+		Height:             height,
+		AppHash:            appHash,
+		ValidatorsHash:     valSet.Hash(),
+		NextValidatorsHash: valSet.Hash(),
+	}
+	blockID := tmtypes.BlockID{Hash: header.Hash()}
+
+	//按验证人集合中的顺序签名
+	signers := make([]tmtypes.PrivValidator, 0, valSet.Size())
+	for _, val := range valSet.Validators {
+		for _, pv := range privVals {
+			if bytes.Equal(pv.GetPubKey().Address(), val.Address) {
+				signers = append(signers, pv)
+			}
+		}
+	}
+	require.Equal(t, valSet.Size(), len(signers))
+
+	voteSet := tmtypes.NewVoteSet(chainID, height, 0, tmtypes.PrecommitType, valSet)
+	commit, err := tmtypes.MakeCommit(blockID, height, 0, voteSet, signers)
+	require.Nil(t, err)
+
+	return tmtypes.SignedHeader{Header: header, Commit: commit}
+}
+
+func Test_VerifyTxQcpProof(t *testing.T) {
+	srcChain, dstChain := "qsc", "qos"
+
+	//来源链保存输出至dstChain的tx并commit
+	cdc := defaultCdc()
+	srcMapper := NewQcpMapper(cdc)
+	srcMapper.SetCodec(cdc)
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(srcMapper.GetStoreKey(), types.StoreTypeIAVL, db)
+	require.Nil(t, cms.LoadLatestVersion())
+	ctx := context.NewContext(cms, abci.Header{}, false, log.NewNopLogger(), map[string]mapper.IMapper{srcMapper.MapperName(): srcMapper})
+	srcMapper = ctx.Mapper(MapperName).(*QcpMapper)
+
+	itx := txs.NewQcpTxResult(types.Result{}, 1, "", "")
+	txQcp := txs.NewTxQCP(txs.NewTxStd(itx, dstChain, types.NewInt(100)), srcChain, dstChain, 0, 1, 0, true, "")
+	txQcp = srcMapper.SignAndSaveTxQcp(txQcp, nil)
+	cid := cms.Commit()
+
+	key := BuildOutSequenceTxKey(dstChain, txQcp.Sequence)
+	res := cms.(store.Queryable).Query(abci.RequestQuery{Path: "/" + MapperName + "/key", Data: key, Height: cid.Version, Prove: true})
+	require.Equal(t, uint32(0), res.Code)
+	require.NotNil(t, res.Proof)
+
+	privVals, valSet := newTestValidators(3, 10)
+	proof := &txs.TxQcpProof{
+		Header:     signHeader(t, srcChain, cid.Version+1, cid.Hash, valSet, privVals),
+		Validators: valSet,
+		Value:      res.Value,
+		Proof:      res.Proof,
+	}
+
+	//接收链未设置可信验证人集合
+	dstMapper := newTestQcpMapper()
+	relayed := *txQcp
+	relayed.Proof = proof
+	require.NotNil(t, dstMapper.VerifyTxQcpProof(&relayed))
+
+	dstMapper.SetChainInValidators(srcChain, valSet)
+	require.Nil(t, dstMapper.VerifyTxQcpProof(&relayed))
+	require.Equal(t, proof.Header.Hash().Bytes(), dstMapper.GetChainInHeaderHash(srcChain, cid.Version+1))
+	require.Equal(t, cid.Version+1, dstMapper.GetMaxChainInHeaderHeight(srcChain))
+
+	//tx与证明不一致
+	tampered := relayed
+	tampered.Extends = "tampered"
+	require.NotNil(t, dstMapper.VerifyTxQcpProof(&tampered))
+
+	tampered = relayed
+	tampered.Sequence = 2
+	require.NotNil(t, dstMapper.VerifyTxQcpProof(&tampered))
+
+	//不参与签名的字段与证明不一致
+	tampered = relayed
+	tamperedStd := *relayed.TxStd
+	tamperedStd.ITxs = []txs.ITx{txs.NewQcpTxResult(types.Result{Log: "tampered"}, 1, "", "")}
+	tampered.TxStd = &tamperedStd
+	require.Equal(t, relayed.BuildSignatureBytes(), tampered.BuildSignatureBytes())
+	require.NotNil(t, dstMapper.VerifyTxQcpProof(&tampered))
+
+	//非可信验证人签名的区块头
+	otherPrivVals, otherValSet := newTestValidators(3, 10)
+	tampered = relayed
+	tampered.Proof = &txs.TxQcpProof{
+		Header:     signHeader(t, srcChain, cid.Version+2, cid.Hash, otherValSet, otherPrivVals),
+		Validators: otherValSet,
+		Value:      res.Value,
+		Proof:      res.Proof,
+	}
+	require.NotNil(t, dstMapper.VerifyTxQcpProof(&tampered))
+
+	//验证人集合变更: 原验证人均签名时更新可信验证人集合
+	newPrivVals, newVals := newTestValidators(1, 10)
+	newValSet := valSet.Copy()
+	require.Nil(t, newValSet.UpdateWithChangeSet(newVals.Validators))
+	rotated := relayed
+	rotated.Proof = &txs.TxQcpProof{
+		Header:     signHeader(t, srcChain, cid.Version+3, cid.Hash, newValSet, append(privVals, newPrivVals...)),
+		Validators: newValSet,
+		Value:      res.Value,
+		Proof:      res.Proof,
+	}
+	require.Nil(t, dstMapper.VerifyTxQcpProof(&rotated))
+	require.Equal(t, newValSet.Hash(), dstMapper.GetChainInValidators(srcChain).Hash())
+}
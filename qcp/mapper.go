@@ -1,10 +1,16 @@
 package qcp
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"strconv"
 
+	"github.com/QOSGroup/qbase/collections"
 	"github.com/QOSGroup/qbase/mapper"
 	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
 	go_amino "github.com/tendermint/go-amino"
 	"github.com/tendermint/tendermint/crypto"
 )
@@ -14,15 +20,36 @@ const (
 	//需要输出到"chainId"的qcp tx最大序号
 	outSequencePrefixKey = "sequence/out/"
 	outSequenceKey       = outSequencePrefixKey + "%s"
-	//需要输出到"chainId"的每个qcp tx
+	//需要输出到"chainId"的每个qcp tx: tx/out/{chainId}/{sequence}, sequence为8字节大端序, 按序号排序
 	outSequenceTxPrefixKey = "tx/out/"
-	outSequenceTxKey       = "outSequenceTxPrefixKey" + "%s/%d"
+	outSequenceTxKey       = outSequenceTxPrefixKey + "%s/"
+
+	//输出到"chainId"的qcp tx已确认的最大连续序号, 该序号及之前的tx均已收到执行结果或为result tx, 且已删除
+	outAckPrefixKey = "ack/out/"
+	outAckKey       = outAckPrefixKey + "%s"
+	//输出到"chainId"的已确认序号之后乱序收到执行结果的tx序号
+	outAckedTxPrefixKey = "acked/out/"
+
+	//旧版本错误的tx/out key前缀, 仅用于数据迁移, 见: MigrateOutTxKeys
+	legacyOutSequenceTxPrefixKey = "outSequenceTxPrefixKey"
 	//已经接受到来自"chainId"的qcp 的合法公钥tx最大序号
 	inSequencePrefixKey = "sequence/in/"
 	inSequenceKey       = inSequencePrefixKey + "%s"
 	//接受来自"chainId"
 	inPubkeyPrefixKey = "pubkey/in/"
 	inPubkeyKey       = inPubkeyPrefixKey + "%s"
+
+	//IterateChainOutTxs单次返回的最大tx数量
+	MaxOutTxsLimit = 100
+)
+
+//各链qcp序号, 与BaseMapper保存的数据相同: {prefix}{chainId} -> amino(int64)
+var (
+	sequenceValue   = collections.AminoValue[int64](go_amino.NewCodec())
+	outSequences    = collections.NewMap([]byte(outSequencePrefixKey), collections.StringKey, sequenceValue)
+	outAckSequences = collections.NewMap([]byte(outAckPrefixKey), collections.StringKey, sequenceValue)
+	inSequences     = collections.NewMap([]byte(inSequencePrefixKey), collections.StringKey, sequenceValue)
+	outAckedTxs     = collections.NewKeySet([]byte(outAckedTxPrefixKey), collections.PairKeyCodec(collections.StringKey, collections.Int64Key))
 )
 
 func BuildQcpStoreQueryPath() []byte {
@@ -38,13 +65,26 @@ func BuildOutSequencePrefixKey() []byte {
 }
 
 func BuildOutSequenceTxKey(outChainID string, sequence int64) []byte {
-	return []byte(fmt.Sprintf(outSequenceTxKey, outChainID, sequence))
+	return append([]byte(fmt.Sprintf(outSequenceTxKey, outChainID)), types.Int2Byte(sequence)...)
+}
+
+//parseOutSequenceTxKey 解析BuildOutSequenceTxKey生成的key
+func parseOutSequenceTxKey(key []byte) (outChainID string, sequence int64, ok bool) {
+	key = key[len(outSequenceTxPrefixKey):]
+	if len(key) < 10 || key[len(key)-9] != '/' {
+		return "", 0, false
+	}
+	return string(key[:len(key)-9]), int64(binary.BigEndian.Uint64(key[len(key)-8:])), true
 }
 
 func BuildOutSequenceTxPrefixKey() []byte {
 	return []byte(outSequenceTxPrefixKey)
 }
 
+func BuildOutAckKey(outChainID string) []byte {
+	return []byte(fmt.Sprintf(outAckKey, outChainID))
+}
+
 func BuildInSequenceKey(inChainID string) []byte {
 	return []byte(fmt.Sprintf(inSequenceKey, inChainID))
 }
@@ -88,13 +128,12 @@ func (mapper *QcpMapper) SetChainInTrustPubKey(inChain string, pubkey crypto.Pub
 	mapper.Set(BuildInPubkeyKey(inChain), pubkey)
 }
 
-func (mapper *QcpMapper) GetMaxChainOutSequence(outChain string) (seq int64) {
-	mapper.Get(BuildOutSequenceKey(outChain), &seq)
-	return
+func (mapper *QcpMapper) GetMaxChainOutSequence(outChain string) int64 {
+	return mapper.getSequence(outSequences, outChain)
 }
 
 func (mapper *QcpMapper) SetMaxChainOutSequence(outChain string, sequence int64) {
-	mapper.Set(BuildOutSequenceKey(outChain), sequence)
+	mapper.setSequence(outSequences, outChain, sequence)
 }
 
 func (mapper *QcpMapper) GetChainOutTxs(outChain string, sequence int64) *txs.TxQcp {
@@ -107,13 +146,105 @@ func (mapper *QcpMapper) SetChainOutTxs(outChain string, sequence int64, txQcp *
 	mapper.Set(BuildOutSequenceTxKey(outChain, sequence), *txQcp)
 }
 
-func (mapper *QcpMapper) GetMaxChainInSequence(inChain string) (seq int64) {
-	mapper.Get(BuildInSequenceKey(inChain), &seq)
-	return
+func (mapper *QcpMapper) GetChainOutAckSequence(outChain string) int64 {
+	return mapper.getSequence(outAckSequences, outChain)
+}
+
+func (mapper *QcpMapper) SetChainOutAckSequence(outChain string, sequence int64) {
+	mapper.setSequence(outAckSequences, outChain, sequence)
+}
+
+//AckChainOutTx 收到输出到outChain的第sequence个qcp tx的执行结果后删除该tx, 并更新已确认的最大连续序号
+//乱序收到的执行结果记录在acked/out/下, 已确认序号推进到该序号时删除记录.
+//result tx不会收到执行结果, 已确认序号推进到该序号时删除. 不存在且未确认的序号阻止已确认序号的更新
+func (mapper *QcpMapper) AckChainOutTx(outChain string, sequence int64) {
+	ackSeq := mapper.GetChainOutAckSequence(outChain)
+	if sequence <= ackSeq {
+		return
+	}
+
+	store := mapper.GetStore()
+	var txQcp txs.TxQcp
+	if exists := mapper.Get(BuildOutSequenceTxKey(outChain, sequence), &txQcp); exists && !txQcp.IsResult {
+		mapper.Del(BuildOutSequenceTxKey(outChain, sequence))
+		if err := outAckedTxs.Set(store, collections.Join(outChain, sequence)); err != nil {
+			panic(err)
+		}
+	}
+
+	maxSeq := mapper.GetMaxChainOutSequence(outChain)
+	for ; ackSeq < maxSeq; ackSeq++ {
+		key := collections.Join(outChain, ackSeq+1)
+		acked, err := outAckedTxs.Has(store, key)
+		if err != nil {
+			panic(err)
+		}
+		if acked {
+			if err := outAckedTxs.Remove(store, key); err != nil {
+				panic(err)
+			}
+			continue
+		}
+
+		var next txs.TxQcp
+		if exists := mapper.Get(BuildOutSequenceTxKey(outChain, ackSeq+1), &next); !exists || !next.IsResult {
+			break
+		}
+		mapper.Del(BuildOutSequenceTxKey(outChain, ackSeq+1))
+	}
+	mapper.SetChainOutAckSequence(outChain, ackSeq)
+}
+
+//IterateChainOutTxs 按序号顺序返回输出到outChain的qcp tx, 从fromSeq开始最多limit个
+//limit <= 0 或超过MaxOutTxsLimit时最多返回MaxOutTxsLimit个.
+//只遍历[fromSeq, 最大序号]范围内的key, 其他chainId以"outChain/"开头的链的tx不在该范围内
+func (mapper *QcpMapper) IterateChainOutTxs(outChain string, fromSeq int64, limit int) []*txs.TxQcp {
+	if limit <= 0 || limit > MaxOutTxsLimit {
+		limit = MaxOutTxsLimit
+	}
+	if fromSeq < 1 {
+		fromSeq = 1
+	}
+
+	txQcps := make([]*txs.TxQcp, 0)
+	maxSeq := mapper.GetMaxChainOutSequence(outChain)
+	if fromSeq > maxSeq {
+		return txQcps
+	}
+
+	start, end := BuildOutSequenceTxKey(outChain, fromSeq), BuildOutSequenceTxKey(outChain, maxSeq+1)
+	mapper.IteratorWithEnd(start, end, func(value []byte) bool {
+		var txQcp txs.TxQcp
+		mapper.DecodeObject(value, &txQcp)
+		if txQcp.To == outChain {
+			txQcps = append(txQcps, &txQcp)
+		}
+		return len(txQcps) >= limit
+	})
+	return txQcps
+}
+
+func (mapper *QcpMapper) GetMaxChainInSequence(inChain string) int64 {
+	return mapper.getSequence(inSequences, inChain)
 }
 
 func (mapper *QcpMapper) SetMaxChainInSequence(inChain string, sequence int64) {
-	mapper.Set(BuildInSequenceKey(inChain), sequence)
+	mapper.setSequence(inSequences, inChain, sequence)
+}
+
+//getSequence 未设置时返回0
+func (mapper *QcpMapper) getSequence(sequences collections.Map[string, int64], chain string) int64 {
+	seq, err := sequences.Get(mapper.GetStore(), chain)
+	if err != nil && !errors.Is(err, collections.ErrNotFound) {
+		panic(err)
+	}
+	return seq
+}
+
+func (mapper *QcpMapper) setSequence(sequences collections.Map[string, int64], chain string, sequence int64) {
+	if err := sequences.Set(mapper.GetStore(), chain, sequence); err != nil {
+		panic(err)
+	}
 }
 
 func (mapper *QcpMapper) SignAndSaveTxQcp(txQcp *txs.TxQcp, signer crypto.PrivKey) *txs.TxQcp {
@@ -136,3 +267,68 @@ func (mapper *QcpMapper) SignAndSaveTxQcp(txQcp *txs.TxQcp, signer crypto.PrivKe
 
 	return txQcp
 }
+
+//IterateChainInTrustPubKeys 遍历所有链的信任公钥
+func (mapper *QcpMapper) IterateChainInTrustPubKeys(process func(inChain string, pubkey crypto.PubKey) (stop bool)) {
+	prefix := BuildInPubkeyPrefixKey()
+	mapper.IteratorWithKV(prefix, func(key []byte, value []byte) bool {
+		var pubkey crypto.PubKey
+		mapper.DecodeObject(value, &pubkey)
+		return process(string(key[len(prefix):]), pubkey)
+	})
+}
+
+//IterateMaxChainOutSequences 遍历所有链的最大输出序号
+func (mapper *QcpMapper) IterateMaxChainOutSequences(process func(outChain string, sequence int64) (stop bool)) {
+	mapper.iterateSequences(outSequences, process)
+}
+
+//IterateMaxChainInSequences 遍历所有链的最大输入序号
+func (mapper *QcpMapper) IterateMaxChainInSequences(process func(inChain string, sequence int64) (stop bool)) {
+	mapper.iterateSequences(inSequences, process)
+}
+
+func (mapper *QcpMapper) iterateSequences(sequences collections.Map[string, int64], process func(chain string, sequence int64) (stop bool)) {
+	if err := sequences.Iterate(mapper.GetStore(), nil, process); err != nil {
+		panic(err)
+	}
+}
+
+//HasLegacyOutTxKeys 是否存在以旧版本key前缀保存的qcp tx
+func HasLegacyOutTxKeys(mapper *QcpMapper) (exists bool) {
+	mapper.IteratorWithKV([]byte(legacyOutSequenceTxPrefixKey), func(key []byte, value []byte) bool {
+		exists = true
+		return true
+	})
+	return
+}
+
+//MigrateOutTxKeys 将旧版本中以"outSequenceTxPrefixKey{chainId}/{十进制sequence}"保存的qcp tx迁移至BuildOutSequenceTxKey下, 返回迁移的tx数量
+func MigrateOutTxKeys(mapper *QcpMapper) (migrated int) {
+	prefix := []byte(legacyOutSequenceTxPrefixKey)
+
+	var keys, values [][]byte
+	mapper.IteratorWithKV(prefix, func(key []byte, value []byte) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return false
+	})
+
+	store := mapper.GetStore()
+	for i, key := range keys {
+		suffix := key[len(prefix):]
+		sep := bytes.LastIndexByte(suffix, '/')
+		if sep < 0 {
+			continue
+		}
+		sequence, err := strconv.ParseInt(string(suffix[sep+1:]), 10, 64)
+		if err != nil {
+			continue
+		}
+		store.Set(BuildOutSequenceTxKey(string(suffix[:sep]), sequence), values[i])
+		store.Delete(key)
+		migrated++
+	}
+
+	return
+}
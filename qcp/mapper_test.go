@@ -5,6 +5,7 @@ import (
 	"github.com/QOSGroup/qbase/types"
 	"testing"
 
+	"github.com/QOSGroup/qbase/collections"
 	"github.com/QOSGroup/qbase/mapper"
 
 	"github.com/QOSGroup/qbase/context"
@@ -102,6 +103,161 @@ func Test_Mapper_GetMaxChainOutSequence(t *testing.T) {
 
 }
 
+func Test_Mapper_IterateChainOutTxs(t *testing.T) {
+	cdc := defaultCdc()
+	qcpMapper := NewQcpMapper(cdc)
+	qcpMapper.SetCodec(cdc)
+
+	mapper := map[string]mapper.IMapper{qcpMapper.MapperName(): qcpMapper}
+	ctx := defaultContext(qcpMapper.GetStoreKey(), mapper)
+	qcpMapper, _ = ctx.Mapper(qcpMapper.MapperName()).(*QcpMapper)
+
+	for i := 0; i < 12; i++ {
+		qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "a", To: "qsc"}, nil)
+	}
+	qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "a", To: "qos"}, nil)
+
+	require.Equal(t, []byte(outSequenceTxPrefixKey+"qsc/\x00\x00\x00\x00\x00\x00\x00\x01"), BuildOutSequenceTxKey("qsc", 1))
+
+	txQcps := qcpMapper.IterateChainOutTxs("qsc", 1, 5)
+	require.Len(t, txQcps, 5)
+	for i, txQcp := range txQcps {
+		require.Equal(t, int64(i+1), txQcp.Sequence)
+	}
+
+	txQcps = qcpMapper.IterateChainOutTxs("qsc", 9, 5)
+	require.Len(t, txQcps, 4)
+	require.Equal(t, int64(12), txQcps[3].Sequence)
+
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 0, 0), 12)
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 13, 5), 0)
+	require.Len(t, qcpMapper.IterateChainOutTxs("qos", 1, 0), 1)
+
+	//chainId以"qsc/"开头的链的tx不返回
+	qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "a", To: "qsc/x"}, nil)
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 1, 0), 12)
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc/x", 1, 0), 1)
+
+	//已删除的tx不返回
+	qcpMapper.Del(BuildOutSequenceTxKey("qsc", 10))
+	txQcps = qcpMapper.IterateChainOutTxs("qsc", 9, 2)
+	require.Equal(t, int64(9), txQcps[0].Sequence)
+	require.Equal(t, int64(11), txQcps[1].Sequence)
+
+	//返回数量不超过MaxOutTxsLimit
+	for i := 0; i < MaxOutTxsLimit; i++ {
+		qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "a", To: "qsc"}, nil)
+	}
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 0, 0), MaxOutTxsLimit)
+	txQcps = qcpMapper.IterateChainOutTxs("qsc", 1, MaxOutTxsLimit+10)
+	require.Len(t, txQcps, MaxOutTxsLimit)
+	require.Equal(t, int64(101), txQcps[MaxOutTxsLimit-1].Sequence)
+}
+
+func Test_Mapper_AckChainOutTx(t *testing.T) {
+	_, qcpMapper := newTestQcpContext()
+
+	//seq 3为result tx
+	for i := 1; i <= 5; i++ {
+		qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "qos", To: "qsc", IsResult: i == 3}, nil)
+	}
+	require.Equal(t, int64(0), qcpMapper.GetChainOutAckSequence("qsc"))
+
+	//乱序确认时已确认序号不变
+	qcpMapper.AckChainOutTx("qsc", 2)
+	require.Equal(t, int64(0), qcpMapper.GetChainOutAckSequence("qsc"))
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 1, 0), 4)
+
+	//跳过已确认的tx, 删除result tx
+	qcpMapper.AckChainOutTx("qsc", 1)
+	require.Equal(t, int64(3), qcpMapper.GetChainOutAckSequence("qsc"))
+	txQcps := qcpMapper.IterateChainOutTxs("qsc", 1, 0)
+	require.Len(t, txQcps, 2)
+	require.Equal(t, int64(4), txQcps[0].Sequence)
+
+	qcpMapper.AckChainOutTx("qsc", 5)
+	require.Equal(t, int64(3), qcpMapper.GetChainOutAckSequence("qsc"))
+	qcpMapper.AckChainOutTx("qsc", 4)
+	require.Equal(t, int64(5), qcpMapper.GetChainOutAckSequence("qsc"))
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 1, 0), 0)
+
+	//重复确认
+	qcpMapper.AckChainOutTx("qsc", 4)
+	require.Equal(t, int64(5), qcpMapper.GetChainOutAckSequence("qsc"))
+
+	//不存在的tx阻止已确认序号的更新
+	for i := 6; i <= 8; i++ {
+		qcpMapper.SignAndSaveTxQcp(&txs.TxQcp{From: "qos", To: "qsc"}, nil)
+	}
+	qcpMapper.Del(BuildOutSequenceTxKey("qsc", 7))
+	qcpMapper.AckChainOutTx("qsc", 8)
+	qcpMapper.AckChainOutTx("qsc", 7)
+	qcpMapper.AckChainOutTx("qsc", 6)
+	require.Equal(t, int64(6), qcpMapper.GetChainOutAckSequence("qsc"))
+
+	//已确认序号之前的记录均已删除
+	var acked []int64
+	require.Nil(t, outAckedTxs.Iterate(qcpMapper.GetStore(), nil, func(key collections.Pair[string, int64]) bool {
+		acked = append(acked, key.K2())
+		return false
+	}))
+	require.Equal(t, []int64{8}, acked)
+}
+
+func Test_MigrateOutTxKeys(t *testing.T) {
+	cdc := defaultCdc()
+	qcpMapper := NewQcpMapper(cdc)
+	qcpMapper.SetCodec(cdc)
+
+	mapper := map[string]mapper.IMapper{qcpMapper.MapperName(): qcpMapper}
+	ctx := defaultContext(qcpMapper.GetStoreKey(), mapper)
+	qcpMapper, _ = ctx.Mapper(qcpMapper.MapperName()).(*QcpMapper)
+
+	//旧版本key
+	for seq := int64(1); seq <= 3; seq++ {
+		key := []byte(fmt.Sprintf(legacyOutSequenceTxPrefixKey+"%s/%d", "qsc", seq))
+		qcpMapper.Set(key, txs.TxQcp{From: "a", To: "qsc", Sequence: seq})
+	}
+	qcpMapper.SetMaxChainOutSequence("qsc", 3)
+	require.Len(t, qcpMapper.IterateChainOutTxs("qsc", 1, 0), 0)
+
+	require.Equal(t, 3, MigrateOutTxKeys(qcpMapper))
+	txQcps := qcpMapper.IterateChainOutTxs("qsc", 1, 0)
+	require.Len(t, txQcps, 3)
+	require.Equal(t, int64(3), txQcps[2].Sequence)
+
+	require.Equal(t, 0, MigrateOutTxKeys(qcpMapper))
+}
+
+func Test_Mapper_SequenceKeys(t *testing.T) {
+	_, qcpMapper := newTestQcpContext()
+
+	qcpMapper.SetMaxChainInSequence("qsc", 7)
+	qcpMapper.SetMaxChainOutSequence("qsc", 8)
+	qcpMapper.SetChainOutAckSequence("qsc", 6)
+	qcpMapper.SetMaxChainInSequence("qos", 1)
+
+	//与旧版本BaseMapper保存的数据相同
+	var seq int64
+	require.True(t, qcpMapper.Get(BuildInSequenceKey("qsc"), &seq))
+	require.Equal(t, int64(7), seq)
+	require.True(t, qcpMapper.Get(BuildOutSequenceKey("qsc"), &seq))
+	require.Equal(t, int64(8), seq)
+	require.True(t, qcpMapper.Get(BuildOutAckKey("qsc"), &seq))
+	require.Equal(t, int64(6), seq)
+
+	qcpMapper.Set(BuildInSequenceKey("qsc"), int64(9))
+	require.Equal(t, int64(9), qcpMapper.GetMaxChainInSequence("qsc"))
+	require.Equal(t, int64(0), qcpMapper.GetMaxChainInSequence("unknown"))
+
+	var chains []string
+	qcpMapper.IterateMaxChainInSequences(func(inChain string, sequence int64) bool {
+		chains = append(chains, inChain)
+		return false
+	})
+	require.Equal(t, []string{"qos", "qsc"}, chains)
+}
+
 func defaultCdc() *go_amino.Codec {
 	var cdc = go_amino.NewCodec()
 	cryptoAmino.RegisterAmino(cdc)
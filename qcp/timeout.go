@@ -0,0 +1,155 @@
+package qcp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/store/rootmulti"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+)
+
+//qcp tx超时:
+//目标链在超时后不再执行TxQcp, 但仍占用sequence, 并将超时结果作为QcpTxResult返回来源链
+//目标链未收到TxQcp时, 来源链可通过TxQcpTimeout提交目标链超时后区块的未接收证明, 由TxQcpResultHandler处理超时结果
+const (
+	//已通过超时证明处理的输出到"chainId"的qcp tx
+	outTimeoutPrefixKey = "timeout/out/"
+	outTimeoutKey       = outTimeoutPrefixKey + "%s/%d"
+)
+
+func BuildOutTimeoutKey(outChainID string, sequence int64) []byte {
+	return []byte(fmt.Sprintf(outTimeoutKey, outChainID, sequence))
+}
+
+//BuildInSequenceKeyPath 返回sequence/in/[inChain]在multistore中的merkle key path
+func BuildInSequenceKeyPath(inChain string) string {
+	return merkle.KeyPath{}.
+		AppendKey([]byte(MapperName), merkle.KeyEncodingURL).
+		AppendKey(BuildInSequenceKey(inChain), merkle.KeyEncodingURL).
+		String()
+}
+
+func (mapper *QcpMapper) IsChainOutTxTimeout(outChain string, sequence int64) bool {
+	v, _ := mapper.GetBool(BuildOutTimeoutKey(outChain, sequence))
+	return v
+}
+
+func (mapper *QcpMapper) SetChainOutTxTimeout(outChain string, sequence int64) {
+	mapper.Set(BuildOutTimeoutKey(outChain, sequence), true)
+}
+
+//VerifyTxQcpTimeout 校验输出到outChain的第sequence个qcp tx已超时且未被目标链接收:
+//1. tx设置了超时, 且未通过超时证明处理过
+//2. proof.Header由outChain可信验证人集合签名, 其区块高度或时间已超时
+//3. proof.Value为该区块头AppHash下outChain的sequence/in/[chainID], 其值小于sequence或不存在
+func (mapper *QcpMapper) VerifyTxQcpTimeout(chainID, outChain string, sequence int64, proof *txs.TxQcpProof) (*txs.TxQcp, error) {
+	var outTx txs.TxQcp
+	if exists := mapper.Get(BuildOutSequenceTxKey(outChain, sequence), &outTx); !exists {
+		return nil, fmt.Errorf("out tx to %s sequence %d not found", outChain, sequence)
+	}
+	if outTx.TimeoutHeight == 0 && outTx.TimeoutTime == 0 {
+		return nil, errors.New("out tx has no timeout")
+	}
+	if mapper.IsChainOutTxTimeout(outChain, sequence) {
+		return nil, errors.New("out tx has already timed out")
+	}
+
+	if proof == nil || proof.Proof == nil {
+		return nil, errors.New("timeout proof is empty")
+	}
+	if err := mapper.verifyHeader(outChain, proof); err != nil {
+		return nil, err
+	}
+
+	header := proof.Header
+	if !outTx.IsTimeout(header.Height, header.Time) {
+		return nil, fmt.Errorf("out tx not timeout at %s height %d", outChain, header.Height)
+	}
+
+	prt := rootmulti.DefaultProofRuntime()
+	keyPath := BuildInSequenceKeyPath(chainID)
+	if len(proof.Value) == 0 {
+		if err := prt.VerifyAbsence(proof.Proof, header.AppHash, keyPath); err != nil {
+			return nil, fmt.Errorf("verify merkle proof error: %v", err)
+		}
+		return &outTx, nil
+	}
+
+	if err := prt.VerifyValue(proof.Proof, header.AppHash, keyPath, proof.Value); err != nil {
+		return nil, fmt.Errorf("verify merkle proof error: %v", err)
+	}
+	var inSequence int64
+	if err := mapper.GetCodec().UnmarshalBinaryBare(proof.Value, &inSequence); err != nil {
+		return nil, fmt.Errorf("decode proof value error: %v", err)
+	}
+	if inSequence >= sequence {
+		return nil, fmt.Errorf("out tx has been received by %s, in sequence: %d", outChain, inSequence)
+	}
+
+	return &outTx, nil
+}
+
+//TxQcpTimeout 提交目标链未接收已超时qcp tx的证明, 以超时结果调用TxQcpResultHandler
+//需在来源链设置目标链的可信验证人集合. 任何账户均可提交
+type TxQcpTimeout struct {
+	To       string          `json:"to"`       //目标链chainId
+	Sequence int64           `json:"sequence"` //超时的qcp tx序号
+	Proof    *txs.TxQcpProof `json:"proof"`    //目标链超时后区块头及sequence/in/[当前链]的merkle证明
+}
+
+var _ txs.ITx = (*TxQcpTimeout)(nil)
+
+func (tx *TxQcpTimeout) ValidateData(ctx context.Context) error {
+	if tx.To == "" {
+		return errors.New("to chain id is empty")
+	}
+	if tx.Sequence <= 0 {
+		return fmt.Errorf("invalid sequence %d", tx.Sequence)
+	}
+	if tx.Proof == nil {
+		return errors.New("proof is nil")
+	}
+	return nil
+}
+
+func (tx *TxQcpTimeout) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper, err := getQcpMapper(ctx)
+	if err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	outTx, err := mapper.VerifyTxQcpTimeout(ctx.ChainID(), tx.To, tx.Sequence, tx.Proof)
+	if err != nil {
+		return types.ErrUnauthorized(fmt.Sprintf("verify timeout proof error: %v", err)).Result(), nil
+	}
+	mapper.SetChainOutTxTimeout(tx.To, tx.Sequence)
+	mapper.AckChainOutTx(tx.To, tx.Sequence)
+
+	timeoutResult := types.ErrQcpTimeout(fmt.Sprintf("qcp tx to %s sequence %d timeout", tx.To, tx.Sequence)).Result()
+	return txs.NewQcpTxResult(timeoutResult, tx.Sequence, outTx.Extends, "timeout").Exec(ctx)
+}
+
+func (tx *TxQcpTimeout) GetSigner() []types.AccAddress {
+	return nil
+}
+
+func (tx *TxQcpTimeout) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxQcpTimeout) GetGasPayer() types.AccAddress {
+	return nil
+}
+
+func (tx *TxQcpTimeout) GetSignData() []byte {
+	ret := []byte(tx.To)
+	ret = append(ret, types.Int2Byte(tx.Sequence)...)
+	if tx.Proof != nil {
+		ret = append(ret, tx.Proof.Header.Hash()...)
+		ret = append(ret, tx.Proof.Value...)
+	}
+	return ret
+}
@@ -0,0 +1,107 @@
+package qcp
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func Test_TxQcpTimeout(t *testing.T) {
+	srcChain, dstChain := "qos", "qsc"
+
+	//来源链保存超时高度为3的输出tx
+	srcCtx, srcMapper := newTestQcpContext()
+	var handled []*txs.QcpTxResult
+	srcCtx = srcCtx.WithTxQcpResultHandler(func(ctx context.Context, itx interface{}) {
+		handled = append(handled, itx.(*txs.QcpTxResult))
+	})
+	for i := 0; i < 2; i++ {
+		itx := txs.NewQcpTxResult(types.Result{}, 1, "", "")
+		txQcp := txs.NewTxQCP(txs.NewTxStd(itx, dstChain, types.NewInt(100)), srcChain, dstChain, 0, 1, 0, false, "refund")
+		txQcp.TimeoutHeight = 3
+		srcMapper.SignAndSaveTxQcp(txQcp, nil)
+	}
+
+	//目标链在version 3接收seq 1
+	cdc := defaultCdc()
+	dstMapper := NewQcpMapper(cdc)
+	dstMapper.SetCodec(cdc)
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.SetPruning(store.PruneNothing)
+	cms.MountStoreWithDB(dstMapper.GetStoreKey(), types.StoreTypeIAVL, db)
+	require.Nil(t, cms.LoadLatestVersion())
+	dstCtx := context.NewContext(cms, abci.Header{}, false, log.NewNopLogger(), map[string]mapper.IMapper{dstMapper.MapperName(): dstMapper})
+	dstMapper = dstCtx.Mapper(MapperName).(*QcpMapper)
+	dstMapper.SetMaxChainInSequence("other", 1)
+	var commitIDs []types.CommitID
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			dstMapper.SetMaxChainInSequence(srcChain, 1)
+		}
+		commitIDs = append(commitIDs, cms.Commit())
+	}
+
+	privVals, valSet := newTestValidators(3, 10)
+	srcMapper.SetChainInValidators(dstChain, valSet)
+
+	//目标链commitIDs[version-1]对应区块头高度为version+1
+	buildProof := func(version int64) *txs.TxQcpProof {
+		res := cms.(store.Queryable).Query(abci.RequestQuery{Path: "/" + MapperName + "/key", Data: BuildInSequenceKey(srcChain), Height: version, Prove: true})
+		require.Equal(t, uint32(0), res.Code, res.Log)
+		return &txs.TxQcpProof{
+			Header:     signHeader(t, dstChain, version+1, commitIDs[version-1].Hash, valSet, privVals),
+			Validators: valSet,
+			Value:      res.Value,
+			Proof:      res.Proof,
+		}
+	}
+
+	//区块头高度2尚未超时
+	timeoutTx := &TxQcpTimeout{To: dstChain, Sequence: 1, Proof: buildProof(1)}
+	require.Nil(t, timeoutTx.ValidateData(srcCtx))
+	result, _ := timeoutTx.Exec(srcCtx)
+	require.False(t, result.IsOK())
+
+	//seq 1在区块头高度4时已被目标链接收
+	timeoutTx.Proof = buildProof(3)
+	result, _ = timeoutTx.Exec(srcCtx)
+	require.False(t, result.IsOK())
+
+	//篡改区块头后签名校验失败
+	forged := buildProof(3)
+	forged.Header.Header.Time = forged.Header.Time.Add(1)
+	timeoutTx = &TxQcpTimeout{To: dstChain, Sequence: 2, Proof: forged}
+	result, _ = timeoutTx.Exec(srcCtx)
+	require.False(t, result.IsOK())
+
+	//目标链in sequence为1, seq 2未被接收
+	timeoutTx.Proof = buildProof(3)
+	result, _ = timeoutTx.Exec(srcCtx)
+	require.True(t, result.IsOK())
+	require.True(t, srcMapper.IsChainOutTxTimeout(dstChain, 2))
+	require.Equal(t, 1, len(handled))
+	require.Equal(t, types.CodeQcpTimeout, handled[0].Result.Code)
+	require.Equal(t, int64(2), handled[0].QcpOriginalSequence)
+	require.Equal(t, "refund", handled[0].QcpOriginalExtends)
+
+	//不可重复处理
+	result, _ = timeoutTx.Exec(srcCtx)
+	require.False(t, result.IsOK())
+	require.Equal(t, 1, len(handled))
+
+	//区块头高度3时目标链in sequence不存在, seq 1未被接收
+	timeoutTx = &TxQcpTimeout{To: dstChain, Sequence: 1, Proof: buildProof(2)}
+	result, _ = timeoutTx.Exec(srcCtx)
+	require.True(t, result.IsOK())
+	require.Equal(t, 2, len(handled))
+	require.Equal(t, int64(1), handled[1].QcpOriginalSequence)
+}
@@ -21,6 +21,9 @@ const (
 type BaseConfig struct {
 	// Tx minimum fee
 	MinFees string `mapstructure:"minimum_fees"`
+
+	// Halt the node after committing the block at this height, 0 disables halting
+	HaltHeight int64 `mapstructure:"halt_height"`
 }
 
 // Config defines the server's top level configuration
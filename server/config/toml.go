@@ -15,6 +15,10 @@ const defaultConfigTemplate = `# This is a TOML config file.
 
 # Validators reject any tx from the mempool with less than the minimum fee per gas.
 minimum_fees = "{{ .BaseConfig.MinFees }}"
+
+# HaltHeight halts the node after committing the block at this height, e.g. for a coordinated upgrade.
+# Setting it to 0 disables halting.
+halt_height = {{ .BaseConfig.HaltHeight }}
 `
 
 var configTemplate *template.Template
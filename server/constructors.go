@@ -9,7 +9,6 @@ import (
 	abci "github.com/tendermint/tendermint/abci/types"
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/libs/log"
-	tmtypes "github.com/tendermint/tendermint/types"
 	dbm "github.com/tendermint/tm-db"
 )
 
@@ -18,9 +17,9 @@ type (
 	// application using various configurations.
 	AppCreator func(*cfg.Config, log.Logger, dbm.DB, io.Writer) abci.Application
 
-	// AppExporter is a function that dumps all app state to
-	// JSON-serializable structure and returns the current validator set.
-	AppExporter func(log.Logger, dbm.DB, io.Writer, int64, bool) (json.RawMessage, []tmtypes.GenesisValidator, error)
+	// AppExporter is a function that dumps all app state at height (-1 for latest) to
+	// JSON-serializable structure, returning the consensus params and the exported height.
+	AppExporter func(*cfg.Config, log.Logger, dbm.DB, io.Writer, int64) (appState json.RawMessage, consParams *abci.ConsensusParams, height int64, err error)
 )
 
 func openDB(rootDir string) (dbm.DB, error) {
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/QOSGroup/qbase/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	go_amino "github.com/tendermint/go-amino"
+	sm "github.com/tendermint/tendermint/state"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const (
+	flagHeight = "height"
+	flagOutput = "output"
+)
+
+// ExportCmd dumps app state to JSON as a new genesis file.
+func ExportCmd(ctx *Context, cdc *go_amino.Codec, appExporter AppExporter) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export state to JSON as a new genesis file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home := viper.GetString("home")
+			traceWriterFile := viper.GetString(flagTraceStore)
+
+			emptyState, err := isEmptyState(home)
+			if err != nil {
+				return err
+			}
+			if emptyState {
+				fmt.Fprintln(os.Stderr, "WARNING: State is not initialized. Returning genesis file.")
+				genesis, err := ioutil.ReadFile(ctx.Config.GenesisFile())
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(genesis))
+				return nil
+			}
+
+			db, err := openDB(home)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			traceWriter, err := openTraceWriter(traceWriterFile)
+			if err != nil {
+				return err
+			}
+
+			height := viper.GetInt64(flagHeight)
+			appState, consParams, lastHeight, err := appExporter(ctx.Config, ctx.Logger, db, traceWriter, height)
+			if err != nil {
+				return errors.Errorf("error exporting state: %v\n", err)
+			}
+
+			validators, err := loadGenesisValidators(ctx, lastHeight)
+			if err != nil {
+				return errors.Errorf("error loading validators at height %d: %v\n", lastHeight, err)
+			}
+
+			doc, err := tmtypes.GenesisDocFromFile(ctx.Config.GenesisFile())
+			if err != nil {
+				return err
+			}
+
+			doc.AppState = appState
+			doc.Validators = validators
+			if consParams != nil {
+				params := tmtypes.DefaultConsensusParams().Update(consParams)
+				doc.ConsensusParams = &params
+			}
+
+			encoded, err := cdc.MarshalJSONIndent(doc, "", " ")
+			if err != nil {
+				return err
+			}
+
+			output := viper.GetString(flagOutput)
+			if output == "" {
+				fmt.Println(string(encoded))
+				return nil
+			}
+			return ioutil.WriteFile(output, encoded, 0644)
+		},
+	}
+
+	cmd.Flags().Int64(flagHeight, -1, "Export state from a particular height (-1 means latest height)")
+	cmd.Flags().String(flagOutput, "", "Write the genesis file to this path instead of stdout")
+	cmd.Flags().String(flagTraceStore, "", "Enable KVStore tracing to an output file")
+	return cmd
+}
+
+// validators of the new chain are the tendermint validators for the block after the exported height
+func loadGenesisValidators(ctx *Context, height int64) ([]tmtypes.GenesisValidator, error) {
+	config := ctx.Config
+	stateDB := dbm.NewDB("state", dbm.DBBackendType(config.DBBackend), config.DBDir())
+	defer stateDB.Close()
+
+	valSet, err := sm.LoadValidators(stateDB, height+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewGenesisValidators(valSet), nil
+}
+
+func isEmptyState(home string) (bool, error) {
+	files, err := ioutil.ReadDir(filepath.Join(home, "data"))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, f := range files {
+		if f.Name() == "application.db" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -20,6 +20,9 @@ const (
 	flagAddress        = "address"
 	flagTraceStore     = "trace-store"
 	flagPruning        = "pruning"
+
+	FlagMinimumFees = "minimum_fees"
+	FlagHaltHeight  = "halt_height"
 )
 
 // StartCmd runs the service passed in, either stand-alone or in-process with
@@ -46,6 +49,8 @@ func StartCmd(ctx *Context, appCreator AppCreator) *cobra.Command {
 	cmd.Flags().String(flagAddress, "tcp://0.0.0.0:26658", "Listen address")
 	cmd.Flags().String(flagTraceStore, "", "Enable KVStore tracing to an output file")
 	cmd.Flags().String(flagPruning, "syncable", "Pruning strategy: syncable, nothing, everything")
+	cmd.Flags().String(FlagMinimumFees, "", "Minimum fees validator will accept for transactions in CheckTx, e.g. 1qstar. Overrides app.toml")
+	cmd.Flags().Int64(FlagHaltHeight, 0, "Halt the node after committing the block at this height, 0 disables halting. Overrides app.toml")
 
 	// add support for all Tendermint-specific command line options
 	tcmd.AddNodeFlags(cmd)
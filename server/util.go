@@ -10,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/QOSGroup/qbase/server/config"
 	"github.com/QOSGroup/qbase/version"
 
 	"github.com/pkg/errors"
@@ -100,6 +101,21 @@ func interceptLoadConfig() (conf *cfg.Config, err error) {
 
 	if conf == nil {
 		conf, err = tcmd.ParseConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//app.toml: 应用配置,如minimum_fees
+	appConfigFilePath := filepath.Join(rootDir, "config/app.toml")
+	if _, err := os.Stat(appConfigFilePath); os.IsNotExist(err) {
+		appConf, _ := config.ParseConfig()
+		config.WriteConfigFile(appConfigFilePath, appConf)
+	}
+
+	viper.SetConfigName("app")
+	if err = viper.MergeInConfig(); err != nil {
+		return nil, err
 	}
 
 	return
@@ -108,7 +124,7 @@ func interceptLoadConfig() (conf *cfg.Config, err error) {
 // add server commands
 func AddCommands(
 	ctx *Context, cdc *go_amino.Codec,
-	rootCmd *cobra.Command, appCreator AppCreator) {
+	rootCmd *cobra.Command, appCreator AppCreator, appExporter AppExporter) {
 
 	rootCmd.PersistentFlags().String("log_level", ctx.Config.LogLevel, "Log level")
 
@@ -125,6 +141,7 @@ func AddCommands(
 
 	rootCmd.AddCommand(
 		StartCmd(ctx, appCreator),
+		ExportCmd(ctx, cdc, appExporter),
 		UnsafeResetAllCmd(ctx),
 		tendermintCmd,
 	)
@@ -0,0 +1,78 @@
+package iavl
+
+import (
+	"io"
+
+	"github.com/tendermint/iavl"
+
+	"github.com/QOSGroup/qbase/store/cachekv"
+	"github.com/QOSGroup/qbase/store/tracekv"
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+var _ types.KVStore = (*immutableStore)(nil)
+
+// immutableStore is a read-only KVStore over a historical version of an
+// IAVL tree. Writes panic.
+type immutableStore struct {
+	tree *iavl.ImmutableTree
+}
+
+// GetImmutable returns a read-only KVStore of the given version.
+func (st *Store) GetImmutable(version int64) (types.KVStore, error) {
+	if !st.VersionExists(version) {
+		return nil, iavl.ErrVersionDoesNotExist
+	}
+
+	tree, err := st.tree.GetImmutable(version)
+	if err != nil {
+		return nil, err
+	}
+	return &immutableStore{tree: tree}, nil
+}
+
+// Implements Store.
+func (st *immutableStore) GetStoreType() types.StoreType {
+	return types.StoreTypeIAVL
+}
+
+// Implements Store.
+func (st *immutableStore) CacheWrap() types.CacheWrap {
+	return cachekv.NewStore(st)
+}
+
+// CacheWrapWithTrace implements the Store interface.
+func (st *immutableStore) CacheWrapWithTrace(w io.Writer, tc types.TraceContext) types.CacheWrap {
+	return cachekv.NewStore(tracekv.NewStore(st, w, tc))
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) Get(key []byte) (value []byte) {
+	_, v := st.tree.Get(key)
+	return v
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) Has(key []byte) (exists bool) {
+	return st.tree.Has(key)
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) Set(key, value []byte) {
+	panic("cannot call Set on an immutable IAVL store")
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) Delete(key []byte) {
+	panic("cannot call Delete on an immutable IAVL store")
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) Iterator(start, end []byte) types.Iterator {
+	return newIAVLIterator(st.tree, start, end, true)
+}
+
+// Implements types.KVStore.
+func (st *immutableStore) ReverseIterator(start, end []byte) types.Iterator {
+	return newIAVLIterator(st.tree, start, end, false)
+}
@@ -0,0 +1,219 @@
+package iavl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// iavl v0.12 nodedb key formats:
+// n<hash>    node
+// r<version> root hash of version
+const (
+	nodeKeyPrefix = 'n'
+	rootKeyPrefix = 'r'
+)
+
+// RootKey returns the nodedb key of the root hash of version.
+func RootKey(version int64) []byte {
+	return rootKey(version)
+}
+
+func rootKey(version int64) []byte {
+	key := make([]byte, 9)
+	key[0] = rootKeyPrefix
+	binary.BigEndian.PutUint64(key[1:], uint64(version))
+	return key
+}
+
+func nodeKey(hash []byte) []byte {
+	return append([]byte{nodeKeyPrefix}, hash...)
+}
+
+// ExportVersion walks the nodes of the iavl tree at version in db and calls fn with the
+// raw nodedb key/value of the version root and every reachable node.
+// Writing these pairs into an empty db restores the tree at version with an identical hash.
+func ExportVersion(db dbm.DB, version int64, fn func(key, value []byte) error) error {
+	rk := rootKey(version)
+	rootHash := db.Get(rk)
+	if rootHash == nil {
+		return fmt.Errorf("iavl version %d does not exist", version)
+	}
+
+	if err := fn(rk, rootHash); err != nil {
+		return err
+	}
+
+	// empty tree
+	if len(rootHash) == 0 {
+		return nil
+	}
+
+	stack := [][]byte{rootHash}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		nk := nodeKey(hash)
+		bz := db.Get(nk)
+		if bz == nil {
+			return fmt.Errorf("iavl node %X missing in version %d", hash, version)
+		}
+
+		if err := fn(nk, bz); err != nil {
+			return err
+		}
+
+		nd, err := decodeNode(bz)
+		if err != nil {
+			return err
+		}
+		if nd.height > 0 {
+			stack = append(stack, nd.rightHash, nd.leftHash)
+		}
+	}
+
+	return nil
+}
+
+// VerifyNode checks that value is an encoded node whose hash matches the nodedb key.
+// Only node keys are accepted, roots are checked by VerifyVersion.
+func VerifyNode(key, value []byte) error {
+	if len(key) == 0 || key[0] != nodeKeyPrefix {
+		return fmt.Errorf("unexpected iavl key %X", key)
+	}
+
+	node, err := decodeNode(value)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(node.hash(), key[1:]) {
+		return fmt.Errorf("iavl node hash mismatch. key: %X", key[1:])
+	}
+	return nil
+}
+
+// VerifyVersion checks that the root of version in db is rootHash, every node reachable
+// from the root exists, and the search keys of inner nodes, which are not covered by
+// the node hashes, match the leaves.
+func VerifyVersion(db dbm.DB, version int64, rootHash []byte) error {
+	stored := db.Get(rootKey(version))
+	if stored == nil || !bytes.Equal(stored, rootHash) {
+		return fmt.Errorf("iavl root of version %d mismatch. expect: %X, got: %X", version, rootHash, stored)
+	}
+
+	// empty tree
+	if len(rootHash) == 0 {
+		return nil
+	}
+
+	_, err := verifySubtree(db, rootHash)
+	return err
+}
+
+// verifySubtree returns the smallest leaf key of the subtree with root hash.
+func verifySubtree(db dbm.DB, hash []byte) ([]byte, error) {
+	bz := db.Get(nodeKey(hash))
+	if bz == nil {
+		return nil, fmt.Errorf("iavl node %X missing", hash)
+	}
+
+	node, err := decodeNode(bz)
+	if err != nil {
+		return nil, err
+	}
+	if node.height == 0 {
+		return node.key, nil
+	}
+
+	leftKey, err := verifySubtree(db, node.leftHash)
+	if err != nil {
+		return nil, err
+	}
+	rightKey, err := verifySubtree(db, node.rightHash)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(node.key, rightKey) {
+		return nil, fmt.Errorf("iavl inner node %X has invalid key", hash)
+	}
+	return leftKey, nil
+}
+
+// node holds the fields of an encoded iavl node.
+// node format: height, size, version, key, [value | leftHash, rightHash]
+type node struct {
+	height    int8
+	size      int64
+	version   int64
+	key       []byte
+	value     []byte
+	leftHash  []byte
+	rightHash []byte
+}
+
+func decodeNode(bz []byte) (*node, error) {
+	height, n, err := amino.DecodeInt8(bz)
+	if err != nil {
+		return nil, fmt.Errorf("decoding node.height: %v", err)
+	}
+	bz = bz[n:]
+
+	size, n, err := amino.DecodeVarint(bz)
+	if err != nil {
+		return nil, fmt.Errorf("decoding node.size: %v", err)
+	}
+	bz = bz[n:]
+
+	version, n, err := amino.DecodeVarint(bz)
+	if err != nil {
+		return nil, fmt.Errorf("decoding node.version: %v", err)
+	}
+	bz = bz[n:]
+
+	key, n, err := amino.DecodeByteSlice(bz)
+	if err != nil {
+		return nil, fmt.Errorf("decoding node.key: %v", err)
+	}
+	bz = bz[n:]
+
+	nd := &node{height: height, size: size, version: version, key: key}
+	if height == 0 {
+		if nd.value, _, err = amino.DecodeByteSlice(bz); err != nil {
+			return nil, fmt.Errorf("decoding node.value: %v", err)
+		}
+		return nd, nil
+	}
+
+	if nd.leftHash, n, err = amino.DecodeByteSlice(bz); err != nil {
+		return nil, fmt.Errorf("decoding node.leftHash: %v", err)
+	}
+	bz = bz[n:]
+	if nd.rightHash, _, err = amino.DecodeByteSlice(bz); err != nil {
+		return nil, fmt.Errorf("decoding node.rightHash: %v", err)
+	}
+	if len(nd.leftHash) == 0 || len(nd.rightHash) == 0 {
+		return nil, fmt.Errorf("inner node with empty child hash")
+	}
+	return nd, nil
+}
+
+// hash computes the node hash the same way as iavl, the key of inner nodes is not hashed.
+func (nd *node) hash() []byte {
+	var buf bytes.Buffer
+	_ = amino.EncodeInt8(&buf, nd.height)
+	_ = amino.EncodeVarint(&buf, nd.size)
+	_ = amino.EncodeVarint(&buf, nd.version)
+	if nd.height == 0 {
+		_ = amino.EncodeByteSlice(&buf, nd.key)
+		_ = amino.EncodeByteSlice(&buf, tmhash.Sum(nd.value))
+	} else {
+		_ = amino.EncodeByteSlice(&buf, nd.leftHash)
+		_ = amino.EncodeByteSlice(&buf, nd.rightHash)
+	}
+	return tmhash.Sum(buf.Bytes())
+}
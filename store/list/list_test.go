@@ -96,3 +96,48 @@ func TestListRandom(t *testing.T) {
 		require.Equal(t, v, i)
 	}
 }
+
+func TestListDelete(t *testing.T) {
+	key := sdk.NewKVStoreKey("test")
+	ctx, cdc := defaultComponents(key)
+	lm := NewList(cdc, ctx.KVStore(key))
+
+	for i := uint64(0); i < 5; i++ {
+		lm.Push(TestStruct{i, true})
+	}
+
+	// deleting keeps the length and the indices of other elements
+	lm.Delete(uint64(1))
+	lm.Delete(uint64(3))
+	require.Equal(t, uint64(5), lm.Len())
+
+	var res TestStruct
+	require.NotNil(t, lm.Get(uint64(1), &res))
+	require.Nil(t, lm.Get(uint64(2), &res))
+	require.Equal(t, uint64(2), res.I)
+
+	var indices []uint64
+	lm.Iterate(&res, func(index uint64) bool {
+		require.Equal(t, index, res.I)
+		indices = append(indices, index)
+		return false
+	})
+	require.Equal(t, []uint64{0, 2, 4}, indices)
+
+	// push appends after the last index, deleted positions are not reused
+	lm.Push(TestStruct{5, false})
+	require.Equal(t, uint64(6), lm.Len())
+	require.Nil(t, lm.Get(uint64(5), &res))
+	require.Equal(t, TestStruct{5, false}, res)
+	require.NotNil(t, lm.Get(uint64(3), &res))
+
+	// deleting all elements keeps the length
+	for i := uint64(0); i < 6; i++ {
+		lm.Delete(i)
+	}
+	require.Equal(t, uint64(6), lm.Len())
+	lm.Iterate(&res, func(index uint64) bool {
+		t.Fatalf("unexpected element %d", index)
+		return true
+	})
+}
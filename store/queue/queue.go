@@ -1,12 +1,13 @@
-package store
+package queue
 
-// TODO: make it independent from list
-/*
 import (
-	"github.com/QOSGroup/qbase/codec"
-	sdk "github.com/QOSGroup/qbase/types"
+	"errors"
 
+	"github.com/tendermint/go-amino"
+
+	"github.com/QOSGroup/qbase/store/gaskv"
 	"github.com/QOSGroup/qbase/store/list"
+	"github.com/QOSGroup/qbase/store/types"
 )
 
 // Key for the top element position in the queue
@@ -14,47 +15,67 @@ func TopKey() []byte {
 	return []byte{0x02}
 }
 
-// Queue is a List wrapper that provides queue-like functions
+// Queue is a List wrapper that provides FIFO queue functions.
+// The elements are stored with list.List in the same store, the head of the
+// queue is the top position, popped elements are deleted from the list.
 // It panics when the element type cannot be (un/)marshalled by the codec
 type Queue struct {
-	List list.List
+	List  list.List
+	cdc   *amino.Codec
+	store types.KVStore
 }
 
-// NewQueue constructs new Queue
-func NewQueue(cdc *codec.Codec, store sdk.KVStore) Queue {
-	return Queue{NewList(cdc, store)}
+// NewQueue constructs new Queue. Use a prefix store to keep multiple queues
+// in the same KVStore. Stores of mappers in context are already gas metered.
+func NewQueue(cdc *amino.Codec, store types.KVStore) Queue {
+	return Queue{
+		List:  list.NewList(cdc, store),
+		cdc:   cdc,
+		store: store,
+	}
+}
+
+// NewGasMeteredQueue constructs new Queue which consumes gas from gasMeter
+// on every store access
+func NewGasMeteredQueue(cdc *amino.Codec, store types.KVStore, gasMeter types.GasMeter) Queue {
+	return NewQueue(cdc, gaskv.NewStore(store, gasMeter, types.KVGasConfig()))
 }
 
 func (m Queue) getTop() (res uint64) {
-	bz := m.List.store.Get(TopKey())
+	bz := m.store.Get(TopKey())
 	if bz == nil {
 		return 0
 	}
 
-	m.List.cdc.MustUnmarshalBinaryLengthPrefixed(bz, &res)
+	m.cdc.MustUnmarshalBinaryLengthPrefixed(bz, &res)
 	return
 }
 
 func (m Queue) setTop(top uint64) {
-	bz := m.List.cdc.MustMarshalBinaryLengthPrefixed(top)
-	m.List.store.Set(TopKey(), bz)
+	bz := m.cdc.MustMarshalBinaryLengthPrefixed(top)
+	m.store.Set(TopKey(), bz)
 }
 
-// Push() inserts the elements to the rear of the queue
+// Push() inserts the element to the rear of the queue
 func (m Queue) Push(value interface{}) {
 	m.List.Push(value)
 }
 
-// Popping/Peeking on an empty queue will cause panic
-// The user should check IsEmpty() before doing any actions
 // Peek() returns the element at the front of the queue without removing it
+// Returns an error if the queue is empty
 func (m Queue) Peek(ptr interface{}) error {
-	top := m.getTop()
-	return m.List.Get(top, ptr)
+	if m.IsEmpty() {
+		return errors.New("queue is empty")
+	}
+	return m.List.Get(m.getTop(), ptr)
 }
 
-// Pop() returns the element at the front of the queue and removes it
+// Pop() removes the element at the front of the queue
+// Popping an empty queue will cause panic, check IsEmpty() or use Peek() first
 func (m Queue) Pop() {
+	if m.IsEmpty() {
+		panic("pop from empty queue")
+	}
 	top := m.getTop()
 	m.List.Delete(top)
 	m.setTop(top + 1)
@@ -62,13 +83,36 @@ func (m Queue) Pop() {
 
 // IsEmpty() checks if the queue is empty
 func (m Queue) IsEmpty() bool {
+	return m.Len() == 0
+}
+
+// Len() returns the number of elements in the queue
+func (m Queue) Len() uint64 {
 	top := m.getTop()
 	length := m.List.Len()
-	return top >= length
+	if top >= length {
+		return 0
+	}
+	return length - top
+}
+
+// Iterate() iterates over the elements from the front of the queue without
+// removing them. Return true in the continuation to break
+// The interface{} is unmarshalled before the continuation is called
+// CONTRACT: No writes may happen within the queue while iterating over it.
+func (m Queue) Iterate(ptr interface{}, fn func() bool) {
+	iter := m.store.Iterator(list.ElemKey(m.getTop()), list.ElemKey(m.List.Len()))
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		m.cdc.MustUnmarshalBinaryLengthPrefixed(iter.Value(), ptr)
+		if fn() {
+			break
+		}
+	}
 }
 
 // Flush() removes elements it processed
-// Return true in the continuation to break
+// Return true in the continuation to break, the element is removed as well
 // The interface{} is unmarshalled before the continuation is called
 // Starts from the top(head) of the queue
 // CONTRACT: Pop() or Push() should not be performed while flushing
@@ -76,18 +120,19 @@ func (m Queue) Flush(ptr interface{}, fn func() bool) {
 	top := m.getTop()
 	length := m.List.Len()
 
-	var i uint64
-	for i = top; i < length; i++ {
+	i := top
+	for i < length {
 		err := m.List.Get(i, ptr)
 		if err != nil {
-			// TODO: Handle with #870
 			panic(err)
 		}
 		m.List.Delete(i)
+		i++
 		if fn() {
 			break
 		}
 	}
-	m.setTop(i)
+	if i != top {
+		m.setTop(i)
+	}
 }
-*/
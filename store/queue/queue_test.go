@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/go-amino"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/QOSGroup/qbase/store/cachekv"
+	"github.com/QOSGroup/qbase/store/dbadapter"
+	"github.com/QOSGroup/qbase/store/prefix"
+	"github.com/QOSGroup/qbase/store/rootmulti"
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+type TestStruct struct {
+	I uint64
+	B bool
+}
+
+func newIAVLStore() types.KVStore {
+	db := dbm.NewMemDB()
+	key := types.NewKVStoreKey("test")
+	cms := rootmulti.NewStore(db)
+	cms.MountStoreWithDB(key, types.StoreTypeIAVL, db)
+	cms.LoadLatestVersion()
+	return cms.GetKVStore(key)
+}
+
+func newCacheKVStore() types.KVStore {
+	return cachekv.NewStore(dbadapter.Store{DB: dbm.NewMemDB()})
+}
+
+var stores = []struct {
+	name     string
+	newStore func() types.KVStore
+}{
+	{"iavl", newIAVLStore},
+	{"cachekv", newCacheKVStore},
+}
+
+func TestQueue(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			cdc := amino.NewCodec()
+			qm := NewQueue(cdc, s.newStore())
+
+			var res TestStruct
+			require.True(t, qm.IsEmpty())
+			require.NotNil(t, qm.Peek(&res))
+			require.Panics(t, func() { qm.Pop() })
+
+			val := TestStruct{1, true}
+			qm.Push(val)
+			require.False(t, qm.IsEmpty())
+			require.Equal(t, uint64(1), qm.Len())
+			require.Nil(t, qm.Peek(&res))
+			require.Equal(t, val, res)
+
+			qm.Pop()
+			require.True(t, qm.IsEmpty())
+			require.Equal(t, uint64(0), qm.Len())
+
+			for i := uint64(2); i <= 10; i++ {
+				qm.Push(TestStruct{i, i%2 == 0})
+			}
+			require.Equal(t, uint64(9), qm.Len())
+
+			// iterate from the head without removing
+			expected := uint64(2)
+			qm.Iterate(&res, func() bool {
+				require.Equal(t, expected, res.I)
+				expected++
+				return res.I == 5
+			})
+			require.Equal(t, uint64(6), expected)
+			require.Equal(t, uint64(9), qm.Len())
+
+			// the element breaking flush is removed as well
+			qm.Flush(&res, func() bool {
+				return res.I == 4
+			})
+			require.Equal(t, uint64(6), qm.Len())
+			require.Nil(t, qm.Peek(&res))
+			require.Equal(t, uint64(5), res.I)
+
+			qm.Pop()
+			qm.Flush(&res, func() bool { return false })
+			require.True(t, qm.IsEmpty())
+			require.Equal(t, uint64(10), res.I)
+
+			// reuse after flushed
+			qm.Push(TestStruct{11, false})
+			require.Nil(t, qm.Peek(&res))
+			require.Equal(t, uint64(11), res.I)
+			require.Equal(t, uint64(1), qm.Len())
+		})
+	}
+}
+
+func TestQueuesInPrefixStores(t *testing.T) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			cdc := amino.NewCodec()
+			store := s.newStore()
+			q1 := NewQueue(cdc, prefix.NewStore(store, []byte("q1/")))
+			q2 := NewQueue(cdc, prefix.NewStore(store, []byte("q2/")))
+
+			q1.Push(uint64(1))
+			q1.Push(uint64(2))
+			q2.Push(uint64(3))
+			q1.Pop()
+
+			var res uint64
+			require.Nil(t, q1.Peek(&res))
+			require.Equal(t, uint64(2), res)
+			require.Nil(t, q2.Peek(&res))
+			require.Equal(t, uint64(3), res)
+			require.Equal(t, uint64(1), q1.Len())
+			require.Equal(t, uint64(1), q2.Len())
+		})
+	}
+}
+
+func TestGasMeteredQueue(t *testing.T) {
+	cdc := amino.NewCodec()
+	store := newIAVLStore()
+
+	gasMeter := types.NewGasMeter(100000)
+	qm := NewGasMeteredQueue(cdc, store, gasMeter)
+	qm.Push(TestStruct{1, true})
+	consumed := gasMeter.GasConsumed()
+	require.True(t, consumed > 0)
+
+	var res TestStruct
+	require.Nil(t, qm.Peek(&res))
+	require.True(t, gasMeter.GasConsumed() > consumed)
+
+	// panics when out of gas
+	qm = NewGasMeteredQueue(cdc, store, types.NewGasMeter(1))
+	require.Panics(t, func() { qm.Push(TestStruct{2, false}) })
+}
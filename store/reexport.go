@@ -27,6 +27,10 @@ type (
 	StoreType        = types.StoreType
 	Queryable        = types.Queryable
 	TraceContext     = types.TraceContext
+	SnapshotItem     = types.SnapshotItem
+	StoreUpgrades    = types.StoreUpgrades
+	StoreRename      = types.StoreRename
+	Snapshotter      = types.Snapshotter
 	Gas              = stypes.Gas
 	GasMeter         = types.GasMeter
 	GasConfig        = stypes.GasConfig
@@ -0,0 +1,182 @@
+package rootmulti
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/QOSGroup/qbase/store/iavl"
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+var _ types.Snapshotter = (*Store)(nil)
+
+// Export implements Snapshotter. The first item carries the commitInfo of height,
+// followed by the iavl nodes of every store sorted by store name.
+func (rs *Store) Export(height int64, fn func(item types.SnapshotItem) error) error {
+	if height <= 0 {
+		return fmt.Errorf("cannot export snapshot at height %d", height)
+	}
+
+	cInfo, err := getCommitInfo(rs.db, height)
+	if err != nil {
+		return err
+	}
+
+	err = fn(types.SnapshotItem{Value: cdc.MustMarshalBinaryLengthPrefixed(cInfo)})
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cInfo.StoreInfos))
+	versions := make(map[string]int64, len(cInfo.StoreInfos))
+	for _, si := range cInfo.StoreInfos {
+		names = append(names, si.Name)
+		versions[si.Name] = si.Core.CommitID.Version
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key, ok := rs.keysByName[name]
+		if !ok {
+			return fmt.Errorf("store %s is not mounted", name)
+		}
+
+		params := rs.storesParams[key]
+		if params.typ != types.StoreTypeIAVL {
+			return fmt.Errorf("snapshot of store %s with type %v is not supported", name, params.typ)
+		}
+
+		// stores added by StoreUpgrades have their own versions
+		err = iavl.ExportVersion(rs.storeDB(params), versions[name], func(k, v []byte) error {
+			return fn(types.SnapshotItem{Store: name, Key: k, Value: v})
+		})
+		if err != nil {
+			return fmt.Errorf("export store %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Restore implements Snapshotter. The store must be empty and mounted with the same stores
+// as the exporter. Every node is verified against its hash and every store root against
+// the snapshot commitInfo, which is verified against appHash if given. The commitInfo
+// and latest version are only written after verification, on any error the written data
+// is deleted so the restore can be retried.
+func (rs *Store) Restore(height int64, appHash []byte, items <-chan types.SnapshotItem) (err error) {
+	if getLatestVersion(rs.db) != 0 {
+		return fmt.Errorf("cannot restore snapshot into non-empty store")
+	}
+
+	defer func() {
+		if err != nil {
+			rs.deleteRestored(height)
+		}
+	}()
+
+	var cInfo *commitInfo
+	storeInfos := make(map[string]storeInfo)
+	for item := range items {
+		if item.Store == "" {
+			if cInfo != nil {
+				return fmt.Errorf("duplicate snapshot commit info")
+			}
+			var ci commitInfo
+			if err := cdc.UnmarshalBinaryLengthPrefixed(item.Value, &ci); err != nil {
+				return fmt.Errorf("invalid snapshot commit info: %v", err)
+			}
+			if err := verifyCommitInfo(ci, height, appHash); err != nil {
+				return err
+			}
+			for _, si := range ci.StoreInfos {
+				storeInfos[si.Name] = si
+			}
+			cInfo = &ci
+			continue
+		}
+
+		if cInfo == nil {
+			return fmt.Errorf("snapshot commit info missing")
+		}
+		si, ok := storeInfos[item.Store]
+		if !ok {
+			return fmt.Errorf("store %s is not in snapshot commit info", item.Store)
+		}
+		key, ok := rs.keysByName[item.Store]
+		if !ok {
+			return fmt.Errorf("store %s is not mounted", item.Store)
+		}
+
+		// the root is checked with the whole tree below, only the root of the store version is accepted
+		if !bytes.Equal(item.Key, iavl.RootKey(si.Core.CommitID.Version)) {
+			if err := iavl.VerifyNode(item.Key, item.Value); err != nil {
+				return fmt.Errorf("store %s: %v", item.Store, err)
+			}
+		}
+		rs.storeDB(rs.storesParams[key]).Set(item.Key, item.Value)
+	}
+
+	if cInfo == nil {
+		return fmt.Errorf("snapshot commit info missing")
+	}
+
+	for _, si := range cInfo.StoreInfos {
+		key, ok := rs.keysByName[si.Name]
+		if !ok {
+			return fmt.Errorf("store %s is not mounted", si.Name)
+		}
+		err := iavl.VerifyVersion(rs.storeDB(rs.storesParams[key]), si.Core.CommitID.Version, si.Core.CommitID.Hash)
+		if err != nil {
+			return fmt.Errorf("store %s: %v", si.Name, err)
+		}
+	}
+
+	batch := rs.db.NewBatch()
+	setCommitInfo(batch, height, *cInfo)
+	setLatestVersion(batch, height)
+	batch.Write()
+
+	if err := rs.LoadVersion(height); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(rs.LastCommitID().Hash, cInfo.Hash()) {
+		return fmt.Errorf("restored app hash mismatch. expect: %X, got: %X", cInfo.Hash(), rs.LastCommitID().Hash)
+	}
+
+	return nil
+}
+
+func verifyCommitInfo(cInfo commitInfo, height int64, appHash []byte) error {
+	if cInfo.Version != height {
+		return fmt.Errorf("snapshot height mismatch. expect: %d, got: %d", height, cInfo.Version)
+	}
+	if len(appHash) != 0 && !bytes.Equal(cInfo.Hash(), appHash) {
+		return fmt.Errorf("snapshot app hash mismatch. expect: %X, got: %X", appHash, cInfo.Hash())
+	}
+	return nil
+}
+
+// deleteRestored deletes the data written by a failed restore.
+func (rs *Store) deleteRestored(height int64) {
+	batch := rs.db.NewBatch()
+	batch.Delete([]byte(fmt.Sprintf(commitInfoKeyFmt, height)))
+	batch.Delete([]byte(latestVersionKey))
+	batch.Write()
+
+	for _, params := range rs.storesParams {
+		db := rs.storeDB(params)
+
+		var keys [][]byte
+		iter := db.Iterator(nil, nil)
+		for ; iter.Valid(); iter.Next() {
+			keys = append(keys, iter.Key())
+		}
+		iter.Close()
+
+		for _, key := range keys {
+			db.Delete(key)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package rootmulti
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	amino "github.com/tendermint/go-amino"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+func setupSnapshotStore(t *testing.T) (*Store, types.CommitID, []types.SnapshotItem) {
+	store := newMultiStoreWithMounts(dbm.NewMemDB())
+	require.NoError(t, store.LoadLatestVersion())
+
+	for _, name := range []string{"store1", "store2"} {
+		kv := store.getStoreByName(name).(types.KVStore)
+		for i := 0; i < 20; i++ {
+			kv.Set([]byte(fmt.Sprintf("key-%02d", i)), []byte(fmt.Sprintf("value-%s-%d", name, i)))
+		}
+	}
+	commitID := store.Commit()
+
+	var items []types.SnapshotItem
+	require.NoError(t, store.Export(commitID.Version, func(item types.SnapshotItem) error {
+		items = append(items, item)
+		return nil
+	}))
+	return store, commitID, items
+}
+
+func restoreItems(store *Store, height int64, appHash []byte, items []types.SnapshotItem) error {
+	ch := make(chan types.SnapshotItem, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return store.Restore(height, appHash, ch)
+}
+
+func copyItems(items []types.SnapshotItem) []types.SnapshotItem {
+	copied := make([]types.SnapshotItem, len(items))
+	for i, item := range items {
+		copied[i] = types.SnapshotItem{
+			Store: item.Store,
+			Key:   append([]byte(nil), item.Key...),
+			Value: append([]byte(nil), item.Value...),
+		}
+	}
+	return copied
+}
+
+// nodeHeight returns the height of a node item, -1 for other items
+func nodeHeight(item types.SnapshotItem) int8 {
+	if item.Store == "" || item.Key[0] != 'n' {
+		return -1
+	}
+	height, _, err := amino.DecodeInt8(item.Value)
+	if err != nil {
+		panic(err)
+	}
+	return height
+}
+
+func requireEmptyStore(t *testing.T, store *Store) {
+	require.Equal(t, int64(0), getLatestVersion(store.db))
+	for _, params := range store.storesParams {
+		iter := store.storeDB(params).Iterator(nil, nil)
+		require.False(t, iter.Valid())
+		iter.Close()
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	source, commitID, items := setupSnapshotStore(t)
+
+	target := newMultiStoreWithMounts(dbm.NewMemDB())
+	require.NoError(t, restoreItems(target, commitID.Version, commitID.Hash, items))
+	require.Equal(t, commitID, target.LastCommitID())
+	for _, name := range []string{"store1", "store2"} {
+		expect := source.getStoreByName(name).(types.KVStore)
+		got := target.getStoreByName(name).(types.KVStore)
+		require.Equal(t, expect.Get([]byte("key-07")), got.Get([]byte("key-07")))
+	}
+}
+
+func TestSnapshotRestoreTampered(t *testing.T) {
+	_, commitID, items := setupSnapshotStore(t)
+
+	cases := []struct {
+		name   string
+		tamper func(items []types.SnapshotItem) []types.SnapshotItem
+	}{
+		{"leaf value", func(items []types.SnapshotItem) []types.SnapshotItem {
+			for _, item := range items {
+				if nodeHeight(item) == 0 {
+					item.Value[len(item.Value)-1]++
+					break
+				}
+			}
+			return items
+		}},
+		{"inner node key", func(items []types.SnapshotItem) []types.SnapshotItem {
+			for _, item := range items {
+				if nodeHeight(item) > 0 {
+					bz := item.Value
+					_, n, _ := amino.DecodeInt8(bz)
+					off := n
+					for i := 0; i < 2; i++ {
+						_, n, _ = amino.DecodeVarint(bz[off:])
+						off += n
+					}
+					_, n, _ = amino.DecodeByteSlice(bz[off:])
+					bz[off+n-1]++
+					break
+				}
+			}
+			return items
+		}},
+		{"missing node", func(items []types.SnapshotItem) []types.SnapshotItem {
+			for i, item := range items {
+				if nodeHeight(item) == 0 {
+					return append(items[:i], items[i+1:]...)
+				}
+			}
+			return items
+		}},
+		{"extra root", func(items []types.SnapshotItem) []types.SnapshotItem {
+			root := items[1]
+			return append(items, types.SnapshotItem{Store: root.Store, Key: []byte("r\x00\x00\x00\x00\x00\x00\x00\x09"), Value: root.Value})
+		}},
+		{"misplaced root", func(items []types.SnapshotItem) []types.SnapshotItem {
+			item := items[1]
+			item.Store = "store3"
+			return append(items, item)
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target := newMultiStoreWithMounts(dbm.NewMemDB())
+
+			require.Error(t, restoreItems(target, commitID.Version, nil, c.tamper(copyItems(items))))
+			requireEmptyStore(t, target)
+
+			// the failed restore can be retried
+			require.NoError(t, restoreItems(target, commitID.Version, commitID.Hash, items))
+			require.Equal(t, commitID, target.LastCommitID())
+		})
+	}
+
+	// untrusted app hash
+	target := newMultiStoreWithMounts(dbm.NewMemDB())
+	require.Error(t, restoreItems(target, commitID.Version, []byte("invalid"), items))
+	requireEmptyStore(t, target)
+}
@@ -96,12 +96,33 @@ func (rs *Store) GetCommitKVStore(key types.StoreKey) types.CommitKVStore {
 
 // Implements CommitMultiStore.
 func (rs *Store) LoadLatestVersion() error {
+	return rs.LoadLatestVersionAndUpgrade(nil)
+}
+
+// Implements CommitMultiStore.
+func (rs *Store) LoadLatestVersionAndUpgrade(upgrades *types.StoreUpgrades) error {
 	ver := getLatestVersion(rs.db)
-	return rs.LoadVersion(ver)
+	return rs.loadVersion(ver, upgrades)
 }
 
 // Implements CommitMultiStore.
 func (rs *Store) LoadVersion(ver int64) error {
+	return rs.loadVersion(ver, nil)
+}
+
+// Implements CommitMultiStore.
+func (rs *Store) LoadVersionAndUpgrade(ver int64, upgrades *types.StoreUpgrades) error {
+	return rs.loadVersion(ver, upgrades)
+}
+
+// loadVersion loads the stores of version ver. If upgrades is set, the mounted
+// stores must match the stores of ver, except those changed by upgrades.
+// Otherwise stores not in ver are mounted empty and stores of ver not mounted
+// are ignored.
+func (rs *Store) loadVersion(ver int64, upgrades *types.StoreUpgrades) error {
+	if err := rs.validateStoreUpgrades(upgrades); err != nil {
+		return err
+	}
 
 	// Special logic for version 0
 	if ver == 0 {
@@ -125,25 +146,47 @@ func (rs *Store) LoadVersion(ver int64) error {
 		return err
 	}
 
-	// Convert StoreInfos slice to map
-	infos := make(map[types.StoreKey]storeInfo)
+	// Convert StoreInfos slice to map, with upgrades set stores not mounted must be deleted or renamed
+	strict := upgrades != nil
+	infos := make(map[string]storeInfo)
 	for _, storeInfo := range cInfo.StoreInfos {
-		infos[rs.nameToKey(storeInfo.Name)] = storeInfo
+		infos[storeInfo.Name] = storeInfo
+		if _, ok := rs.keysByName[storeInfo.Name]; strict && !ok && !upgrades.IsDeleted(storeInfo.Name) && !upgrades.IsRenamedFrom(storeInfo.Name) {
+			return fmt.Errorf("store %s of version %d is not mounted, add it to StoreUpgrades.Deleted to drop it", storeInfo.Name, ver)
+		}
 	}
 
 	// Load each Store
 	var newStores = make(map[types.StoreKey]types.CommitStore)
 	for key, storeParams := range rs.storesParams {
-		var id types.CommitID
-		info, ok := infos[key]
-		if ok {
-			id = info.Core.CommitID
+		info, ok := infos[key.Name()]
+
+		var oldInfo storeInfo
+		oldName := upgrades.RenamedFrom(key.Name())
+		renamed := false
+		if oldName != "" {
+			oldInfo, renamed = infos[oldName]
+		}
+
+		switch {
+		case ok && renamed:
+			return fmt.Errorf("cannot rename store %s to %s: both exist in version %d", oldName, key.Name(), ver)
+		case !ok && !renamed && oldName != "":
+			return fmt.Errorf("cannot rename store %s to %s: neither exists in version %d", oldName, key.Name(), ver)
+		case strict && !ok && !renamed && !upgrades.IsAdded(key.Name()):
+			return fmt.Errorf("store %s is not in version %d, add it to StoreUpgrades.Added to mount it", key.Name(), ver)
 		}
 
-		store, err := rs.loadCommitStoreFromParams(key, id, storeParams)
+		store, err := rs.loadCommitStoreFromParams(key, info.Core.CommitID, storeParams)
 		if err != nil {
 			return fmt.Errorf("failed to load Store: %v", err)
 		}
+
+		if renamed {
+			if err := rs.moveStoreData(oldName, oldInfo.Core.CommitID, storeParams, store); err != nil {
+				return fmt.Errorf("failed to rename store %s to %s: %v", oldName, key.Name(), err)
+			}
+		}
 		newStores[key] = store
 	}
 
@@ -153,6 +196,60 @@ func (rs *Store) LoadVersion(ver int64) error {
 	return nil
 }
 
+// validateStoreUpgrades checks added and renamed stores are mounted, deleted
+// and renamed-from stores are not.
+func (rs *Store) validateStoreUpgrades(upgrades *types.StoreUpgrades) error {
+	if upgrades == nil {
+		return nil
+	}
+
+	for _, name := range upgrades.Added {
+		if _, ok := rs.keysByName[name]; !ok {
+			return fmt.Errorf("added store %s is not mounted", name)
+		}
+	}
+	for _, name := range upgrades.Deleted {
+		if _, ok := rs.keysByName[name]; ok {
+			return fmt.Errorf("deleted store %s is still mounted", name)
+		}
+	}
+	for _, rename := range upgrades.Renamed {
+		if _, ok := rs.keysByName[rename.NewKey]; !ok {
+			return fmt.Errorf("renamed store %s is not mounted", rename.NewKey)
+		}
+		if _, ok := rs.keysByName[rename.OldKey]; ok {
+			return fmt.Errorf("store %s renamed to %s is still mounted", rename.OldKey, rename.NewKey)
+		}
+	}
+	return nil
+}
+
+// moveStoreData copies all data of the unmounted store oldName at id into store.
+// The data of oldName is left in the db and dropped from the next commit.
+func (rs *Store) moveStoreData(oldName string, id types.CommitID, params storeParams, store types.CommitStore) error {
+	params.key = types.NewKVStoreKey(oldName)
+	oldStore, err := rs.loadCommitStoreFromParams(params.key, id, params)
+	if err != nil {
+		return err
+	}
+
+	oldKVStore, ok := oldStore.(types.KVStore)
+	if !ok {
+		return fmt.Errorf("store %s is not a KVStore", oldName)
+	}
+	kvStore, ok := store.(types.KVStore)
+	if !ok {
+		return fmt.Errorf("store %s is not a KVStore", params.key.Name())
+	}
+
+	iter := oldKVStore.Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		kvStore.Set(iter.Key(), iter.Value())
+	}
+	return nil
+}
+
 // SetTracer sets the tracer for the MultiStore that the underlying
 // stores will utilize to trace operations. A MultiStore is returned.
 func (rs *Store) SetTracer(w io.Writer) types.MultiStore {
@@ -233,6 +330,47 @@ func (rs *Store) CacheMultiStore() types.CacheMultiStore {
 	return cachemulti.NewStore(rs.db, stores, rs.keysByName, rs.traceWriter, rs.traceContext)
 }
 
+// Implements CommitMultiStore.
+// IAVL stores are loaded at their version committed in the given version, IAVL
+// stores mounted after it are empty. Other stores are cache wrapped at their
+// latest state.
+func (rs *Store) CacheMultiStoreWithVersion(version int64) (types.CacheMultiStore, error) {
+	if version <= 0 || version > rs.lastCommitID.Version {
+		return nil, fmt.Errorf("version %d does not exist, latest version is %d", version, rs.lastCommitID.Version)
+	}
+
+	cInfo, err := getCommitInfo(rs.db, version)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]storeInfo)
+	for _, storeInfo := range cInfo.StoreInfos {
+		infos[storeInfo.Name] = storeInfo
+	}
+
+	stores := make(map[types.StoreKey]types.CacheWrapper)
+	for k, v := range rs.stores {
+		store, ok := v.(*iavl.Store)
+		if !ok {
+			stores[k] = v
+			continue
+		}
+
+		info, ok := infos[k.Name()]
+		if !ok || info.Core.CommitID.Version == 0 {
+			stores[k] = dbadapter.Store{DB: dbm.NewMemDB()}
+			continue
+		}
+
+		immutable, err := store.GetImmutable(info.Core.CommitID.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load store %s at version %d: %v", k.Name(), version, err)
+		}
+		stores[k] = immutable
+	}
+	return cachemulti.NewStore(rs.db, stores, rs.keysByName, rs.traceWriter, rs.traceContext), nil
+}
+
 // Implements MultiStore.
 // If the store does not exist, panics.
 func (rs *Store) GetStore(key types.StoreKey) types.Store {
@@ -347,12 +485,7 @@ func parsePath(path string) (storeName string, subpath string, err errors.Error)
 //----------------------------------------
 
 func (rs *Store) loadCommitStoreFromParams(key types.StoreKey, id types.CommitID, params storeParams) (store types.CommitStore, err error) {
-	var db dbm.DB
-	if params.db != nil {
-		db = dbm.NewPrefixDB(params.db, []byte("s/_/"))
-	} else {
-		db = dbm.NewPrefixDB(rs.db, []byte("s/k:"+params.key.Name()+"/"))
-	}
+	db := rs.storeDB(params)
 	switch params.typ {
 	case types.StoreTypeMulti:
 		panic("recursive MultiStores not yet supported")
@@ -377,13 +510,12 @@ func (rs *Store) loadCommitStoreFromParams(key types.StoreKey, id types.CommitID
 	}
 }
 
-func (rs *Store) nameToKey(name string) types.StoreKey {
-	for key := range rs.storesParams {
-		if key.Name() == name {
-			return key
-		}
+// storeDB returns the prefixed db backing the store
+func (rs *Store) storeDB(params storeParams) dbm.DB {
+	if params.db != nil {
+		return dbm.NewPrefixDB(params.db, []byte("s/_/"))
 	}
-	panic("Unknown name " + name)
+	return dbm.NewPrefixDB(rs.db, []byte("s/k:"+params.key.Name()+"/"))
 }
 
 //----------------------------------------
@@ -188,6 +188,74 @@ func TestMultiStoreQuery(t *testing.T) {
 	require.Equal(t, v2, qres.Value)
 }
 
+func TestStoreUpgrades(t *testing.T) {
+	db := dbm.NewMemDB()
+	store := newMultiStoreWithMounts(db)
+	require.Nil(t, store.LoadLatestVersion())
+	store.getStoreByName("store1").(types.KVStore).Set([]byte("a"), []byte("1"))
+	store.getStoreByName("store2").(types.KVStore).Set([]byte("b"), []byte("2"))
+	store.getStoreByName("store3").(types.KVStore).Set([]byte("c"), []byte("3"))
+	store.Commit()
+	store.Commit()
+
+	// store2 renamed to store4, store5 added, store3 deleted
+	newUpgradedStore := func() *Store {
+		store := NewStore(db)
+		store.pruningOpts = types.PruneSyncable
+		store.MountStoreWithDB(types.NewKVStoreKey("store1"), types.StoreTypeIAVL, nil)
+		store.MountStoreWithDB(types.NewKVStoreKey("store4"), types.StoreTypeIAVL, nil)
+		store.MountStoreWithDB(types.NewKVStoreKey("store5"), types.StoreTypeIAVL, nil)
+		return store
+	}
+	upgrades := &types.StoreUpgrades{
+		Added:   []string{"store5"},
+		Renamed: []types.StoreRename{{OldKey: "store2", NewKey: "store4"}},
+		Deleted: []string{"store3"},
+	}
+
+	// without upgrades, new stores are mounted empty and unmounted stores are ignored
+	lenient := newUpgradedStore()
+	require.Nil(t, lenient.LoadLatestVersion())
+	require.Equal(t, int64(2), lenient.LastCommitID().Version)
+	require.Equal(t, []byte("1"), lenient.getStoreByName("store1").(types.KVStore).Get([]byte("a")))
+	require.Nil(t, lenient.getStoreByName("store4").(types.KVStore).Get([]byte("b")))
+
+	require.NotNil(t, newUpgradedStore().LoadLatestVersionAndUpgrade(&types.StoreUpgrades{Renamed: upgrades.Renamed, Deleted: upgrades.Deleted}))
+	require.NotNil(t, newUpgradedStore().LoadLatestVersionAndUpgrade(&types.StoreUpgrades{Added: upgrades.Added, Renamed: upgrades.Renamed}))
+	require.NotNil(t, newMultiStoreWithMounts(db).LoadLatestVersionAndUpgrade(upgrades))
+	// neither the old nor the new name of a renamed store exists
+	require.NotNil(t, newUpgradedStore().LoadLatestVersionAndUpgrade(&types.StoreUpgrades{
+		Added:   upgrades.Added,
+		Renamed: []types.StoreRename{{OldKey: "store9", NewKey: "store4"}},
+		Deleted: []string{"store2", "store3"},
+	}))
+
+	store = newUpgradedStore()
+	require.Nil(t, store.LoadLatestVersionAndUpgrade(upgrades))
+	require.Equal(t, int64(2), store.LastCommitID().Version)
+	require.Equal(t, []byte("2"), store.getStoreByName("store4").(types.KVStore).Get([]byte("b")))
+	require.Nil(t, store.getStoreByName("store3"))
+
+	commitID := store.Commit()
+	checkStore(t, store, getExpectedCommitID(store, 3), commitID)
+
+	// the upgrades are only applied once
+	store = newUpgradedStore()
+	require.Nil(t, store.LoadLatestVersionAndUpgrade(upgrades))
+	checkStore(t, store, commitID, store.LastCommitID())
+	require.Equal(t, []byte("1"), store.getStoreByName("store1").(types.KVStore).Get([]byte("a")))
+	require.Equal(t, []byte("2"), store.getStoreByName("store4").(types.KVStore).Get([]byte("b")))
+
+	// stores added after a version are empty at the version
+	cms, err := store.CacheMultiStoreWithVersion(2)
+	require.Nil(t, err)
+	require.Equal(t, []byte("1"), cms.GetKVStore(store.keysByName["store1"]).Get([]byte("a")))
+	require.Nil(t, cms.GetKVStore(store.keysByName["store4"]).Get([]byte("b")))
+	cms, err = store.CacheMultiStoreWithVersion(3)
+	require.Nil(t, err)
+	require.Equal(t, []byte("2"), cms.GetKVStore(store.keysByName["store4"]).Get([]byte("b")))
+}
+
 //-----------------------------------------------------------------------
 // utils
 
@@ -0,0 +1,345 @@
+package snapshots
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	amino "github.com/tendermint/go-amino"
+
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+const (
+	// SnapshotFormat is the format of the snapshot chunks: amino length prefixed SnapshotItems.
+	SnapshotFormat uint32 = 1
+
+	// DefaultChunkSize is the default maximum size of a snapshot chunk.
+	DefaultChunkSize = 10 * 1024 * 1024
+
+	metadataFile = "metadata.json"
+)
+
+var cdc = amino.NewCodec()
+
+// Snapshot describes a snapshot stored on local disk. Fields follow tendermint's abci.Snapshot.
+type Snapshot struct {
+	Height      int64    `json:"height"`
+	Format      uint32   `json:"format"`
+	Chunks      uint32   `json:"chunks"`
+	Hash        []byte   `json:"hash"`
+	ChunkHashes [][]byte `json:"chunk_hashes"`
+}
+
+// Validate checks the snapshot metadata and its hash over the chunk hashes.
+func (s Snapshot) Validate() error {
+	if s.Format != SnapshotFormat {
+		return fmt.Errorf("unsupported snapshot format %d", s.Format)
+	}
+	if s.Chunks == 0 || int(s.Chunks) != len(s.ChunkHashes) {
+		return fmt.Errorf("invalid snapshot chunks. chunks: %d, chunk hashes: %d", s.Chunks, len(s.ChunkHashes))
+	}
+	if !bytes.Equal(s.Hash, hashChunkHashes(s.ChunkHashes)) {
+		return fmt.Errorf("snapshot hash mismatch")
+	}
+	return nil
+}
+
+// Manager creates snapshots of a multistore into chunked files under dir, and restores
+// a fresh multistore from snapshot chunks.
+// Layout: <dir>/<height>/<format>/{metadata.json, 0, 1, ...}
+type Manager struct {
+	mtx        sync.Mutex
+	dir        string
+	chunkSize  int
+	multistore types.Snapshotter
+
+	// restore in progress
+	restoreSnapshot *Snapshot
+	restoreChunk    uint32
+	restoreItems    chan types.SnapshotItem
+	restoreDone     chan error
+}
+
+// NewManager creates a snapshot manager.
+func NewManager(dir string, multistore types.Snapshotter) *Manager {
+	return &Manager{
+		dir:        dir,
+		chunkSize:  DefaultChunkSize,
+		multistore: multistore,
+	}
+}
+
+// SetChunkSize sets the maximum size of snapshot chunks. Items larger than size are kept in a single chunk.
+func (m *Manager) SetChunkSize(size int) {
+	m.chunkSize = size
+}
+
+// Create exports the multistore at height into a new snapshot.
+func (m *Manager) Create(height int64) (*Snapshot, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	dir := m.snapshotDir(height, SnapshotFormat)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("snapshot at height %d already exists", height)
+	}
+
+	tmpDir := dir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := &Snapshot{
+		Height: height,
+		Format: SnapshotFormat,
+	}
+
+	var chunk bytes.Buffer
+	flush := func() error {
+		bz := chunk.Bytes()
+		path := filepath.Join(tmpDir, strconv.FormatUint(uint64(snapshot.Chunks), 10))
+		if err := ioutil.WriteFile(path, bz, 0644); err != nil {
+			return err
+		}
+		hash := sha256.Sum256(bz)
+		snapshot.ChunkHashes = append(snapshot.ChunkHashes, hash[:])
+		snapshot.Chunks++
+		chunk.Reset()
+		return nil
+	}
+
+	err := m.multistore.Export(height, func(item types.SnapshotItem) error {
+		bz, err := cdc.MarshalBinaryLengthPrefixed(item)
+		if err != nil {
+			return err
+		}
+		if chunk.Len() > 0 && chunk.Len()+len(bz) > m.chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		chunk.Write(bz)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chunk.Len() > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	snapshot.Hash = hashChunkHashes(snapshot.ChunkHashes)
+
+	bz, err := cdc.MarshalJSONIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, metadataFile), bz, 0644); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Get returns the snapshot at height with format.
+func (m *Manager) Get(height int64, format uint32) (*Snapshot, error) {
+	bz, err := ioutil.ReadFile(filepath.Join(m.snapshotDir(height, format), metadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := cdc.UnmarshalJSON(bz, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// List returns all snapshots on disk, latest first.
+func (m *Manager) List() ([]*Snapshot, error) {
+	heightDirs, err := ioutil.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Snapshot
+	for _, heightDir := range heightDirs {
+		height, err := strconv.ParseInt(heightDir.Name(), 10, 64)
+		if err != nil || !heightDir.IsDir() {
+			continue
+		}
+
+		formatDirs, err := ioutil.ReadDir(filepath.Join(m.dir, heightDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, formatDir := range formatDirs {
+			format, err := strconv.ParseUint(formatDir.Name(), 10, 32)
+			if err != nil || !formatDir.IsDir() {
+				continue
+			}
+			snapshot, err := m.Get(height, uint32(format))
+			if err != nil {
+				return nil, err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Height == snapshots[j].Height {
+			return snapshots[i].Format > snapshots[j].Format
+		}
+		return snapshots[i].Height > snapshots[j].Height
+	})
+	return snapshots, nil
+}
+
+// Prune deletes all but the latest retain snapshots.
+func (m *Manager) Prune(retain int) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	snapshots, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for i := retain; i < len(snapshots); i++ {
+		if err := os.RemoveAll(filepath.Join(m.dir, strconv.FormatInt(snapshots[i].Height, 10))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadChunk returns the content of a snapshot chunk.
+func (m *Manager) LoadChunk(height int64, format uint32, chunk uint32) ([]byte, error) {
+	path := filepath.Join(m.snapshotDir(height, format), strconv.FormatUint(uint64(chunk), 10))
+	return ioutil.ReadFile(path)
+}
+
+// Restore begins restoring snapshot into the multistore, chunks are then applied via RestoreChunk.
+// appHash is the trusted app hash of the snapshot height, the restored commitInfo hash is verified against it.
+func (m *Manager) Restore(snapshot Snapshot, appHash []byte) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.restoreSnapshot != nil {
+		return fmt.Errorf("snapshot restore already in progress")
+	}
+	if err := snapshot.Validate(); err != nil {
+		return err
+	}
+
+	items := make(chan types.SnapshotItem)
+	done := make(chan error, 1)
+	go func() {
+		done <- m.multistore.Restore(snapshot.Height, appHash, items)
+	}()
+
+	m.restoreSnapshot = &snapshot
+	m.restoreChunk = 0
+	m.restoreItems = items
+	m.restoreDone = done
+	return nil
+}
+
+// RestoreChunk applies the next chunk of the snapshot being restored. It returns true when
+// all chunks were applied and the restored state verified.
+// A chunk with mismatched hash is rejected without aborting the restore, so it can be refetched.
+func (m *Manager) RestoreChunk(chunk []byte) (bool, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.restoreSnapshot == nil {
+		return false, fmt.Errorf("no snapshot restore in progress")
+	}
+
+	hash := sha256.Sum256(chunk)
+	if !bytes.Equal(hash[:], m.restoreSnapshot.ChunkHashes[m.restoreChunk]) {
+		return false, fmt.Errorf("snapshot chunk %d hash mismatch", m.restoreChunk)
+	}
+
+	for len(chunk) > 0 {
+		size, n, err := amino.DecodeUvarint(chunk)
+		if err != nil || uint64(len(chunk)-n) < size {
+			m.abortRestore()
+			return false, fmt.Errorf("invalid snapshot chunk %d", m.restoreChunk)
+		}
+
+		var item types.SnapshotItem
+		if err := cdc.UnmarshalBinaryBare(chunk[n:n+int(size)], &item); err != nil {
+			m.abortRestore()
+			return false, fmt.Errorf("invalid snapshot chunk %d: %v", m.restoreChunk, err)
+		}
+		chunk = chunk[n+int(size):]
+
+		select {
+		case m.restoreItems <- item:
+		case err := <-m.restoreDone:
+			m.resetRestore()
+			if err == nil {
+				err = fmt.Errorf("snapshot restore stopped unexpectedly")
+			}
+			return false, err
+		}
+	}
+
+	m.restoreChunk++
+	if m.restoreChunk < m.restoreSnapshot.Chunks {
+		return false, nil
+	}
+
+	close(m.restoreItems)
+	err := <-m.restoreDone
+	m.resetRestore()
+	return err == nil, err
+}
+
+func (m *Manager) abortRestore() {
+	close(m.restoreItems)
+	<-m.restoreDone
+	m.resetRestore()
+}
+
+func (m *Manager) resetRestore() {
+	m.restoreSnapshot = nil
+	m.restoreChunk = 0
+	m.restoreItems = nil
+	m.restoreDone = nil
+}
+
+func (m *Manager) snapshotDir(height int64, format uint32) string {
+	return filepath.Join(m.dir, strconv.FormatInt(height, 10), strconv.FormatUint(uint64(format), 10))
+}
+
+func hashChunkHashes(chunkHashes [][]byte) []byte {
+	hasher := sha256.New()
+	for _, hash := range chunkHashes {
+		hasher.Write(hash)
+	}
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,181 @@
+package snapshots
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/QOSGroup/qbase/store/rootmulti"
+	"github.com/QOSGroup/qbase/store/types"
+)
+
+var storeKeys = []*types.KVStoreKey{
+	types.NewKVStoreKey("store1"),
+	types.NewKVStoreKey("store2"),
+}
+
+func newMultiStore(db dbm.DB) *rootmulti.Store {
+	ms := rootmulti.NewStore(db)
+	ms.SetPruning(types.PruneNothing)
+	for _, key := range storeKeys {
+		ms.MountStoreWithDB(key, types.StoreTypeIAVL, nil)
+	}
+	if err := ms.LoadLatestVersion(); err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+func setupMultiStore(t *testing.T, versions int) (dbm.DB, *rootmulti.Store, []types.CommitID) {
+	db := dbm.NewMemDB()
+	ms := newMultiStore(db)
+	var commitIDs []types.CommitID
+	for v := 0; v < versions; v++ {
+		for i, key := range storeKeys {
+			store := ms.GetKVStore(key)
+			for j := 0; j < 50; j++ {
+				store.Set([]byte(fmt.Sprintf("key-%d-%d", i, j)), []byte(fmt.Sprintf("value-%d-%d", v, j)))
+			}
+			store.Delete([]byte(fmt.Sprintf("key-%d-%d", i, v)))
+		}
+		commitIDs = append(commitIDs, ms.Commit())
+	}
+	return db, ms, commitIDs
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "snapshots")
+	require.NoError(t, err)
+	return dir
+}
+
+func restore(t *testing.T, source *Manager, snapshot *Snapshot, target *Manager, appHash []byte) (bool, error) {
+	require.NoError(t, target.Restore(*snapshot, appHash))
+
+	var done bool
+	var err error
+	for i := uint32(0); i < snapshot.Chunks; i++ {
+		chunk, e := source.LoadChunk(snapshot.Height, snapshot.Format, i)
+		require.NoError(t, e)
+		done, err = target.RestoreChunk(chunk)
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, err
+}
+
+func TestSnapshotCreateAndRestore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, ms, commitIDs := setupMultiStore(t, 3)
+	manager := NewManager(dir, ms)
+	manager.SetChunkSize(1024)
+
+	snapshot, err := manager.Create(2)
+	require.NoError(t, err)
+	require.True(t, snapshot.Chunks > 1)
+	require.NoError(t, snapshot.Validate())
+
+	_, err = manager.Create(2)
+	require.Error(t, err)
+	_, err = manager.Create(10)
+	require.Error(t, err)
+
+	snapshots, err := manager.List()
+	require.NoError(t, err)
+	require.Equal(t, []*Snapshot{snapshot}, snapshots)
+
+	//restore a fresh store
+	target := newMultiStore(dbm.NewMemDB())
+	targetManager := NewManager(tempDir(t), target)
+	defer os.RemoveAll(targetManager.dir)
+
+	appHash := commitIDs[1].Hash
+	done, err := restore(t, manager, snapshot, targetManager, appHash)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	require.Equal(t, int64(2), target.LastCommitID().Version)
+	require.Equal(t, appHash, target.LastCommitID().Hash)
+
+	old := newMultiStore(db)
+	require.NoError(t, old.LoadVersion(2))
+	for _, key := range storeKeys {
+		expect := old.GetKVStore(key).Iterator(nil, nil)
+		got := target.GetKVStore(key).Iterator(nil, nil)
+		for ; expect.Valid(); expect.Next() {
+			require.True(t, got.Valid())
+			require.Equal(t, expect.Key(), got.Key())
+			require.Equal(t, expect.Value(), got.Value())
+			got.Next()
+		}
+		require.False(t, got.Valid())
+		expect.Close()
+		got.Close()
+	}
+
+	//restored store continues committing
+	target.GetKVStore(storeKeys[0]).Set([]byte("new"), []byte("value"))
+	require.Equal(t, int64(3), target.Commit().Version)
+}
+
+func TestSnapshotRestoreInvalid(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	_, ms, _ := setupMultiStore(t, 2)
+	manager := NewManager(dir, ms)
+	manager.SetChunkSize(1024)
+
+	snapshot, err := manager.Create(2)
+	require.NoError(t, err)
+
+	//corrupted chunk is rejected
+	targetManager := NewManager(tempDir(t), newMultiStore(dbm.NewMemDB()))
+	defer os.RemoveAll(targetManager.dir)
+	require.NoError(t, targetManager.Restore(*snapshot, nil))
+	chunk, err := manager.LoadChunk(snapshot.Height, snapshot.Format, 0)
+	require.NoError(t, err)
+	chunk[len(chunk)-1]++
+	_, err = targetManager.RestoreChunk(chunk)
+	require.Error(t, err)
+	require.Error(t, targetManager.Restore(*snapshot, nil))
+
+	//untrusted app hash
+	targetManager = NewManager(tempDir(t), newMultiStore(dbm.NewMemDB()))
+	defer os.RemoveAll(targetManager.dir)
+	done, err := restore(t, manager, snapshot, targetManager, []byte("invalid app hash"))
+	require.Error(t, err)
+	require.False(t, done)
+
+	//invalid snapshot metadata
+	invalid := *snapshot
+	invalid.Hash = []byte("invalid")
+	require.Error(t, targetManager.Restore(invalid, nil))
+}
+
+func TestSnapshotPrune(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	_, ms, _ := setupMultiStore(t, 3)
+	manager := NewManager(dir, ms)
+
+	for h := int64(1); h <= 3; h++ {
+		_, err := manager.Create(h)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, manager.Prune(2))
+	snapshots, err := manager.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, int64(3), snapshots[0].Height)
+	require.Equal(t, int64(2), snapshots[1].Height)
+}
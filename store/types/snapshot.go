@@ -0,0 +1,20 @@
+package types
+
+// SnapshotItem is a single entry of a store snapshot.
+// Store is the name of the mounted store, Key/Value are the raw entries of its underlying db.
+// An item with empty Store carries the commitInfo of the snapshot height.
+type SnapshotItem struct {
+	Store string `json:"store"`
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Snapshotter exports and restores the state of a CommitMultiStore at a height.
+type Snapshotter interface {
+	// Export streams all items of the stores at height to fn.
+	Export(height int64, fn func(item SnapshotItem) error) error
+
+	// Restore writes items into an empty store, loads height and verifies the
+	// resulting commitInfo hash against appHash.
+	Restore(height int64, appHash []byte, items <-chan SnapshotItem) error
+}
@@ -75,6 +75,7 @@ type CacheMultiStore interface {
 type CommitMultiStore interface {
 	Committer
 	MultiStore
+	Snapshotter
 
 	// Mount a store of type using the given db.
 	// If db == nil, the new store will use the CommitMultiStore db.
@@ -95,6 +96,18 @@ type CommitMultiStore interface {
 	// the next commit after loading must be idempotent (return the
 	// same commit id).  Otherwise the behavior is undefined.
 	LoadVersion(ver int64) error
+
+	// LoadLatestVersionAndUpgrade loads the latest persisted version, applying
+	// the pending store upgrades. upgrades may be nil. See StoreUpgrades.
+	LoadLatestVersionAndUpgrade(upgrades *StoreUpgrades) error
+
+	// LoadVersionAndUpgrade loads a specific persisted version, applying the
+	// pending store upgrades. upgrades may be nil. See StoreUpgrades.
+	LoadVersionAndUpgrade(ver int64, upgrades *StoreUpgrades) error
+
+	// Cache wrap the stores at the given committed version for read-only
+	// queries. Returns an error if the version does not exist.
+	CacheMultiStoreWithVersion(version int64) (CacheMultiStore, error)
 }
 
 //---------subsp-------------------------------
@@ -0,0 +1,76 @@
+package types
+
+// StoreUpgrades defines the changes of mounted stores applied when loading a
+// version. Each change is only applied if it is still pending in the loaded
+// version, so the same StoreUpgrades can be passed on every restart.
+//
+// Added stores start empty. Renamed stores move all data of OldKey into the
+// mounted NewKey store. Deleted stores are dropped from the commit info, their
+// data is left in the db.
+//
+// Once StoreUpgrades is set, all changes of mounted stores since the loaded
+// version must be listed. Without it, stores not in the loaded version are
+// mounted empty and stores of the loaded version not mounted are ignored.
+type StoreUpgrades struct {
+	Added   []string      `json:"added"`
+	Renamed []StoreRename `json:"renamed"`
+	Deleted []string      `json:"deleted"`
+}
+
+// StoreRename defines a store renamed from OldKey to NewKey.
+type StoreRename struct {
+	OldKey string `json:"old_key"`
+	NewKey string `json:"new_key"`
+}
+
+// IsAdded returns true if the given store is added.
+func (s *StoreUpgrades) IsAdded(key string) bool {
+	if s == nil {
+		return false
+	}
+	for _, added := range s.Added {
+		if key == added {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeleted returns true if the given store is deleted.
+func (s *StoreUpgrades) IsDeleted(key string) bool {
+	if s == nil {
+		return false
+	}
+	for _, deleted := range s.Deleted {
+		if key == deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// RenamedFrom returns the old name of the given store, or "" if it is not renamed.
+func (s *StoreUpgrades) RenamedFrom(key string) string {
+	if s == nil {
+		return ""
+	}
+	for _, rename := range s.Renamed {
+		if rename.NewKey == key {
+			return rename.OldKey
+		}
+	}
+	return ""
+}
+
+// IsRenamedFrom returns true if the given store is renamed to another store.
+func (s *StoreUpgrades) IsRenamedFrom(key string) bool {
+	if s == nil {
+		return false
+	}
+	for _, rename := range s.Renamed {
+		if rename.OldKey == key {
+			return true
+		}
+	}
+	return false
+}
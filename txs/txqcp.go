@@ -3,10 +3,13 @@ package txs
 import (
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/QOSGroup/qbase/types"
 	"github.com/pkg/errors"
 	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 // 功能：
@@ -20,6 +23,23 @@ type TxQcp struct {
 	TxIndex     int64     `json:"txindex"`     //Tx在block的位置
 	IsResult    bool      `json:"isresult"`    //是否为Result
 	Extends     string    `json:"extends"`     //扩展字段
+
+	//超时设置, 以目标链区块高度/区块时间(unix秒)为准, 为0时不超时
+	//目标链在区块高度>=TimeoutHeight或区块时间>=TimeoutTime时不再执行该tx
+	TimeoutHeight int64 `json:"timeout_height,omitempty"`
+	TimeoutTime   int64 `json:"timeout_time,omitempty"`
+
+	Proof *TxQcpProof `json:"proof,omitempty"` //轻客户端验证模式下, tx在来源链中的存在性证明. 不参与签名
+}
+
+//TxQcpProof TxQcp存在性证明:
+//Value为来源链tx/out/[To]/[Sequence]下保存的TxQcp, Proof为Value在Header.AppHash下的merkle证明
+//Header为来源链Height+1高度的区块头, 其AppHash为Height高度commit后的状态, 由Validators签名
+type TxQcpProof struct {
+	Header     tmtypes.SignedHeader  `json:"header"`
+	Validators *tmtypes.ValidatorSet `json:"validators"`
+	Value      []byte                `json:"value"`
+	Proof      *merkle.Proof         `json:"proof"`
 }
 
 var _ types.Tx = (*TxQcp)(nil)
@@ -43,7 +63,11 @@ func (tx *TxQcp) getSigData() []byte {
 	ret = append(ret, types.Int2Byte(tx.BlockHeight)...)
 	ret = append(ret, types.Int2Byte(tx.TxIndex)...)
 	ret = append(ret, types.Bool2Byte(tx.IsResult)...)
+	//Extends长度(8字节) + Extends + 超时高度(8字节) + 超时时间(8字节), 避免Extends与超时设置相互转换后签名仍有效
+	ret = append(ret, types.Int2Byte(int64(len(tx.Extends)))...)
 	ret = append(ret, []byte(tx.Extends)...)
+	ret = append(ret, types.Int2Byte(tx.TimeoutHeight)...)
+	ret = append(ret, types.Int2Byte(tx.TimeoutTime)...)
 	return ret
 }
 
@@ -78,6 +102,9 @@ func NewTxQCP(txStd *TxStd, from string, to string, sequence int64,
 		txIndex,
 		isResult,
 		extends,
+		0,
+		0,
+		nil,
 	}
 
 	return
@@ -93,7 +120,12 @@ func (tx *TxQcp) ValidateBasicData(isCheckTx bool, currentChainID string) (err t
 			tx.From, tx.To, tx.Sequence, tx.BlockHeight, tx.TxIndex))
 	}
 
-	if len(tx.Sig.Signature) == 0 {
+	//轻客户端验证模式下由Proof保证tx合法性, 不要求签名
+	if tx.TimeoutHeight < 0 || tx.TimeoutTime < 0 {
+		return types.ErrInternal(fmt.Sprintf("TxQcp's timeout is not valid. height: %d, time: %d", tx.TimeoutHeight, tx.TimeoutTime))
+	}
+
+	if len(tx.Sig.Signature) == 0 && tx.Proof == nil {
 		return types.ErrInternal("TxQcp's Signature is empty")
 	}
 
@@ -112,3 +144,9 @@ func (tx *TxQcp) GenTxHash() string {
 	bz := crypto.Sha256(tx.BuildSignatureBytes())
 	return hex.EncodeToString(bz)
 }
+
+//IsTimeout 按区块高度及区块时间判断tx是否已超时
+func (tx *TxQcp) IsTimeout(height int64, blockTime time.Time) bool {
+	return (tx.TimeoutHeight > 0 && height >= tx.TimeoutHeight) ||
+		(tx.TimeoutTime > 0 && blockTime.Unix() >= tx.TimeoutTime)
+}
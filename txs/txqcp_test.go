@@ -128,3 +128,22 @@ func TestTxQcp(t *testing.T) {
 	data := txqcp.BuildSignatureBytes()
 	require.NotNil(t, data)
 }
+
+func TestTxQcp_ExtendsAndTimeoutSignData(t *testing.T) {
+	txStd := NewTxStd(&mockITX{1, false}, "qsc1", types.NewInt(100))
+	txqcp := NewTxQCP(txStd, "qsc1", "qos", 1, 10, 0, false, "extends")
+	txqcp.TimeoutHeight = 100
+	txqcp.TimeoutTime = 1000
+	signData := txqcp.BuildSignatureBytes()
+
+	//超时设置不能移入Extends
+	stripped := NewTxQCP(txStd, "qsc1", "qos", 1, 10, 0, false,
+		"extends"+string(types.Int2Byte(100))+string(types.Int2Byte(1000)))
+	require.NotEqual(t, signData, stripped.BuildSignatureBytes())
+
+	//Extends结尾不能转换为超时设置
+	moved := NewTxQCP(txStd, "qsc1", "qos", 1, 10, 0, false, "ext")
+	moved.TimeoutHeight = 100
+	require.NotEqual(t, NewTxQCP(txStd, "qsc1", "qos", 1, 10, 0, false, "ext"+string(types.Int2Byte(100))+string(types.Int2Byte(0))).BuildSignatureBytes(),
+		moved.BuildSignatureBytes())
+}
@@ -29,6 +29,9 @@ type TxStd struct {
 	Signature []Signature  `json:"sigature"` //签名数组
 	ChainID   string       `json:"chainid"`  //ChainID: 执行ITx.exec方法的链ID
 	MaxGas    types.BigInt `json:"maxgas"`   //Gas消耗的最大值
+
+	Memo          string `json:"memo,omitempty"`           //备注, 长度不超过ctx.MaxMemoLength()
+	TimeoutHeight int64  `json:"timeout_height,omitempty"` //大于0时, 区块高度超过该值后tx不再有效
 }
 
 var _ types.Tx = (*TxStd)(nil)
@@ -99,6 +102,14 @@ func (tx *TxStd) getSignData() []byte {
 	ret = append(ret, []byte(tx.ChainID)...)
 	ret = append(ret, types.Int2Byte(tx.MaxGas.Int64())...)
 
+	//兼容未设置memo及超时高度的tx签名
+	//设置任一字段时均写入: memo长度(8字节) + memo + 超时高度(8字节), 避免memo与超时高度相互转换后签名仍有效
+	if tx.Memo != "" || tx.TimeoutHeight != 0 {
+		ret = append(ret, types.Int2Byte(int64(len(tx.Memo)))...)
+		ret = append(ret, []byte(tx.Memo)...)
+		ret = append(ret, types.Int2Byte(tx.TimeoutHeight)...)
+	}
+
 	return ret
 }
 
@@ -132,10 +143,10 @@ func NewTxStd(itx ITx, cid string, maxGas types.BigInt) (rTx *TxStd) {
 
 func NewTxsStd(cid string, maxGas types.BigInt, itx ...ITx) (rTx *TxStd) {
 	rTx = &TxStd{
-		itx,
-		[]Signature{},
-		cid,
-		maxGas,
+		ITxs:      itx,
+		Signature: []Signature{},
+		ChainID:   cid,
+		MaxGas:    maxGas,
 	}
 
 	return
@@ -180,5 +191,22 @@ func (tx *TxStd) ValidateBasicData(ctx context.Context, isCheckTx bool, currentC
 		return types.ErrInternal("TxStd's MaxGas is less than zero")
 	}
 
+	if len(tx.Memo) > ctx.MaxMemoLength() {
+		return types.ErrMemoTooLarge(fmt.Sprintf("TxStd's Memo is too large. max length: %d , actual: %d", ctx.MaxMemoLength(), len(tx.Memo)))
+	}
+
+	if tx.TimeoutHeight < 0 {
+		return types.ErrInternal("TxStd's TimeoutHeight is less than zero")
+	}
+
+	//checkTx阶段tx最早在下一区块执行
+	height := ctx.BlockHeight()
+	if isCheckTx {
+		height++
+	}
+	if tx.TimeoutHeight > 0 && height > tx.TimeoutHeight {
+		return types.ErrTxTimeout(fmt.Sprintf("TxStd is timeout. timeout height: %d , current height: %d", tx.TimeoutHeight, height))
+	}
+
 	return
 }
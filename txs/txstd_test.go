@@ -1,12 +1,17 @@
 package txs
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/store"
 	"github.com/QOSGroup/qbase/types"
 	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
 )
 
 func TestTxStd_GetSigners(t *testing.T) {
@@ -101,3 +106,59 @@ func getAddress() types.AccAddress {
 	addr := types.AccAddress(pub.Address())
 	return addr
 }
+
+func TestTxStd_MemoAndTimeoutHeight(t *testing.T) {
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(types.NewKVStoreKey("test"), types.StoreTypeIAVL, db)
+	cms.LoadLatestVersion()
+	ctx := context.NewContext(cms, abci.Header{Height: 10}, false, log.NewNopLogger(), nil)
+
+	txStd := NewTxStd(&mockITX{1, false}, "qsc1", types.NewInt(100))
+	signData := txStd.getSignData()
+	require.Nil(t, txStd.ValidateBasicData(ctx, false, "qsc1"))
+
+	//memo及超时高度参与签名
+	txStd.Memo = "deposit"
+	require.NotEqual(t, signData, txStd.getSignData())
+	signData = txStd.getSignData()
+	txStd.TimeoutHeight = 10
+	require.NotEqual(t, signData, txStd.getSignData())
+	require.Nil(t, txStd.ValidateBasicData(ctx, false, "qsc1"))
+
+	//checkTx时tx最早在下一区块执行
+	err := txStd.ValidateBasicData(ctx, true, "qsc1")
+	require.NotNil(t, err)
+	require.Equal(t, types.CodeTxTimeout, err.Code())
+
+	txStd.TimeoutHeight = -1
+	require.NotNil(t, txStd.ValidateBasicData(ctx, false, "qsc1"))
+	txStd.TimeoutHeight = 0
+
+	txStd.Memo = strings.Repeat("m", context.DefaultMaxMemoLength+1)
+	err = txStd.ValidateBasicData(ctx, false, "qsc1")
+	require.NotNil(t, err)
+	require.Equal(t, types.CodeMemoTooLarge, err.Code())
+	require.Nil(t, txStd.ValidateBasicData(ctx.WithMaxMemoLength(context.DefaultMaxMemoLength+1), false, "qsc1"))
+}
+
+func TestTxStd_MemoAndTimeoutHeightSignData(t *testing.T) {
+	txStd := NewTxStd(&mockITX{1, false}, "qsc1", types.NewInt(100))
+	txStd.Memo = "deposit"
+	txStd.TimeoutHeight = 10
+	signData := txStd.getSignData()
+
+	//超时高度不能移入memo
+	stripped := NewTxStd(&mockITX{1, false}, "qsc1", types.NewInt(100))
+	stripped.Memo = "deposit" + string(types.Int2Byte(10))
+	require.NotEqual(t, signData, stripped.getSignData())
+
+	//memo结尾不能转换为超时高度
+	txStd.TimeoutHeight = 0
+	txStd.Memo = "deposit" + string(types.Int2Byte(10))
+	signData = txStd.getSignData()
+	moved := NewTxStd(&mockITX{1, false}, "qsc1", types.NewInt(100))
+	moved.Memo = "deposit"
+	moved.TimeoutHeight = 10
+	require.NotEqual(t, signData, moved.getSignData())
+}
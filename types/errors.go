@@ -43,6 +43,8 @@ const (
 	CodeMemoTooLarge      CodeType = 13
 	CodeInsufficientFee   CodeType = 14
 	CodeTooManySignatures CodeType = 15
+	CodeQcpTimeout        CodeType = 16
+	CodeTxTimeout         CodeType = 17
 
 	// CodespaceRoot is a codespace for error codes in this file only.
 	// Notice that 0 is an "unset" codespace, which can be overridden with
@@ -88,6 +90,10 @@ func CodeToDefaultMsg(code CodeType) string {
 		return "insufficient fee"
 	case CodeTooManySignatures:
 		return "maximum numer of signatures exceeded"
+	case CodeQcpTimeout:
+		return "qcp tx timeout"
+	case CodeTxTimeout:
+		return "tx timeout"
 	default:
 		return unknownCodeMsg(code)
 	}
@@ -143,6 +149,12 @@ func ErrInsufficientFee(msg string) Error {
 func ErrTooManySignatures(msg string) Error {
 	return newErrorWithRootCodespace(CodeTooManySignatures, msg)
 }
+func ErrQcpTimeout(msg string) Error {
+	return newErrorWithRootCodespace(CodeQcpTimeout, msg)
+}
+func ErrTxTimeout(msg string) Error {
+	return newErrorWithRootCodespace(CodeTxTimeout, msg)
+}
 
 //----------------------------------------
 // Error & sdkError
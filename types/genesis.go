@@ -1,10 +1,16 @@
 package types
 
-import "github.com/tendermint/tendermint/crypto"
+import (
+	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
 
 // app_state in genesis.json
 type GenesisState struct {
 	QCPs []*QCPConfig `json:"qcps"`
+
+	//可通过TxSetTrustKeys, TxRevokeTrustKey管理qcp信任公钥的管理员账户
+	QCPAdmin AccAddress `json:"qcp_admin,omitempty"`
 }
 
 // QCP配置
@@ -12,4 +18,33 @@ type QCPConfig struct {
 	Name    string        `json:"name"`
 	ChainId string        `json:"chain_id"`
 	PubKey  crypto.PubKey `json:"pub_key"`
+
+	//导出状态时保存的跨链序号
+	InSequence  int64 `json:"in_sequence,omitempty"`
+	OutSequence int64 `json:"out_sequence,omitempty"`
+
+	//来源链可信的验证人集合, 设置后使用轻客户端模式校验来自该链的TxQcp
+	Validators []tmtypes.GenesisValidator `json:"validators,omitempty"`
+}
+
+//ValidatorSet 返回Validators对应的验证人集合
+func (qcp *QCPConfig) ValidatorSet() *tmtypes.ValidatorSet {
+	vals := make([]*tmtypes.Validator, 0, len(qcp.Validators))
+	for _, val := range qcp.Validators {
+		vals = append(vals, tmtypes.NewValidator(val.PubKey, val.Power))
+	}
+	return tmtypes.NewValidatorSet(vals)
+}
+
+//NewGenesisValidators 将验证人集合转换为genesis中的验证人
+func NewGenesisValidators(valSet *tmtypes.ValidatorSet) []tmtypes.GenesisValidator {
+	validators := make([]tmtypes.GenesisValidator, 0, valSet.Size())
+	for _, val := range valSet.Validators {
+		validators = append(validators, tmtypes.GenesisValidator{
+			Address: val.Address,
+			PubKey:  val.PubKey,
+			Power:   val.VotingPower,
+		})
+	}
+	return validators
 }
@@ -50,6 +50,20 @@ type ABCIMessageLog struct {
 	MsgIndex uint16 `json:"msg_index"`
 	Success  bool   `json:"success"`
 	Log      string `json:"log"`
+
+	// Events contains a slice of Event objects that were emitted during the
+	// execution of the indexed message.
+	Events StringEvents `json:"events"`
+}
+
+// NewABCIMessageLog returns the log of the i-th message of a tx.
+func NewABCIMessageLog(i uint16, success bool, log string, events Events) ABCIMessageLog {
+	return ABCIMessageLog{
+		MsgIndex: i,
+		Success:  success,
+		Log:      log,
+		Events:   StringifyEvents(events.ToABCIEvents()),
+	}
 }
 
 // String implements the fmt.Stringer interface for the ABCIMessageLogs type.
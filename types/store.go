@@ -22,6 +22,8 @@ type (
 	CommitMultiStore = types.CommitMultiStore
 	KVStore          = types.KVStore
 	Iterator         = types.Iterator
+	StoreUpgrades    = types.StoreUpgrades
+	StoreRename      = types.StoreRename
 )
 
 // Iterator over all the keys with a certain prefix in ascending order
@@ -0,0 +1,10 @@
+package upgrade
+
+import (
+	go_amino "github.com/tendermint/go-amino"
+)
+
+func RegisterCodec(cdc *go_amino.Codec) {
+	cdc.RegisterConcrete(&TxScheduleUpgrade{}, "qbase/upgrade/TxScheduleUpgrade", nil)
+	cdc.RegisterConcrete(&TxCancelUpgrade{}, "qbase/upgrade/TxCancelUpgrade", nil)
+}
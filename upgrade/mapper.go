@@ -0,0 +1,150 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/types"
+	go_amino "github.com/tendermint/go-amino"
+)
+
+//链升级:
+//authority通过TxScheduleUpgrade设置升级计划, 计划高度的BeginBlock中:
+//未注册该计划UpgradeHandler的旧版本程序panic并停止出块; 新版本程序执行UpgradeHandler迁移数据, 每个计划只执行一次
+const (
+	MapperName = "upgrade"
+	//升级授权地址, 在InitChainer中设置
+	authorityKey = "authority"
+	//待执行的升级计划
+	planKey = "plan"
+	//已执行的升级计划高度
+	donePrefixKey = "done/"
+	doneKey       = donePrefixKey + "%s"
+)
+
+func BuildUpgradeStoreQueryPath() []byte {
+	return []byte(fmt.Sprintf("/store/%s/key", MapperName))
+}
+
+func BuildAuthorityKey() []byte {
+	return []byte(authorityKey)
+}
+
+func BuildPlanKey() []byte {
+	return []byte(planKey)
+}
+
+func BuildDoneKey(name string) []byte {
+	return []byte(fmt.Sprintf(doneKey, name))
+}
+
+func BuildDonePrefixKey() []byte {
+	return []byte(donePrefixKey)
+}
+
+//Plan 升级计划
+type Plan struct {
+	Name   string `json:"name"`   //计划名称, 新版本程序按名称注册UpgradeHandler
+	Height int64  `json:"height"` //执行高度
+	Info   string `json:"info"`   //升级说明, 如新版本程序下载地址
+}
+
+func (p Plan) ValidateBasic() error {
+	if len(p.Name) == 0 {
+		return errors.New("plan name is empty")
+	}
+	if p.Height <= 0 {
+		return errors.New("plan height must be greater than 0")
+	}
+	return nil
+}
+
+//ShouldExecute 高度height时是否执行计划
+func (p Plan) ShouldExecute(height int64) bool {
+	return height >= p.Height
+}
+
+func (p Plan) String() string {
+	return fmt.Sprintf("Upgrade Plan{Name: %s, Height: %d, Info: %s}", p.Name, p.Height, p.Info)
+}
+
+type UpgradeMapper struct {
+	*mapper.BaseMapper
+}
+
+var _ mapper.IMapper = (*UpgradeMapper)(nil)
+
+func NewUpgradeMapper(cdc *go_amino.Codec) *UpgradeMapper {
+	var upgradeMapper = UpgradeMapper{}
+	upgradeMapper.BaseMapper = mapper.NewBaseMapper(cdc, MapperName)
+	return &upgradeMapper
+}
+
+func (mapper *UpgradeMapper) Copy() mapper.IMapper {
+	cpyMapper := &UpgradeMapper{}
+	cpyMapper.BaseMapper = mapper.BaseMapper.Copy()
+	return cpyMapper
+}
+
+func (mapper *UpgradeMapper) GetAuthority() (authority types.AccAddress, exists bool) {
+	exists = mapper.Get(BuildAuthorityKey(), &authority)
+	return
+}
+
+//SetAuthority 设置可设置升级计划的地址, 一般在InitChainer中调用
+func (mapper *UpgradeMapper) SetAuthority(authority types.AccAddress) {
+	mapper.Set(BuildAuthorityKey(), authority)
+}
+
+func (mapper *UpgradeMapper) GetPlan() (plan Plan, exists bool) {
+	exists = mapper.Get(BuildPlanKey(), &plan)
+	return
+}
+
+//ScheduleUpgrade 设置升级计划, 覆盖未执行的计划
+func (mapper *UpgradeMapper) ScheduleUpgrade(ctx context.Context, plan Plan) error {
+	if err := plan.ValidateBasic(); err != nil {
+		return err
+	}
+	if plan.Height <= ctx.BlockHeight() {
+		return fmt.Errorf("plan height %d must be greater than current height %d", plan.Height, ctx.BlockHeight())
+	}
+	if height, done := mapper.GetDoneHeight(plan.Name); done {
+		return fmt.Errorf("upgrade %s has been applied at height %d", plan.Name, height)
+	}
+
+	mapper.Set(BuildPlanKey(), plan)
+	return nil
+}
+
+func (mapper *UpgradeMapper) ClearPlan() {
+	mapper.Del(BuildPlanKey())
+}
+
+//GetDoneHeight 返回名称为name的计划的执行高度
+func (mapper *UpgradeMapper) GetDoneHeight(name string) (height int64, exists bool) {
+	exists = mapper.Get(BuildDoneKey(name), &height)
+	return
+}
+
+//ApplyUpgrade 记录计划已执行并删除计划, 在UpgradeHandler执行后调用
+func (mapper *UpgradeMapper) ApplyUpgrade(ctx context.Context, plan Plan) {
+	mapper.Set(BuildDoneKey(plan.Name), ctx.BlockHeight())
+	mapper.ClearPlan()
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeUpgrade,
+		types.NewAttribute(AttributeKeyName, plan.Name),
+		types.NewAttribute(AttributeKeyHeight, fmt.Sprintf("%d", ctx.BlockHeight())),
+	))
+}
+
+func GetUpgradeMapper(ctx context.Context) *UpgradeMapper {
+	mapper := ctx.Mapper(MapperName)
+	if mapper == nil {
+		return nil
+	}
+	return mapper.(*UpgradeMapper)
+}
@@ -0,0 +1,96 @@
+package upgrade
+
+import (
+	"testing"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/mapper"
+	"github.com/QOSGroup/qbase/store"
+	"github.com/QOSGroup/qbase/types"
+	"github.com/stretchr/testify/require"
+	go_amino "github.com/tendermint/go-amino"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func newTestUpgradeContext(height int64) (context.Context, *UpgradeMapper) {
+	cdc := go_amino.NewCodec()
+	upgradeMapper := NewUpgradeMapper(cdc)
+	upgradeMapper.SetCodec(cdc)
+
+	db := dbm.NewMemDB()
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(upgradeMapper.GetStoreKey(), types.StoreTypeIAVL, db)
+	cms.LoadLatestVersion()
+	ctx := context.NewContext(cms, abci.Header{Height: height}, false, log.NewNopLogger(), map[string]mapper.IMapper{MapperName: upgradeMapper})
+	return ctx, GetUpgradeMapper(ctx)
+}
+
+func newTestAddress() types.AccAddress {
+	return types.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+}
+
+func Test_UpgradeMapper(t *testing.T) {
+	ctx, upgradeMapper := newTestUpgradeContext(10)
+
+	require.NotNil(t, upgradeMapper.ScheduleUpgrade(ctx, Plan{Height: 20}))
+	require.NotNil(t, upgradeMapper.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 10}))
+	_, exists := upgradeMapper.GetPlan()
+	require.False(t, exists)
+
+	//覆盖未执行的计划
+	require.Nil(t, upgradeMapper.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 20}))
+	require.Nil(t, upgradeMapper.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 30, Info: "info"}))
+	plan, exists := upgradeMapper.GetPlan()
+	require.True(t, exists)
+	require.Equal(t, Plan{Name: "v2", Height: 30, Info: "info"}, plan)
+	require.False(t, plan.ShouldExecute(29))
+	require.True(t, plan.ShouldExecute(30))
+
+	//已执行的计划不可再次设置
+	upgradeMapper.ApplyUpgrade(ctx.WithBlockHeight(30), plan)
+	_, exists = upgradeMapper.GetPlan()
+	require.False(t, exists)
+	height, done := upgradeMapper.GetDoneHeight("v2")
+	require.True(t, done)
+	require.Equal(t, int64(30), height)
+	require.NotNil(t, upgradeMapper.ScheduleUpgrade(ctx, Plan{Name: "v2", Height: 40}))
+
+	bz, err := Query(ctx, []string{"done", "v2"}, abci.RequestQuery{})
+	require.Nil(t, err)
+	upgradeMapper.DecodeObject(bz, &height)
+	require.Equal(t, int64(30), height)
+	_, err = Query(ctx, []string{"plan"}, abci.RequestQuery{})
+	require.NotNil(t, err)
+}
+
+func Test_TxScheduleUpgrade(t *testing.T) {
+	ctx, upgradeMapper := newTestUpgradeContext(10)
+	authority, other := newTestAddress(), newTestAddress()
+
+	tx := &TxScheduleUpgrade{Authority: authority, Plan: Plan{Name: "v2", Height: 20}}
+	//未设置authority时不能设置升级计划
+	require.NotNil(t, tx.ValidateData(ctx))
+
+	upgradeMapper.SetAuthority(authority)
+	require.Nil(t, tx.ValidateData(ctx))
+	require.NotNil(t, (&TxScheduleUpgrade{Authority: other, Plan: tx.Plan}).ValidateData(ctx))
+	require.NotNil(t, (&TxScheduleUpgrade{Authority: authority}).ValidateData(ctx))
+
+	result, _ := tx.Exec(ctx)
+	require.True(t, result.IsOK())
+	plan, exists := upgradeMapper.GetPlan()
+	require.True(t, exists)
+	require.Equal(t, tx.Plan, plan)
+
+	cancelTx := &TxCancelUpgrade{Authority: other}
+	require.NotNil(t, cancelTx.ValidateData(ctx))
+	cancelTx.Authority = authority
+	require.Nil(t, cancelTx.ValidateData(ctx))
+	result, _ = cancelTx.Exec(ctx)
+	require.True(t, result.IsOK())
+	result, _ = cancelTx.Exec(ctx)
+	require.False(t, result.IsOK())
+}
@@ -0,0 +1,37 @@
+package upgrade
+
+import (
+	"fmt"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+//Query 升级计划查询, 注册方式: app.RegisterQueryHandler(upgrade.MapperName, upgrade.Query)
+//path: /custom/upgrade/plan: 待执行的升级计划
+//path: /custom/upgrade/done/{name}: 名称为name的计划的执行高度
+//查询结果使用amino编码
+func Query(ctx context.Context, route []string, req abci.RequestQuery) ([]byte, types.Error) {
+	mapper := GetUpgradeMapper(ctx)
+	if mapper == nil {
+		return nil, types.ErrInternal("upgrade mapper not found")
+	}
+
+	switch {
+	case len(route) == 1 && route[0] == "plan":
+		plan, exists := mapper.GetPlan()
+		if !exists {
+			return nil, types.ErrUnknownRequest("upgrade plan does not exist")
+		}
+		return mapper.EncodeObject(plan), nil
+	case len(route) == 2 && route[0] == "done":
+		height, exists := mapper.GetDoneHeight(route[1])
+		if !exists {
+			return nil, types.ErrUnknownRequest(fmt.Sprintf("upgrade %s has not been applied", route[1]))
+		}
+		return mapper.EncodeObject(height), nil
+	}
+
+	return nil, types.ErrUnknownRequest(fmt.Sprintf("unknown %s query route: %v", MapperName, route))
+}
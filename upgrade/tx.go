@@ -0,0 +1,137 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QOSGroup/qbase/context"
+	"github.com/QOSGroup/qbase/txs"
+	"github.com/QOSGroup/qbase/types"
+)
+
+const (
+	EventTypeScheduleUpgrade = "schedule_upgrade"
+	EventTypeCancelUpgrade   = "cancel_upgrade"
+	EventTypeUpgrade         = "upgrade"
+
+	AttributeKeyName   = "name"
+	AttributeKeyHeight = "height"
+)
+
+//TxScheduleUpgrade 设置升级计划, 覆盖未执行的计划. 需由authority签名
+type TxScheduleUpgrade struct {
+	Authority types.AccAddress `json:"authority"`
+	Plan      Plan             `json:"plan"`
+}
+
+var _ txs.ITx = (*TxScheduleUpgrade)(nil)
+
+func (tx *TxScheduleUpgrade) ValidateData(ctx context.Context) error {
+	if err := tx.Plan.ValidateBasic(); err != nil {
+		return err
+	}
+	return validateAuthority(ctx, tx.Authority)
+}
+
+func (tx *TxScheduleUpgrade) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper := GetUpgradeMapper(ctx)
+	if mapper == nil {
+		return types.ErrInternal("upgrade mapper not found").Result(), nil
+	}
+
+	if err := mapper.ScheduleUpgrade(ctx, tx.Plan); err != nil {
+		return types.ErrInternal(err.Error()).Result(), nil
+	}
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeScheduleUpgrade,
+		types.NewAttribute(AttributeKeyName, tx.Plan.Name),
+		types.NewAttribute(AttributeKeyHeight, fmt.Sprintf("%d", tx.Plan.Height)),
+	))
+	return
+}
+
+func (tx *TxScheduleUpgrade) GetSigner() []types.AccAddress {
+	return []types.AccAddress{tx.Authority}
+}
+
+func (tx *TxScheduleUpgrade) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxScheduleUpgrade) GetGasPayer() types.AccAddress {
+	return tx.Authority
+}
+
+func (tx *TxScheduleUpgrade) GetSignData() []byte {
+	//name及info以长度为前缀, 避免不同的计划签名数据相同
+	ret := append([]byte{}, tx.Authority.Bytes()...)
+	ret = append(ret, types.Int2Byte(int64(len(tx.Plan.Name)))...)
+	ret = append(ret, []byte(tx.Plan.Name)...)
+	ret = append(ret, types.Int2Byte(tx.Plan.Height)...)
+	ret = append(ret, types.Int2Byte(int64(len(tx.Plan.Info)))...)
+	return append(ret, []byte(tx.Plan.Info)...)
+}
+
+//TxCancelUpgrade 取消未执行的升级计划. 需由authority签名
+type TxCancelUpgrade struct {
+	Authority types.AccAddress `json:"authority"`
+}
+
+var _ txs.ITx = (*TxCancelUpgrade)(nil)
+
+func (tx *TxCancelUpgrade) ValidateData(ctx context.Context) error {
+	return validateAuthority(ctx, tx.Authority)
+}
+
+func (tx *TxCancelUpgrade) Exec(ctx context.Context) (result types.Result, crossTxQcp *txs.TxQcp) {
+	mapper := GetUpgradeMapper(ctx)
+	if mapper == nil {
+		return types.ErrInternal("upgrade mapper not found").Result(), nil
+	}
+
+	plan, exists := mapper.GetPlan()
+	if !exists {
+		return types.ErrInternal("upgrade plan not found").Result(), nil
+	}
+	mapper.ClearPlan()
+
+	ctx.EventManager().EmitEvent(types.NewEvent(
+		EventTypeCancelUpgrade,
+		types.NewAttribute(AttributeKeyName, plan.Name),
+		types.NewAttribute(AttributeKeyHeight, fmt.Sprintf("%d", plan.Height)),
+	))
+	return
+}
+
+func (tx *TxCancelUpgrade) GetSigner() []types.AccAddress {
+	return []types.AccAddress{tx.Authority}
+}
+
+func (tx *TxCancelUpgrade) CalcGas() types.BigInt {
+	return types.ZeroInt()
+}
+
+func (tx *TxCancelUpgrade) GetGasPayer() types.AccAddress {
+	return tx.Authority
+}
+
+func (tx *TxCancelUpgrade) GetSignData() []byte {
+	return append([]byte{}, tx.Authority.Bytes()...)
+}
+
+func validateAuthority(ctx context.Context, authority types.AccAddress) error {
+	if len(authority) == 0 {
+		return errors.New("authority is empty")
+	}
+
+	mapper := GetUpgradeMapper(ctx)
+	if mapper == nil {
+		return errors.New("upgrade mapper not found")
+	}
+	expected, exists := mapper.GetAuthority()
+	if !exists || !expected.Equals(authority) {
+		return fmt.Errorf("%s is not the upgrade authority", authority)
+	}
+	return nil
+}